@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/config"
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/grpcapi"
+)
+
+// startGRPCServer serves the detection service on the configured gRPC port and returns a
+// function that stops it gracefully, closing remaining streams once ctx is done
+func startGRPCServer(cfg *config.Config, analyzer detector.Analyzer, log *logrus.Logger) func(ctx context.Context) {
+	if !cfg.Server.GRPC.Enabled {
+		return func(context.Context) {}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPC.Port))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to listen for gRPC")
+	}
+
+	server := grpcapi.NewServer(analyzer, cfg.Detection.WorkerPoolSize, cfg.Jobs.MaxBatchSize, log)
+	go func() {
+		log.WithField("port", cfg.Server.GRPC.Port).Info("Starting gRPC server")
+		if err := server.Serve(listener); err != nil {
+			log.WithError(err).Error("gRPC server stopped")
+		}
+	}()
+
+	return func(ctx context.Context) {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// Long-lived DetectStream calls would otherwise hold shutdown open indefinitely
+			log.Warn("gRPC graceful stop timed out, closing remaining connections")
+			server.Stop()
+		}
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
@@ -16,6 +20,11 @@ import (
 	"prompt-injection-detection/internal/config"
 	"prompt-injection-detection/internal/detector"
 	"prompt-injection-detection/internal/handler"
+	"prompt-injection-detection/internal/logging"
+	"prompt-injection-detection/internal/middleware"
+	"prompt-injection-detection/internal/redact"
+	"prompt-injection-detection/internal/tracing"
+	"prompt-injection-detection/internal/webhook"
 )
 
 func main() {
@@ -30,49 +39,143 @@ func main() {
 		log.WithError(err).Fatal("Failed to load configuration")
 	}
 
-	// Initialize detection pipeline with circuit breaker fallback
-	detectionPipeline := detector.NewFallbackPipeline(log)
+	// Mask PII and secrets in logs and in any prompt text that is stored or sent on
+	redactor := newRedactor(cfg, log)
+	log.AddHook(logging.NewRedactionHook(redactor))
 
-	// Initialize HTTP handlers with fallback support
-	handlers := handler.NewFallbackDetectionHandler(detectionPipeline, log)
+	// Tracing is a no-op unless an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), "prompt-shield-detection-engine")
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize tracing")
+	}
+	if tracing.Enabled() {
+		log.Info("OpenTelemetry tracing enabled")
+	}
 
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
 	// Add middleware
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
 	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
+	router.Use(middleware.Recovery(log))
 
-	// Health check endpoint
-	router.GET("/health", handlers.HealthCheck)
+	// Track in-flight requests so shutdown can drain them
+	drainer := middleware.NewDrainer()
+	router.Use(drainer.Middleware("/live"))
 
-	// Detection endpoints
-	v1 := router.Group("/v1")
-	{
-		v1.POST("/detect", handlers.DetectInjection)
-		v1.GET("/metrics", handlers.GetMetrics)
-		v1.GET("/diagnose-llm", handlers.DiagnoseLLM)
-		v1.GET("/circuit-breakers", handlers.GetCircuitBreakers)
-		v1.POST("/circuit-breakers/:model/reset", handlers.ResetCircuitBreaker)
+	// Shed requests over the global in-flight cap so a burst cannot exhaust the instance
+	if cfg.Server.MaxInFlight > 0 {
+		router.Use(middleware.MaxInFlight(cfg.Server.MaxInFlight, cfg.Server.InFlightRetryAfterSeconds, "/health", "/live", "/ready", "/metrics"))
+		log.WithField("max_in_flight", cfg.Server.MaxInFlight).Info("Global in-flight request limit enabled")
+	}
+
+	router.Use(middleware.CORS(cfg.CORS.AllowedOrigins, cfg.CORS.AllowedMethods, cfg.CORS.AllowedHeaders))
+	if cfg.Auth.Enabled {
+		router.Use(middleware.BearerAuth(cfg.Auth.APIKeys, "/health", "/live", "/ready", "/version", "/openapi.json"))
+		log.WithField("keys", len(cfg.Auth.APIKeys)).Info("API key authentication enabled")
+	}
+
+	// Oversized bodies are rejected before they are buffered, inflated or bound
+	router.Use(middleware.MaxBodySize(cfg.Server.MaxBodyBytes))
+
+	// Gzip request bodies are inflated before handlers bind them, large responses are gzipped
+	router.Use(middleware.DecompressRequest(cfg.Server.MaxDecompressedBytes))
+	if cfg.Server.CompressionMinBytes > 0 {
+		router.Use(middleware.Compress(cfg.Server.CompressionMinBytes))
+	}
+
+	// Retries carrying an Idempotency-Key get the stored response instead of a second detection
+	if cfg.Idempotency.Enabled {
+		router.Use(middleware.Idempotency(middleware.NewIdempotencyStore(cfg.Idempotency.TTL, cfg.Idempotency.MaxEntries)))
+	}
+
+	// Load known-attack signatures and keep them fresh in the background
+	signatures := detector.NewSignatureStore(cfg.Patterns.File, cfg.Patterns.CacheSize, cfg.Patterns.UpdateInterval, log)
+	if err := signatures.Load(); err != nil {
+		log.WithError(err).Fatal("Failed to load attack signatures")
 	}
+	signatures.Start()
+
+	// Optional alerting on malicious detections
+	var notifier *webhook.Notifier
+	if cfg.Webhook.URL != "" {
+		notifier = webhook.NewNotifier(webhook.Config{
+			URL:           cfg.Webhook.URL,
+			Secret:        cfg.Webhook.Secret,
+			MinConfidence: cfg.Webhook.MinConfidence,
+			IncludeText:   cfg.Webhook.IncludeText,
+			MaxRetries:    cfg.Webhook.MaxRetries,
+			Backoff:       cfg.Webhook.Backoff,
+			Timeout:       cfg.Webhook.Timeout,
+			QueueSize:     cfg.Webhook.QueueSize,
+			Redactor:      redactor,
+		}, log)
+		log.WithField("min_confidence", cfg.Webhook.MinConfidence).Info("Webhook alerting enabled")
+	}
+
+	// Optional persistence of detection records, written in the background
+	resultSink := newResultSink(cfg, log)
+
+	// Optional background probes of real endpoint reachability
+	var prober *detector.HealthProber
+	if cfg.Detection.HealthProbe.Enabled && !cfg.Detection.LocalOnly {
+		prober = detector.NewHealthProber(cfg.Detection.HealthProbe.Interval, cfg.Detection.HealthProbe.Timeout)
+		log.WithField("interval", cfg.Detection.HealthProbe.Interval).Info("Active endpoint health probes enabled")
+	}
+
+	// Initialize the configured detection pipeline and its endpoints
+	var analyzer detector.Analyzer
+	switch cfg.Detection.Pipeline {
+	case config.PipelineSimple:
+		analyzer = registerSimpleRoutes(router, cfg, log, signatures, notifier, prober, resultSink, redactor)
+	default:
+		analyzer = registerFallbackRoutes(router, cfg, log, signatures, notifier, prober, resultSink, redactor)
+	}
+	prober.Start()
+
+	// Async batch jobs run on the configured pipeline in the background
+	jobs := detector.NewJobStore(analyzer, cfg.Detection.WorkerPoolSize, cfg.Jobs.QueueSize, cfg.Jobs.TTL)
+	jobHandlers := handler.NewJobHandler(jobs, cfg.Jobs.MaxBatchSize, log)
+	router.POST("/v1/detect/batch/async", jobHandlers.SubmitBatch)
+	router.GET("/v1/jobs/:id", jobHandlers.GetJob)
+
+	// Synchronous batches can also stream results as Server-Sent Events
+	streamHandlers := handler.NewStreamHandler(analyzer, cfg.Detection.WorkerPoolSize, cfg.Jobs.MaxBatchSize, log)
+	router.POST("/v1/detect/batch/stream", streamHandlers.StreamBatch)
+
+	stopGRPC := startGRPCServer(cfg, analyzer, log)
+
+	router.GET("/version", handler.Version)
+	router.GET("/openapi.json", handler.OpenAPI)
+	router.GET("/v1/threats", handler.Threats)
 
 	// Prometheus metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	registerPprofRoutes(router, cfg, log)
+
+	// Request contexts derive from baseCtx so in-flight work is cancelled if draining times out
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	// Create HTTP server
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      router,
-		ReadTimeout:  cfg.Server.Timeout,
-		WriteTimeout: cfg.Server.Timeout,
-	}
+	server := newHTTPServer(cfg, router, baseCtx)
 
 	// Start server in goroutine
 	go func() {
-		log.WithField("port", cfg.Server.Port).Info("Starting detection engine server")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.WithFields(logrus.Fields{
+			"port": cfg.Server.Port,
+			"tls":  cfg.Server.TLS.Enabled(),
+		}).Info("Starting detection engine server")
+
+		listener, err := net.Listen("tcp", server.Addr)
+		if err == nil {
+			err = serve(server, listener, cfg.Server.TLS)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.WithError(err).Fatal("Failed to start server")
 		}
 	}()
@@ -88,24 +191,400 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Reject new requests and let active detections finish
+	drainer.StartDrain()
+	log.WithField("in_flight", drainer.InFlight()).Info("Draining in-flight requests")
+	if err := drainer.Wait(ctx); err != nil {
+		log.WithField("in_flight", drainer.InFlight()).Warn("Drain timed out, cancelling remaining requests")
+		cancelRequests()
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.WithError(err).Error("Server forced to shutdown")
 	}
+	stopGRPC(ctx)
+
+	signatures.Stop()
+	prober.Stop()
+	jobs.Stop()
+	if notifier != nil {
+		notifier.Stop()
+	}
+	if resultSink != nil {
+		resultSink.Stop()
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.WithError(err).Warn("Failed to flush traces")
+	}
 
 	log.Info("Server stopped")
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// newHTTPServer builds the HTTP server for the router, requiring TLS 1.2 or later when TLS is configured
+func newHTTPServer(cfg *config.Config, handler http.Handler, baseCtx context.Context) *http.Server {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      handler,
+		ReadTimeout:  cfg.Server.Timeout,
+		WriteTimeout: cfg.Server.Timeout,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
+	}
+	if cfg.Server.TLS.Enabled() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return server
+}
+
+// serve accepts connections on listener, over HTTPS when a certificate and key are configured
+func serve(server *http.Server, listener net.Listener, tlsConfig config.TLSConfig) error {
+	if tlsConfig.Enabled() {
+		return server.ServeTLS(listener, tlsConfig.CertFile, tlsConfig.KeyFile)
+	}
+	return server.Serve(listener)
+}
+
+// registerSimpleRoutes wires the single-pass LLM pipeline without circuit breakers and returns it
+func registerSimpleRoutes(router *gin.Engine, cfg *config.Config, log *logrus.Logger, signatures *detector.SignatureStore, notifier *webhook.Notifier, prober *detector.HealthProber, resultSink *detector.AsyncSink, redactor *redact.Redactor) detector.Analyzer {
+	modelRegistry := loadModelRegistry(cfg, log)
+	detectionPipeline := detector.NewPipelineWithDetector(log, newLLMDetector(cfg, signatures, modelRegistry.GetEnabledModels()))
+	detectionPipeline.SetLocalOnly(cfg.Detection.LocalOnly)
+	detectionPipeline.SetFailureMode(detector.FailureMode(cfg.Detection.OnFailure))
+	detectionPipeline.SetSeverityThresholds(newSeverityThresholds(cfg))
+	detectionPipeline.SetHealthProber(prober)
+	if cache := newResultCache(cfg, log); cache != nil {
+		detectionPipeline.SetResultCache(cache)
+	}
+	detectionPipeline.SetRedactor(redactor)
+	detectionPipeline.SetRecentDetections(newRecentDetections(cfg, redactor))
+	if notifier != nil {
+		detectionPipeline.SetNotifier(notifier)
+	}
+	if resultSink != nil {
+		detectionPipeline.SetResultSink(resultSink, cfg.ResultSink.IncludeText)
+	}
+	runStartupSelfTest(cfg, log, detectionPipeline)
+	handlers := handler.NewDetectionHandler(detectionPipeline, log)
+
+	// Health check endpoints: /health is detailed, /live and /ready are for orchestrator probes
+	router.GET("/health", handlers.HealthCheck)
+	router.GET("/live", handlers.Liveness)
+	router.GET("/ready", handlers.Readiness)
+
+	// Detection endpoints
+	v1 := router.Group("/v1")
+	{
+		v1.POST("/detect", handlers.DetectInjection)
+		v1.POST("/detect/output", handlers.DetectOutput)
+		v1.POST("/detect/document", handlers.DetectDocument)
+		v1.POST("/detect/tool-call", handlers.DetectToolCall)
+		v1.GET("/metrics", handlers.GetMetrics)
+		v1.GET("/diagnose-llm", handlers.DiagnoseLLM)
+		v1.GET("/selftest", handlers.GetSelfTest)
+		v1.GET("/detections/recent", handlers.GetRecentDetections)
+	}
+
+	log.WithField("pipeline", config.PipelineSimple).Info("Detection pipeline configured")
+	return detectionPipeline
+}
+
+// registerFallbackRoutes wires the multi-model pipeline with circuit breaker fallback and returns it
+func registerFallbackRoutes(router *gin.Engine, cfg *config.Config, log *logrus.Logger, signatures *detector.SignatureStore, notifier *webhook.Notifier, prober *detector.HealthProber, resultSink *detector.AsyncSink, redactor *redact.Redactor) detector.Analyzer {
+	modelRegistry := loadModelRegistry(cfg, log)
+	detectionPipeline := detector.NewFallbackPipelineWithRegistry(log, modelRegistry, newLLMDetector(cfg, signatures, modelRegistry.GetEnabledModels()))
+	detectionPipeline.SetLocalOnly(cfg.Detection.LocalOnly)
+	detectionPipeline.SetFailureMode(detector.FailureMode(cfg.Detection.OnFailure))
+	detectionPipeline.SetSeverityThresholds(newSeverityThresholds(cfg))
+	detectionPipeline.SetHealthProber(prober)
+	detectionPipeline.SetSessionTTL(cfg.Detection.SessionTTL)
+	detectionPipeline.SetDisagreementThreshold(cfg.Detection.DisagreementThreshold)
+	if cfg.Detection.TieBreaker != "" {
+		if _, err := modelRegistry.GetModelByName(cfg.Detection.TieBreaker); err != nil {
+			log.WithError(err).Fatal("Unknown consensus tie-breaker model")
+		}
+		detectionPipeline.SetTieBreaker(cfg.Detection.TieBreaker)
+		log.WithField("model", cfg.Detection.TieBreaker).Info("Consensus tie-breaker configured")
+	}
+	detectionPipeline.SetMinBenignVotes(cfg.Detection.MinBenignVotes)
+	if store := newBreakerStore(cfg, log); store != nil {
+		detectionPipeline.SetBreakerStore(store)
+	}
+	if cache := newResultCache(cfg, log); cache != nil {
+		detectionPipeline.SetResultCache(cache)
+	}
+	detectionPipeline.SetRedactor(redactor)
+	detectionPipeline.SetRecentDetections(newRecentDetections(cfg, redactor))
+	if notifier != nil {
+		detectionPipeline.SetNotifier(notifier)
+	}
+	if resultSink != nil {
+		detectionPipeline.SetResultSink(resultSink, cfg.ResultSink.IncludeText)
+	}
+	if budget := detector.NewCostBudget(cfg.Detection.DailyBudgetUSD); budget != nil {
+		detectionPipeline.SetCostBudget(budget)
+		log.WithField("daily_budget_usd", cfg.Detection.DailyBudgetUSD).Info("Daily cost budget enabled for paid models")
+	}
+	runStartupSelfTest(cfg, log, detectionPipeline)
+	handlers := handler.NewFallbackDetectionHandler(detectionPipeline, log)
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+	// Health check endpoints: /health is detailed, /live and /ready are for orchestrator probes
+	router.GET("/health", handlers.HealthCheck)
+	router.GET("/live", handlers.Liveness)
+	router.GET("/ready", handlers.Readiness)
+
+	// Detection endpoints
+	v1 := router.Group("/v1")
+	{
+		v1.POST("/detect", handlers.DetectInjection)
+		v1.POST("/detect/output", handlers.DetectOutput)
+		v1.POST("/detect/document", handlers.DetectDocument)
+		v1.POST("/detect/tool-call", handlers.DetectToolCall)
+		v1.POST("/detect/session", handlers.DetectSession)
+		v1.GET("/metrics", handlers.GetMetrics)
+		v1.GET("/diagnose-llm", handlers.DiagnoseLLM)
+		v1.GET("/selftest", handlers.GetSelfTest)
+		v1.GET("/detections/recent", handlers.GetRecentDetections)
+		v1.GET("/circuit-breakers", handlers.GetCircuitBreakers)
+		v1.POST("/circuit-breakers/:model/reset", handlers.ResetCircuitBreaker)
+		v1.GET("/models", handlers.ListModels)
+		v1.PATCH("/models/:name", handlers.UpdateModel)
+		v1.POST("/models/:name/test", handlers.TestModel)
+	}
+
+	log.WithField("pipeline", config.PipelineFallback).Info("Detection pipeline configured")
+	return detectionPipeline
+}
+
+// registerPprofRoutes mounts the net/http/pprof handlers under /debug/pprof when
+// server.pprof is enabled
+func registerPprofRoutes(router *gin.Engine, cfg *config.Config, log *logrus.Logger) {
+	if !cfg.Server.Pprof {
+		return
+	}
+	log.Warn("pprof profiling endpoints enabled at /debug/pprof")
+
+	debug := router.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", withoutWriteTimeout(pprof.Profile))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", withoutWriteTimeout(pprof.Trace))
+		debug.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+}
+
+// withoutWriteTimeout serves a long-running pprof download past the server's WriteTimeout.
+// pprof refuses a duration at or above the WriteTimeout of the server in the request
+// context, so once the write deadline is lifted the handler sees a server without one.
+func withoutWriteTimeout(h http.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			h(c.Writer, c.Request)
 			return
 		}
+		ctx := context.WithValue(c.Request.Context(), http.ServerContextKey, &http.Server{})
+		h(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+// selfTester is implemented by pipelines that can verify their models against a known corpus
+type selfTester interface {
+	RunSelfTest(ctx context.Context) *detector.SelfTestReport
+}
+
+// runStartupSelfTest runs the self-test when enabled, exiting if fail-fast is set and no model can classify
+func runStartupSelfTest(cfg *config.Config, log *logrus.Logger, pipeline selfTester) {
+	if !cfg.SelfTest.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.SelfTest.Timeout)
+	defer cancel()
+
+	report := pipeline.RunSelfTest(ctx)
+	log.WithFields(logrus.Fields{
+		"healthy":     report.Healthy,
+		"models":      len(report.Models),
+		"duration_ms": report.DurationMs,
+	}).Info("Startup self-test completed")
+
+	if !report.Healthy && cfg.SelfTest.FailFast {
+		log.Fatal("Startup self-test failed: no model could classify the test corpus")
+	}
+}
 
-		c.Next()
+// newBreakerStore connects the shared circuit breaker store when configured,
+// returning nil so breakers stay in memory if Redis is unavailable
+func newBreakerStore(cfg *config.Config, log *logrus.Logger) detector.BreakerStateStore {
+	if cfg.BreakerStore.Backend != config.BreakerStoreRedis {
+		return nil
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := detector.NewRedisBreakerStore(ctx, cfg.BreakerStore.RedisURL, cfg.BreakerStore.KeyPrefix)
+	if err != nil {
+		log.WithError(err).Warn("Shared circuit breaker store unavailable, using in-memory breaker state")
+		return nil
+	}
+
+	log.Info("Circuit breaker state shared via Redis")
+	return store
+}
+
+// newResultCache builds the configured response cache, using the local cache
+// alone when Redis is selected but unreachable
+func newResultCache(cfg *config.Config, log *logrus.Logger) detector.ResultCache {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+
+	local := detector.NewMemoryResultCache(cfg.Cache.Size, cfg.Cache.TTL)
+	if cfg.Cache.Backend != config.CacheBackendRedis {
+		log.WithField("size", cfg.Cache.Size).Info("In-memory result cache enabled")
+		return local
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cache, err := detector.NewRedisResultCache(ctx, cfg.Cache.RedisURL, cfg.Cache.KeyPrefix, cfg.Cache.TTL, local, log)
+	if err != nil {
+		log.WithError(err).Warn("Redis result cache unavailable, using in-memory result cache")
+		return local
+	}
+
+	log.Info("Result cache shared via Redis")
+	return cache
+}
+
+// newRecentDetections builds the recent detection log, nil when capture is disabled
+func newRecentDetections(cfg *config.Config, redactor *redact.Redactor) *detector.RecentDetections {
+	if !cfg.RecentDetections.Enabled {
+		return nil
+	}
+	return detector.NewRecentDetections(cfg.RecentDetections.Size, cfg.RecentDetections.CaptureText, redactor)
+}
+
+// newRedactor builds the redactor from the built-in and configured patterns
+func newRedactor(cfg *config.Config, log *logrus.Logger) *redact.Redactor {
+	patterns := make([]redact.Pattern, 0, len(cfg.Redaction.Patterns))
+	for _, pattern := range cfg.Redaction.Patterns {
+		patterns = append(patterns, redact.Pattern{Name: pattern.Name, Pattern: pattern.Pattern})
+	}
+
+	redactor, err := redact.New(!cfg.Redaction.DisableBuiltin, patterns)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to build redaction patterns")
+	}
+	return redactor
+}
+
+// newResultSink opens the configured detection record sink, nil when records are not persisted
+func newResultSink(cfg *config.Config, log *logrus.Logger) *detector.AsyncSink {
+	if cfg.ResultSink.Type != config.ResultSinkJSONL {
+		return nil
+	}
+
+	sink, err := detector.NewJSONLSink(cfg.ResultSink.Path)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open result sink")
+	}
+	log.WithFields(logrus.Fields{
+		"path":         cfg.ResultSink.Path,
+		"include_text": cfg.ResultSink.IncludeText,
+	}).Info("Detection records persisted to JSONL file")
+	return detector.NewAsyncSink(sink, cfg.ResultSink.QueueSize, log)
+}
+
+// newSeverityThresholds converts the configured severity score bands
+func newSeverityThresholds(cfg *config.Config) detector.SeverityThresholds {
+	return detector.SeverityThresholds{
+		Low:      cfg.Detection.Severity.Low,
+		Medium:   cfg.Detection.Severity.Medium,
+		High:     cfg.Detection.Severity.High,
+		Critical: cfg.Detection.Severity.Critical,
+	}
+}
+
+// newLLMDetector builds the LLM detector from configuration, calling the endpoints of models
+func newLLMDetector(cfg *config.Config, signatures *detector.SignatureStore, models []detector.ModelConfig) *detector.LLMDetector {
+	llmConfig := detector.DefaultLLMDetectorConfig()
+	llmConfig.Models = models
+	llmConfig.EndpointDelay = cfg.Detection.EndpointRetryDelay
+	llmConfig.MaxConcurrentCalls = cfg.Detection.WorkerPoolSize
+	llmConfig.DispatchQueueTimeout = cfg.Detection.DispatchQueueTimeout
+	llmConfig.Signatures = signatures
+	llmConfig.DecodeKeywords = detector.DecodeKeywords{
+		Languages:  cfg.Detection.DecodeKeywords.Languages,
+		MinMatches: cfg.Detection.DecodeKeywords.MinMatches,
+	}
+	llmConfig.VariantAggregation = detector.VariantAggregation(cfg.Detection.VariantAggregation)
+	llmConfig.Chunking = detector.TextChunking{
+		WindowSize: cfg.Detection.Chunking.WindowSize,
+		Overlap:    cfg.Detection.Chunking.Overlap,
+	}
+	llmConfig.Truncation = detector.TruncationStrategy(cfg.Detection.Truncation)
+	llmConfig.SystemPrompt = cfg.Detection.SystemPrompt
+	llmConfig.AdaptiveTimeout = detector.AdaptiveTimeout{
+		Enabled:    cfg.Detection.AdaptiveTimeout.Enabled,
+		Factor:     cfg.Detection.AdaptiveTimeout.Factor,
+		Min:        cfg.Detection.AdaptiveTimeout.Min,
+		MinSamples: cfg.Detection.AdaptiveTimeout.MinSamples,
+		Window:     cfg.Detection.AdaptiveTimeout.Window,
+	}
+	llmConfig.FanOut = detector.EndpointFanOut{
+		Enabled:        cfg.Detection.FanOut.Enabled,
+		MaxConcurrency: cfg.Detection.FanOut.MaxConcurrency,
+	}
+	llmConfig.Retry = detector.RetryPolicy{
+		MaxAttempts: cfg.Detection.Retry.MaxAttempts,
+		BaseDelay:   cfg.Detection.Retry.BaseDelay,
+		MaxDelay:    cfg.Detection.Retry.MaxDelay,
+	}
+	llmConfig.Transport = detector.HTTPTransportConfig{
+		MaxIdleConns:        cfg.Detection.HTTP.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.Detection.HTTP.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.Detection.HTTP.IdleConnTimeout,
+		DisableKeepAlives:   cfg.Detection.HTTP.DisableKeepAlives,
+		ProxyURL:            cfg.Detection.HTTP.ProxyURL,
+	}
+
+	return detector.NewLLMDetectorWithConfig(llmConfig)
+}
+
+// loadModelRegistry builds the model registry from the configured models file,
+// falling back to the built-in model list when the file does not exist, and
+// disables the models listed in detection.disabled_models
+func loadModelRegistry(cfg *config.Config, log *logrus.Logger) *detector.ModelRegistry {
+	registry := detector.NewModelRegistry()
+
+	modelConfigs, err := detector.LoadModelConfigsFromFile(cfg.Detection.ModelsFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.WithField("models_file", cfg.Detection.ModelsFile).Info("Model config file not found, using built-in model registry")
+	case err != nil:
+		log.WithError(err).Fatal("Failed to load model config file")
+	default:
+		registry.LoadFromConfig(modelConfigs)
+		log.WithFields(logrus.Fields{
+			"models_file": cfg.Detection.ModelsFile,
+			"models":      len(modelConfigs),
+		}).Info("Model registry loaded from config file")
+	}
+
+	for _, name := range cfg.Detection.DisabledModels {
+		if err := registry.DisableModel(name); err != nil {
+			log.WithField("model", name).Warn("Cannot disable unknown model")
+			continue
+		}
+		log.WithField("model", name).Info("Model disabled by configuration")
+	}
+
+	return registry
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/config"
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/handler"
+	"prompt-injection-detection/internal/redact"
+)
+
+// newTestRouter registers the routes of the given pipeline with the default configuration
+func newTestRouter(t *testing.T, pipeline string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	signatures := detector.NewSignatureStore(cfg.Patterns.File, cfg.Patterns.CacheSize, cfg.Patterns.UpdateInterval, log)
+
+	router := gin.New()
+	switch pipeline {
+	case config.PipelineSimple:
+		registerSimpleRoutes(router, cfg, log, signatures, nil, nil, nil, redact.Default())
+	default:
+		registerFallbackRoutes(router, cfg, log, signatures, nil, nil, nil, redact.Default())
+	}
+	return router
+}
+
+// hasRoute reports whether the router serves method on path
+func hasRoute(router *gin.Engine, method, path string) bool {
+	for _, route := range router.Routes() {
+		if route.Method == method && route.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreakerRoutes are only served by the fallback pipeline
+var circuitBreakerRoutes = []struct{ method, path string }{
+	{http.MethodGet, "/v1/circuit-breakers"},
+	{http.MethodPost, "/v1/circuit-breakers/:model/reset"},
+	{http.MethodGet, "/v1/models"},
+	{http.MethodPatch, "/v1/models/:name"},
+}
+
+func TestFallbackPipelineRegistersCircuitBreakerRoutes(t *testing.T) {
+	router := newTestRouter(t, config.PipelineFallback)
+
+	for _, route := range circuitBreakerRoutes {
+		if !hasRoute(router, route.method, route.path) {
+			t.Errorf("fallback pipeline does not serve %s %s", route.method, route.path)
+		}
+	}
+	if !hasRoute(router, http.MethodPost, "/v1/detect") {
+		t.Error("fallback pipeline does not serve POST /v1/detect")
+	}
+}
+
+func TestSimplePipelineOmitsCircuitBreakerRoutes(t *testing.T) {
+	router := newTestRouter(t, config.PipelineSimple)
+
+	for _, route := range circuitBreakerRoutes {
+		if hasRoute(router, route.method, route.path) {
+			t.Errorf("simple pipeline serves %s %s", route.method, route.path)
+		}
+	}
+	if !hasRoute(router, http.MethodPost, "/v1/detect") {
+		t.Error("simple pipeline does not serve POST /v1/detect")
+	}
+}
+
+// documentedOperations returns the "METHOD /path" operations described by the served OpenAPI document
+func documentedOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	router := gin.New()
+	router.GET("/openapi.json", handler.OpenAPI)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode OpenAPI document: %v", err)
+	}
+	operations := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations
+}
+
+// openAPIPath converts a gin route path such as /v1/models/:name to its OpenAPI form /v1/models/{name}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestOpenAPIDocumentsPipelineRoutes(t *testing.T) {
+	operations := documentedOperations(t)
+
+	for _, pipeline := range []string{config.PipelineFallback, config.PipelineSimple} {
+		t.Run(pipeline, func(t *testing.T) {
+			for _, route := range newTestRouter(t, pipeline).Routes() {
+				if operation := route.Method + " " + openAPIPath(route.Path); !operations[operation] {
+					t.Errorf("%s is served but not described in openapi.json", operation)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadModelRegistryFromFileWithDisabledModels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	err := os.WriteFile(path, []byte(`
+models:
+  - name: local-classifier
+    provider: huggingface
+    type: classification
+    model: local-classifier
+    url: http://localhost:9000/classify
+    priority: 1
+    enabled: true
+  - name: hosted-genai
+    provider: openrouter
+    type: genai
+    model: vendor/model
+    url: http://localhost:9001/v1/chat/completions
+    priority: 2
+    enabled: true
+`), 0o600)
+	if err != nil {
+		t.Fatalf("write models file: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.Detection.ModelsFile = path
+	cfg.Detection.DisabledModels = []string{"hosted-genai", "not-a-model"}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	enabled := loadModelRegistry(cfg, log).GetEnabledModels()
+	if len(enabled) != 1 || enabled[0].Name != "local-classifier" || enabled[0].URL != "http://localhost:9000/classify" {
+		t.Errorf("enabled models = %+v, want only local-classifier from the file", enabled)
+	}
+}
+
+func TestPprofRoutesOnlyWhenEnabled(t *testing.T) {
+	tests := map[string]struct {
+		env        string
+		wantStatus int
+	}{
+		"default": {"", http.StatusNotFound},
+		"enabled": {"true", http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROMPT_SHIELD_PPROF", tt.env)
+			gin.SetMode(gin.TestMode)
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatalf("config.Load: %v", err)
+			}
+			log := logrus.New()
+			log.SetOutput(io.Discard)
+
+			router := gin.New()
+			registerPprofRoutes(router, cfg, log)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("GET /debug/pprof/ = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
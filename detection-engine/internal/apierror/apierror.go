@@ -0,0 +1,81 @@
+package apierror
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Code is a stable, machine-readable error identifier clients can branch on
+type Code string
+
+const (
+	CodeInvalidPayload       Code = "invalid_payload"        // Malformed or invalid request (400)
+	CodeUnauthorized         Code = "unauthorized"           // Missing or invalid API key (401)
+	CodeNotFound             Code = "not_found"              // Unknown resource (404)
+	CodeFeatureDisabled      Code = "feature_disabled"       // Endpoint disabled by configuration (404)
+	CodeTimeout              Code = "timeout"                // Detection did not finish in time (408)
+	CodeRequestTooLarge      Code = "request_too_large"      // Body or batch exceeds the configured limit (413)
+	CodeRateLimited          Code = "rate_limited"           // Upstream providers are rate limiting (429)
+	CodeInternal             Code = "internal_error"         // Unexpected failure (500)
+	CodeAllModelsUnavailable Code = "all_models_unavailable" // Every detection model failed or is open (503)
+	CodeOverloaded           Code = "overloaded"             // Capacity exhausted, retry later (503)
+	CodeBudgetExhausted      Code = "budget_exhausted"       // Daily cost budget spent and no free model answered (503)
+	CodeShuttingDown         Code = "shutting_down"          // Instance is draining (503)
+
+	// Idempotency-Key replay errors
+	CodeRequestInProgress Code = "request_in_progress" // A request with the same key is still running (409)
+	CodeIdempotencyReused Code = "idempotency_reused"  // Key reused with a different request body (422)
+)
+
+// APIError is the JSON body of every error response
+type APIError struct {
+	Status     int    `json:"-"`
+	Code       Code   `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details,omitempty"`
+	Field      string `json:"field,omitempty"`       // JSON path of the invalid request field
+	RetryAfter int    `json:"retry_after,omitempty"` // Suggested seconds before retrying
+}
+
+// New creates an API error returned with the given HTTP status
+func New(status int, code Code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// WithDetails returns a copy of the error with human-readable details attached
+func (e *APIError) WithDetails(details string) *APIError {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithField returns a copy of the error naming the invalid request field
+func (e *APIError) WithField(field string) *APIError {
+	copied := *e
+	copied.Field = field
+	return &copied
+}
+
+// WithRetryAfter returns a copy of the error suggesting a retry delay in seconds
+func (e *APIError) WithRetryAfter(seconds int) *APIError {
+	copied := *e
+	copied.RetryAfter = seconds
+	return &copied
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Details != "" {
+		return string(e.Code) + ": " + e.Message + ": " + e.Details
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+// Render writes the error as the response
+func Render(c *gin.Context, err *APIError) {
+	c.JSON(err.Status, err)
+}
+
+// Abort writes the error as the response and stops the middleware chain
+func Abort(c *gin.Context, err *APIError) {
+	c.AbortWithStatusJSON(err.Status, err)
+}
@@ -0,0 +1,80 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIErrorJSON(t *testing.T) {
+	tests := map[string]struct {
+		err  *APIError
+		want string
+	}{
+		"minimal": {
+			err:  New(http.StatusNotFound, CodeNotFound, "Job not found"),
+			want: `{"code":"not_found","message":"Job not found"}`,
+		},
+		"every field": {
+			err:  New(http.StatusServiceUnavailable, CodeAllModelsUnavailable, "Unavailable").WithDetails("try later").WithField("text").WithRetryAfter(60),
+			want: `{"code":"all_models_unavailable","message":"Unavailable","details":"try later","field":"text","retry_after":60}`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(tt.err)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("JSON = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorBuildersCopy(t *testing.T) {
+	base := New(http.StatusBadRequest, CodeInvalidPayload, "Invalid request")
+	detailed := base.WithDetails("text is required").WithField("text")
+
+	if base.Details != "" || base.Field != "" {
+		t.Errorf("base = %+v, want it unchanged by the builders", base)
+	}
+	if detailed.Status != http.StatusBadRequest || detailed.Code != CodeInvalidPayload {
+		t.Errorf("detailed = %+v, want the base status and code kept", detailed)
+	}
+	if got := detailed.Error(); got != "invalid_payload: Invalid request: text is required" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := base.Error(); got != "invalid_payload: Invalid request" {
+		t.Errorf("Error() without details = %q", got)
+	}
+}
+
+func TestRenderAndAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiErr := New(http.StatusTooManyRequests, CodeRateLimited, "Slow down")
+
+	reached := false
+	router := gin.New()
+	router.GET("/render", func(c *gin.Context) { Render(c, apiErr) })
+	router.GET("/abort", func(c *gin.Context) { Abort(c, apiErr) }, func(c *gin.Context) { reached = true })
+
+	for _, path := range []string{"/render", "/abort"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusTooManyRequests {
+			t.Errorf("%s status = %d, want 429", path, recorder.Code)
+		}
+		var body APIError
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Code != CodeRateLimited {
+			t.Errorf("%s body = %s, want the rate_limited error", path, recorder.Body)
+		}
+	}
+	if reached {
+		t.Error("handler after Abort ran, want the chain stopped")
+	}
+}
@@ -0,0 +1,77 @@
+package canary
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tokenPrefix marks generated canaries so they are easy to spot in logs
+const tokenPrefix = "cnry"
+
+// tokenBytes is the amount of randomness in a generated canary (128 bits)
+const tokenBytes = 16
+
+// encodedSegment matches runs that may be base64-wrapped canary echoes
+var encodedSegment = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}`)
+
+// Generate returns a new high-entropy canary token for embedding in a system prompt
+func Generate() string {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		panic("canary: failed to read random bytes: " + err.Error())
+	}
+	return tokenPrefix + "-" + hex.EncodeToString(buf)
+}
+
+// Contains reports whether output echoes the token, including spaced, re-cased or base64-wrapped copies
+func Contains(output, token string) bool {
+	normalizedToken := normalize(token)
+	if normalizedToken == "" {
+		return false
+	}
+
+	if strings.Contains(normalize(output), normalizedToken) {
+		return true
+	}
+
+	for _, segment := range encodedSegment.FindAllString(output, -1) {
+		if decoded, ok := decodeBase64(segment); ok && strings.Contains(normalize(decoded), normalizedToken) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalize lowercases text and drops everything but letters and digits,
+// so separators inserted between characters don't hide the token
+func normalize(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return builder.String()
+}
+
+// decodeBase64 tries the standard and URL-safe alphabets, with and without padding
+func decodeBase64(segment string) (string, bool) {
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.RawURLEncoding,
+	}
+	for _, encoding := range encodings {
+		if decoded, err := encoding.DecodeString(segment); err == nil {
+			return string(decoded), true
+		}
+	}
+	return "", false
+}
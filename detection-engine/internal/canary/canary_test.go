@@ -0,0 +1,58 @@
+package canary
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	format := regexp.MustCompile(`^cnry-[0-9a-f]{32}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token := Generate()
+		if !format.MatchString(token) {
+			t.Fatalf("Generate() = %q, want cnry- followed by 32 hex digits", token)
+		}
+		if seen[token] {
+			t.Fatalf("Generate() repeated %q", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestContains(t *testing.T) {
+	token := Generate()
+	spaced := strings.Join(strings.Split(token, ""), " ")
+
+	tests := map[string]struct {
+		output string
+		want   bool
+	}{
+		"direct":           {"My instructions include the marker " + token + ".", true},
+		"upper case":       {"MARKER: " + strings.ToUpper(token), true},
+		"spaced":           {"Here it is: " + spaced, true},
+		"dotted":           {"Here it is: " + strings.Join(strings.Split(token, ""), "."), true},
+		"base64":           {"Encoded as requested: " + base64.StdEncoding.EncodeToString([]byte(token)), true},
+		"base64 url raw":   {"Encoded: " + base64.RawURLEncoding.EncodeToString([]byte("prefix "+token)), true},
+		"other token":      {"The marker is " + Generate(), false},
+		"truncated":        {"The marker is " + token[:len(token)-4], false},
+		"unrelated output": {"I'm sorry, I can't help with that request.", false},
+		"unrelated base64": {"Data: " + base64.StdEncoding.EncodeToString([]byte("nothing to see here at all")), false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Contains(tt.output, token); got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsEmptyToken(t *testing.T) {
+	if Contains("any output", "") || Contains("any output", " - ") {
+		t.Error("an empty token matched, want no match")
+	}
+}
@@ -1,34 +1,345 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+// Detection pipeline implementations selectable via detection.pipeline
+const (
+	PipelineFallback = "fallback" // Multi-model with circuit breakers (default)
+	PipelineSimple   = "simple"   // Single-pass LLM detector
+)
+
+// Circuit breaker state backends selectable via breaker_store.backend
+const (
+	BreakerStoreMemory = "memory" // Per-instance state (default)
+	BreakerStoreRedis  = "redis"  // Shared across replicas
+)
+
+// Result cache backends selectable via cache.backend
+const (
+	CacheBackendMemory = "memory" // Per-instance LRU (default)
+	CacheBackendRedis  = "redis"  // Shared across replicas, local LRU on Redis errors
+)
+
+// Detection record sinks selectable via result_sink.type
+const (
+	ResultSinkNone  = "none"  // Records are not persisted (default)
+	ResultSinkJSONL = "jsonl" // Appended to a file, one JSON object per line
+)
+
 type Config struct {
 	Server    ServerConfig    `mapstructure:"server"`
 	Detection DetectionConfig `mapstructure:"detection"`
 	Patterns  PatternsConfig  `mapstructure:"patterns"`
 	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	SelfTest  SelfTestConfig  `mapstructure:"selftest"`
+
+	BreakerStore BreakerStoreConfig `mapstructure:"breaker_store"`
+	Cache        CacheConfig        `mapstructure:"cache"`
+
+	RecentDetections RecentDetectionsConfig `mapstructure:"recent_detections"`
+	Jobs             JobsConfig             `mapstructure:"jobs"`
+	Webhook          WebhookConfig          `mapstructure:"webhook"`
+
+	// Persistence of detection outcomes for audit and analytics
+	ResultSink ResultSinkConfig `mapstructure:"result_sink"`
+
+	// Masking of PII and secrets wherever prompt text is stored or logged
+	Redaction RedactionConfig `mapstructure:"redaction"`
+
+	// Replay of responses for retried requests carrying an Idempotency-Key header
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
 }
 
 type ServerConfig struct {
 	Port    int           `mapstructure:"port"`
 	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Largest request body accepted as sent (compressed or not); larger bodies get 413
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+
+	// Largest gzip request body accepted once inflated
+	MaxDecompressedBytes int64 `mapstructure:"max_decompressed_bytes"`
+
+	// Responses at least this large are gzipped for clients that accept it, 0 disables
+	CompressionMinBytes int `mapstructure:"compression_min_bytes"`
+
+	TLS TLSConfig `mapstructure:"tls"`
+
+	// Mount net/http/pprof under /debug/pprof, off by default
+	Pprof bool `mapstructure:"pprof"`
+
+	// Requests served at once across all clients before new ones get 503, 0 is unlimited
+	MaxInFlight int `mapstructure:"max_in_flight"`
+
+	// Retry-After sent with requests shed by the in-flight limit
+	InFlightRetryAfterSeconds int `mapstructure:"in_flight_retry_after_seconds"`
+
+	// gRPC listener served alongside HTTP (requires a build with the grpc tag)
+	GRPC GRPCConfig `mapstructure:"grpc"`
+}
+
+// TLSConfig enables HTTPS when both files are set; plain HTTP is used otherwise
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+}
+
+// Enabled reports whether a certificate and key are configured
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
 }
 
 type DetectionConfig struct {
 	ConfidenceThreshold float64 `mapstructure:"confidence_threshold"`
 	MaxPromptLength     int     `mapstructure:"max_prompt_length"`
 	WorkerPoolSize      int     `mapstructure:"worker_pool_size"`
+	ModelsFile          string  `mapstructure:"models_file"`
+	Pipeline            string  `mapstructure:"pipeline"`
+	LocalOnly           bool    `mapstructure:"local_only"`
+
+	EndpointRetryDelay time.Duration `mapstructure:"endpoint_retry_delay"`
+	SessionTTL         time.Duration `mapstructure:"session_ttl"`
+
+	// WorkerPoolSize caps concurrent outbound model calls; callers wait up to
+	// DispatchQueueTimeout for a free slot before being rejected
+	DispatchQueueTimeout time.Duration `mapstructure:"dispatch_queue_timeout"`
+
+	// Connection pooling for outbound provider calls
+	HTTP HTTPClientConfig `mapstructure:"http"`
+
+	// Daily USD cap on paid model calls (0 = unlimited); resets at UTC midnight
+	DailyBudgetUSD float64 `mapstructure:"daily_budget_usd"`
+
+	// Injection keywords used to validate decoded variants such as ROT13
+	DecodeKeywords DecodeKeywordsConfig `mapstructure:"decode_keywords"`
+
+	// How scores for the original text and its decoded variants are combined: max, mean or weighted
+	VariantAggregation string `mapstructure:"variant_aggregation"`
+
+	// Verdict when no model can classify the input: open (safe) or closed (malicious)
+	OnFailure string `mapstructure:"on_failure"`
+
+	// Overlapping windows long prompts are split into for Hugging Face classifiers
+	Chunking ChunkingConfig `mapstructure:"chunking"`
+
+	// Which part of an over-long input is kept for a model: head, tail, middle or smart (beginning and end)
+	Truncation string `mapstructure:"truncation"`
+
+	// Standard deviation of consensus model scores above which high_disagreement is reported
+	DisagreementThreshold float64 `mapstructure:"disagreement_threshold"`
+
+	// Model that decides consensus votes that split evenly or disagree; it does not vote itself
+	TieBreaker string `mapstructure:"tie_breaker"`
+
+	// Benign votes from answering models required before an input is reported safe;
+	// fewer fail closed. 0 disables. Requests run in consensus when set.
+	MinBenignVotes int `mapstructure:"min_benign_votes"`
+
+	// Instructions sent to generative models, empty uses the built-in security analyst
+	// prompt; SystemPromptFile is read into SystemPrompt at load. Models may override it.
+	SystemPrompt     string `mapstructure:"system_prompt"`
+	SystemPromptFile string `mapstructure:"system_prompt_file"`
+
+	// Retries of network errors and 502/503/504 responses within one model call
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Minimum scores for each response severity
+	Severity SeverityConfig `mapstructure:"severity"`
+
+	// Names of models to disable, from the models file or the built-in registry
+	DisabledModels []string `mapstructure:"disabled_models"`
+
+	// Query the simple pipeline's endpoints concurrently instead of in order
+	FanOut FanOutConfig `mapstructure:"fan_out"`
+
+	// Periodically check that each endpoint actually answers
+	HealthProbe HealthProbeConfig `mapstructure:"health_probe"`
+
+	// Per-model call timeouts derived from recent latencies
+	AdaptiveTimeout AdaptiveTimeoutConfig `mapstructure:"adaptive_timeout"`
+}
+
+// FanOutConfig enables concurrent endpoint queries; the first verdict scoring 0.8
+// or more wins and the remaining calls are cancelled
+type FanOutConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	MaxConcurrency int  `mapstructure:"max_concurrency"` // 0 queries every endpoint at once
+}
+
+// HealthProbeConfig enables background probes sending a trivial classification
+// to every endpoint; unreachable endpoints degrade /health. Each probe is a real
+// model call, so paid models are billed for it.
+type HealthProbeConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// AdaptiveTimeoutConfig sets each model call's timeout to its recent p99 latency
+// times factor, at least min and never more than the model's configured timeout.
+// Successful and timed-out calls feed the latency window; other failures do not.
+type AdaptiveTimeoutConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Factor     float64       `mapstructure:"factor"`
+	Min        time.Duration `mapstructure:"min"`
+	MinSamples int           `mapstructure:"min_samples"` // Calls observed before a model's timeout adapts
+	Window     int           `mapstructure:"window"`      // Most recent latencies kept per model
+}
+
+// SeverityConfig sets the score bands responses are graded into; high-confidence
+// data_extraction and system_prompt_leak detections are escalated to critical
+type SeverityConfig struct {
+	Low      float64 `mapstructure:"low"`
+	Medium   float64 `mapstructure:"medium"`
+	High     float64 `mapstructure:"high"`
+	Critical float64 `mapstructure:"critical"`
+}
+
+// RetryConfig sets how often and how patiently a transient provider failure is retried
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"` // Including the first attempt, 1 disables retries
+	BaseDelay   time.Duration `mapstructure:"base_delay"`   // Doubled for each further retry, with jitter
+	MaxDelay    time.Duration `mapstructure:"max_delay"`
+}
+
+// ChunkingConfig sets the window size and overlap, in characters, for classifying long prompts
+type ChunkingConfig struct {
+	WindowSize int `mapstructure:"window_size"` // 0 disables chunking and truncates (see truncation) instead
+	Overlap    int `mapstructure:"overlap"`
+}
+
+// DecodeKeywordsConfig lists injection keywords per language; the built-in
+// multilingual lists are used when no languages are configured
+type DecodeKeywordsConfig struct {
+	Languages  map[string][]string `mapstructure:"languages"`
+	MinMatches int                 `mapstructure:"min_matches"` // Keywords from one language needed to flag a decode
+}
+
+// HTTPClientConfig tunes the transport shared by all provider calls
+type HTTPClientConfig struct {
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DisableKeepAlives   bool          `mapstructure:"disable_keep_alives"`
+	ProxyURL            string        `mapstructure:"proxy_url"` // Overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+}
+
+// GRPCConfig controls the optional gRPC detection service
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
 }
 
 type PatternsConfig struct {
+	File           string        `mapstructure:"file"` // Attack signatures file, reloaded every UpdateInterval
 	UpdateInterval time.Duration `mapstructure:"update_interval"`
 	CacheSize      int           `mapstructure:"cache_size"`
 }
 
+// AuthConfig controls bearer-token authentication for the API
+type AuthConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	APIKeys []string `mapstructure:"api_keys"`
+}
+
+// CORSConfig controls cross-origin access to the API
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"` // "*" allows any origin
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+}
+
+// SelfTestConfig controls the startup self-test against a built-in prompt corpus
+type SelfTestConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	FailFast bool          `mapstructure:"fail_fast"` // Exit if no model can classify
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// BreakerStoreConfig selects where circuit breaker state is kept
+type BreakerStoreConfig struct {
+	Backend   string `mapstructure:"backend"`
+	RedisURL  string `mapstructure:"redis_url"` // Falls back to REDIS_URL
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// CacheConfig controls caching of detection responses for repeated text
+type CacheConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Backend   string        `mapstructure:"backend"`
+	Size      int           `mapstructure:"size"` // Entries kept in the local LRU
+	TTL       time.Duration `mapstructure:"ttl"`
+	RedisURL  string        `mapstructure:"redis_url"` // Falls back to REDIS_URL
+	KeyPrefix string        `mapstructure:"key_prefix"`
+}
+
+// RecentDetectionsConfig controls the in-memory log served at /v1/detections/recent
+type RecentDetectionsConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	Size        int  `mapstructure:"size"`
+	CaptureText int  `mapstructure:"capture_text"` // Runes of redacted text to keep, 0 keeps only a hash
+}
+
+// JobsConfig controls async batch detection jobs
+type JobsConfig struct {
+	MaxBatchSize int           `mapstructure:"max_batch_size"`
+	QueueSize    int           `mapstructure:"queue_size"` // Jobs waiting before submissions are rejected
+	TTL          time.Duration `mapstructure:"ttl"`        // How long finished results are kept
+}
+
+// WebhookConfig controls alerting on malicious detections; an empty URL disables it
+type WebhookConfig struct {
+	URL           string        `mapstructure:"url"`
+	Secret        string        `mapstructure:"secret"` // Also read from PROMPT_SHIELD_WEBHOOK_SECRET
+	MinConfidence float64       `mapstructure:"min_confidence"`
+	IncludeText   bool          `mapstructure:"include_text"`
+	MaxRetries    int           `mapstructure:"max_retries"`
+	Backoff       time.Duration `mapstructure:"backoff"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	QueueSize     int           `mapstructure:"queue_size"`
+}
+
+// ResultSinkConfig selects where detection records are persisted. Records carry
+// a hash of the analyzed text; the text itself only when include_text is set.
+type ResultSinkConfig struct {
+	Type        string `mapstructure:"type"` // "none" or "jsonl"
+	Path        string `mapstructure:"path"` // File appended to by the jsonl sink
+	IncludeText bool   `mapstructure:"include_text"`
+	QueueSize   int    `mapstructure:"queue_size"` // Pending records before new ones are dropped
+}
+
+// RedactionConfig selects the patterns masked in logs, recent detections, result
+// sink records and webhook payloads. The built-in patterns cover emails, card
+// numbers, API keys and tokens, private keys and credential assignments.
+type RedactionConfig struct {
+	DisableBuiltin bool                     `mapstructure:"disable_builtin"`
+	Patterns       []RedactionPatternConfig `mapstructure:"patterns"` // Masked in addition to the built-in patterns
+}
+
+// RedactionPatternConfig is a named regular expression whose matches are masked
+type RedactionPatternConfig struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// IdempotencyConfig controls how long responses to Idempotency-Key requests are kept
+type IdempotencyConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	TTL        time.Duration `mapstructure:"ttl"`
+	MaxEntries int           `mapstructure:"max_entries"` // Oldest responses are dropped beyond this
+}
+
 type MetricsConfig struct {
 	Enabled bool   `mapstructure:"enabled"`
 	Path    string `mapstructure:"path"`
@@ -37,13 +348,95 @@ type MetricsConfig struct {
 func Load() (*Config, error) {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.timeout", "30s")
+	viper.SetDefault("server.max_body_bytes", 4<<20)
+	viper.SetDefault("server.max_decompressed_bytes", 8<<20)
+	viper.SetDefault("server.compression_min_bytes", 1024)
+	viper.SetDefault("server.pprof", false)
+	viper.SetDefault("server.max_in_flight", 512)
+	viper.SetDefault("server.in_flight_retry_after_seconds", 1)
+	viper.SetDefault("server.grpc.enabled", false)
+	viper.SetDefault("server.grpc.port", 9090)
 	viper.SetDefault("detection.confidence_threshold", 0.5) // Lowered from 0.7 to 0.5
 	viper.SetDefault("detection.max_prompt_length", 10000)
 	viper.SetDefault("detection.worker_pool_size", 10)
+	viper.SetDefault("detection.models_file", "./configs/models.yaml")
+	viper.SetDefault("detection.pipeline", PipelineFallback)
+	viper.SetDefault("detection.endpoint_retry_delay", "100ms")
+	viper.SetDefault("detection.local_only", false)
+	viper.SetDefault("detection.session_ttl", "30m")
+	viper.SetDefault("detection.dispatch_queue_timeout", "250ms")
+	viper.SetDefault("detection.daily_budget_usd", 0.0)
+	viper.SetDefault("detection.decode_keywords.min_matches", 2)
+	viper.SetDefault("detection.variant_aggregation", "max")
+	viper.SetDefault("detection.on_failure", "open")
+	viper.SetDefault("detection.chunking.window_size", 500)
+	viper.SetDefault("detection.chunking.overlap", 100)
+	viper.SetDefault("detection.truncation", "smart")
+	viper.SetDefault("detection.disagreement_threshold", 0.3)
+	viper.SetDefault("detection.tie_breaker", "")
+	viper.SetDefault("detection.min_benign_votes", 0)
+	viper.SetDefault("detection.system_prompt", "")
+	viper.SetDefault("detection.system_prompt_file", "")
+	viper.SetDefault("detection.retry.max_attempts", 2)
+	viper.SetDefault("detection.retry.base_delay", "200ms")
+	viper.SetDefault("detection.retry.max_delay", "2s")
+	viper.SetDefault("detection.severity.low", 0.3)
+	viper.SetDefault("detection.severity.medium", 0.5)
+	viper.SetDefault("detection.severity.high", 0.7)
+	viper.SetDefault("detection.severity.critical", 0.9)
+	viper.SetDefault("detection.fan_out.enabled", false)
+	viper.SetDefault("detection.fan_out.max_concurrency", 0)
+	viper.SetDefault("detection.health_probe.enabled", false)
+	viper.SetDefault("detection.health_probe.interval", "60s")
+	viper.SetDefault("detection.health_probe.timeout", "10s")
+	viper.SetDefault("detection.adaptive_timeout.enabled", false)
+	viper.SetDefault("detection.adaptive_timeout.factor", 3.0)
+	viper.SetDefault("detection.adaptive_timeout.min", "1s")
+	viper.SetDefault("detection.adaptive_timeout.min_samples", 20)
+	viper.SetDefault("detection.adaptive_timeout.window", 100)
+	viper.SetDefault("detection.http.max_idle_conns", 100)
+	viper.SetDefault("detection.http.max_idle_conns_per_host", 10)
+	viper.SetDefault("detection.http.idle_conn_timeout", "90s")
+	viper.SetDefault("detection.http.disable_keep_alives", false)
+	viper.SetDefault("patterns.file", "./configs/signatures.yaml")
 	viper.SetDefault("patterns.update_interval", "1h")
 	viper.SetDefault("patterns.cache_size", 1000)
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("auth.enabled", false)
+	viper.SetDefault("cors.allowed_origins", []string{"*"})
+	viper.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PATCH", "OPTIONS"})
+	viper.SetDefault("selftest.enabled", false)
+	viper.SetDefault("selftest.fail_fast", false)
+	viper.SetDefault("selftest.timeout", "60s")
+	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-Request-ID", "Idempotency-Key", "If-None-Match"})
+	viper.SetDefault("breaker_store.backend", BreakerStoreMemory)
+	viper.SetDefault("breaker_store.key_prefix", "prompt-shield:breaker:")
+	viper.SetDefault("cache.enabled", false)
+	viper.SetDefault("cache.backend", CacheBackendMemory)
+	viper.SetDefault("cache.size", 10000)
+	viper.SetDefault("cache.ttl", "10m")
+	viper.SetDefault("cache.key_prefix", "prompt-shield:result:")
+	viper.SetDefault("recent_detections.enabled", false)
+	viper.SetDefault("recent_detections.size", 200)
+	viper.SetDefault("recent_detections.capture_text", 0)
+	viper.SetDefault("jobs.max_batch_size", 1000)
+	viper.SetDefault("jobs.queue_size", 100)
+	viper.SetDefault("jobs.ttl", "1h")
+	viper.SetDefault("webhook.min_confidence", 0.8)
+	viper.SetDefault("webhook.include_text", false)
+	viper.SetDefault("webhook.max_retries", 3)
+	viper.SetDefault("webhook.backoff", "1s")
+	viper.SetDefault("webhook.timeout", "5s")
+	viper.SetDefault("webhook.queue_size", 1000)
+	viper.SetDefault("result_sink.type", ResultSinkNone)
+	viper.SetDefault("result_sink.path", "./detections.jsonl")
+	viper.SetDefault("result_sink.include_text", false)
+	viper.SetDefault("result_sink.queue_size", 1000)
+	viper.SetDefault("redaction.disable_builtin", false)
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.ttl", "24h")
+	viper.SetDefault("idempotency.max_entries", 10000)
 
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -60,5 +453,187 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	switch config.Detection.Pipeline {
+	case PipelineFallback, PipelineSimple:
+	default:
+		return nil, fmt.Errorf("invalid detection.pipeline %q: must be %q or %q", config.Detection.Pipeline, PipelineFallback, PipelineSimple)
+	}
+
+	switch config.BreakerStore.Backend {
+	case BreakerStoreMemory:
+	case BreakerStoreRedis:
+		if config.BreakerStore.RedisURL == "" {
+			config.BreakerStore.RedisURL = os.Getenv("REDIS_URL")
+		}
+		if config.BreakerStore.RedisURL == "" {
+			return nil, fmt.Errorf("breaker_store.backend is %q but no redis url is configured (breaker_store.redis_url or REDIS_URL)", BreakerStoreRedis)
+		}
+	default:
+		return nil, fmt.Errorf("invalid breaker_store.backend %q: must be %q or %q", config.BreakerStore.Backend, BreakerStoreMemory, BreakerStoreRedis)
+	}
+
+	switch config.Cache.Backend {
+	case CacheBackendMemory:
+	case CacheBackendRedis:
+		if config.Cache.RedisURL == "" {
+			config.Cache.RedisURL = os.Getenv("REDIS_URL")
+		}
+		if config.Cache.Enabled && config.Cache.RedisURL == "" {
+			return nil, fmt.Errorf("cache.backend is %q but no redis url is configured (cache.redis_url or REDIS_URL)", CacheBackendRedis)
+		}
+	default:
+		return nil, fmt.Errorf("invalid cache.backend %q: must be %q or %q", config.Cache.Backend, CacheBackendMemory, CacheBackendRedis)
+	}
+
+	if proxyURL := config.Detection.HTTP.ProxyURL; proxyURL != "" {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid detection.http.proxy_url %q: must be an absolute URL such as http://proxy:3128", proxyURL)
+		}
+	}
+
+	if config.Detection.DecodeKeywords.MinMatches < 1 {
+		return nil, fmt.Errorf("invalid detection.decode_keywords.min_matches %d: must be at least 1", config.Detection.DecodeKeywords.MinMatches)
+	}
+
+	switch config.Detection.VariantAggregation {
+	case "max", "mean", "weighted":
+	default:
+		return nil, fmt.Errorf("invalid detection.variant_aggregation %q: must be max, mean or weighted", config.Detection.VariantAggregation)
+	}
+
+	switch config.Detection.OnFailure {
+	case "open", "closed":
+	default:
+		return nil, fmt.Errorf("invalid detection.on_failure %q: must be open or closed", config.Detection.OnFailure)
+	}
+
+	if chunking := config.Detection.Chunking; chunking.WindowSize < 0 || chunking.Overlap < 0 || (chunking.WindowSize > 0 && chunking.Overlap >= chunking.WindowSize) {
+		return nil, fmt.Errorf("invalid detection.chunking: window_size %d and overlap %d must not be negative, and overlap must be smaller than window_size", chunking.WindowSize, chunking.Overlap)
+	}
+
+	switch config.Detection.Truncation {
+	case "head", "tail", "middle", "smart":
+	default:
+		return nil, fmt.Errorf("invalid detection.truncation %q: must be head, tail, middle or smart", config.Detection.Truncation)
+	}
+
+	if config.Detection.DisagreementThreshold <= 0 || config.Detection.DisagreementThreshold > 0.5 {
+		return nil, fmt.Errorf("invalid detection.disagreement_threshold %v: must be greater than 0 and at most 0.5", config.Detection.DisagreementThreshold)
+	}
+
+	if retry := config.Detection.Retry; retry.MaxAttempts < 1 || retry.BaseDelay < 0 || retry.MaxDelay < retry.BaseDelay {
+		return nil, fmt.Errorf("invalid detection.retry: max_attempts %d must be at least 1, and base_delay %s must not be negative or exceed max_delay %s", retry.MaxAttempts, retry.BaseDelay, retry.MaxDelay)
+	}
+
+	if severity := config.Detection.Severity; severity.Low <= 0 || severity.Low > severity.Medium || severity.Medium > severity.High || severity.High > severity.Critical || severity.Critical > 1 {
+		return nil, fmt.Errorf("invalid detection.severity: low %v, medium %v, high %v and critical %v must be ascending between 0 and 1", severity.Low, severity.Medium, severity.High, severity.Critical)
+	}
+
+	if config.Detection.FanOut.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("invalid detection.fan_out.max_concurrency %d: must not be negative", config.Detection.FanOut.MaxConcurrency)
+	}
+
+	if probe := config.Detection.HealthProbe; probe.Enabled && (probe.Interval <= 0 || probe.Timeout <= 0) {
+		return nil, fmt.Errorf("invalid detection.health_probe: interval %s and timeout %s must be positive", probe.Interval, probe.Timeout)
+	}
+
+	if config.Server.MaxInFlight < 0 {
+		return nil, fmt.Errorf("invalid server.max_in_flight %d: must not be negative", config.Server.MaxInFlight)
+	}
+	if config.Server.MaxInFlight > 0 && config.Server.InFlightRetryAfterSeconds <= 0 {
+		return nil, fmt.Errorf("invalid server.in_flight_retry_after_seconds %d: must be positive", config.Server.InFlightRetryAfterSeconds)
+	}
+
+	switch config.ResultSink.Type {
+	case ResultSinkNone:
+	case ResultSinkJSONL:
+		if config.ResultSink.Path == "" {
+			return nil, fmt.Errorf("result_sink.path is required for the %q sink", ResultSinkJSONL)
+		}
+	default:
+		return nil, fmt.Errorf("invalid result_sink.type %q: must be %q or %q", config.ResultSink.Type, ResultSinkNone, ResultSinkJSONL)
+	}
+
+	for _, pattern := range config.Redaction.Patterns {
+		if pattern.Name == "" {
+			return nil, fmt.Errorf("invalid redaction pattern %q: name is required", pattern.Pattern)
+		}
+		if _, err := regexp.Compile(pattern.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern.Name, err)
+		}
+	}
+
+	if adaptive := config.Detection.AdaptiveTimeout; adaptive.Enabled {
+		if adaptive.Factor < 1 {
+			return nil, fmt.Errorf("invalid detection.adaptive_timeout.factor %v: must be at least 1", adaptive.Factor)
+		}
+		if adaptive.Min <= 0 || adaptive.MinSamples <= 0 || adaptive.Window < adaptive.MinSamples {
+			return nil, fmt.Errorf("invalid detection.adaptive_timeout: min %s and min_samples %d must be positive and window %d at least min_samples", adaptive.Min, adaptive.MinSamples, adaptive.Window)
+		}
+	}
+
+	if config.Detection.SystemPromptFile != "" {
+		if config.Detection.SystemPrompt != "" {
+			return nil, fmt.Errorf("detection.system_prompt and detection.system_prompt_file are mutually exclusive")
+		}
+		prompt, err := os.ReadFile(config.Detection.SystemPromptFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read detection.system_prompt_file: %w", err)
+		}
+		config.Detection.SystemPrompt = strings.TrimSpace(string(prompt))
+		if config.Detection.SystemPrompt == "" {
+			return nil, fmt.Errorf("detection.system_prompt_file %s is empty", config.Detection.SystemPromptFile)
+		}
+	}
+
+	if config.Detection.MinBenignVotes < 0 {
+		return nil, fmt.Errorf("invalid detection.min_benign_votes %d: must not be negative", config.Detection.MinBenignVotes)
+	}
+	if config.Detection.MinBenignVotes > 0 && config.Detection.Pipeline != PipelineFallback {
+		return nil, fmt.Errorf("detection.min_benign_votes requires detection.pipeline %q", PipelineFallback)
+	}
+
+	if config.Server.MaxBodyBytes <= 0 {
+		return nil, fmt.Errorf("invalid server.max_body_bytes %d: must be positive", config.Server.MaxBodyBytes)
+	}
+
+	if config.Idempotency.Enabled && config.Idempotency.TTL <= 0 {
+		return nil, fmt.Errorf("invalid idempotency.ttl %s: must be positive", config.Idempotency.TTL)
+	}
+
+	if config.Detection.DailyBudgetUSD < 0 {
+		return nil, fmt.Errorf("invalid detection.daily_budget_usd %v: must not be negative", config.Detection.DailyBudgetUSD)
+	}
+
+	if (config.Server.TLS.CertFile == "") != (config.Server.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("server.tls.cert_file and server.tls.key_file must be set together")
+	}
+
+	// Profiling can be switched on for a single run without editing config
+	if os.Getenv("PROMPT_SHIELD_PPROF") == "true" {
+		config.Server.Pprof = true
+	}
+
+	if secret := os.Getenv("PROMPT_SHIELD_WEBHOOK_SECRET"); secret != "" {
+		config.Webhook.Secret = secret
+	}
+	if config.Webhook.URL != "" && config.Webhook.Secret == "" {
+		return nil, fmt.Errorf("webhook.url is set but no signing secret is configured (webhook.secret or PROMPT_SHIELD_WEBHOOK_SECRET)")
+	}
+
+	// API keys can also be supplied as a comma-separated environment variable
+	if envKeys := os.Getenv("PROMPT_SHIELD_API_KEYS"); envKeys != "" {
+		for _, key := range strings.Split(envKeys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				config.Auth.APIKeys = append(config.Auth.APIKeys, key)
+			}
+		}
+	}
+
+	if config.Auth.Enabled && len(config.Auth.APIKeys) == 0 {
+		return nil, fmt.Errorf("auth.enabled is set but no API keys are configured (auth.api_keys or PROMPT_SHIELD_API_KEYS)")
+	}
+
 	return &config, nil
 }
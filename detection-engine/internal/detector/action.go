@@ -0,0 +1,110 @@
+package detector
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action controls what the caller wants done with a detected injection
+type Action string
+
+const (
+	ActionFlag     Action = "flag"     // Report only (default)
+	ActionBlock    Action = "block"    // Report and mark the prompt as blocked
+	ActionSanitize Action = "sanitize" // Report and return a cleaned prompt
+)
+
+// encodedPayloadPattern matches base64 runs that may hide an injection payload
+var encodedPayloadPattern = regexp.MustCompile(`[A-Za-z0-9+/]{20,}={0,2}`)
+
+// validateAction checks that an action is supported
+func validateAction(action Action) error {
+	switch action {
+	case "", ActionFlag, ActionBlock, ActionSanitize:
+		return nil
+	}
+	return fmt.Errorf("invalid action %q: must be %q, %q or %q", action, ActionFlag, ActionBlock, ActionSanitize)
+}
+
+// applyAction records the action taken and, for sanitize, attaches the cleaned input
+func applyAction(response *DetectionResponse, req *DetectionRequest, config *DetectionConfig, heuristic *HeuristicDetector) {
+	if config == nil || config.Action == "" {
+		return
+	}
+
+	response.Action = config.Action
+	if !response.IsMalicious {
+		return
+	}
+
+	switch config.Action {
+	case ActionBlock:
+		response.Blocked = true
+	case ActionSanitize:
+		if len(req.Messages) > 0 {
+			sanitized := make([]Message, len(req.Messages))
+			for i, message := range req.Messages {
+				sanitized[i] = Message{Role: message.Role, Content: heuristic.Sanitize(message.Content)}
+			}
+			response.SanitizedMessages = sanitized
+		} else {
+			response.SanitizedText = heuristic.Sanitize(req.Text)
+		}
+	}
+}
+
+// Sanitize strips spans matched by heuristic rules, including encoded payloads that decode to matches
+func (h *HeuristicDetector) Sanitize(text string) string {
+	spans := make([][2]int, 0)
+	for _, match := range h.match(text) {
+		spans = append(spans, [2]int{match.start, match.end})
+	}
+	for _, loc := range encodedPayloadPattern.FindAllStringIndex(text, -1) {
+		if decoded, err := base64.StdEncoding.DecodeString(text[loc[0]:loc[1]]); err == nil && len(h.match(string(decoded))) > 0 {
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		}
+	}
+
+	if len(spans) == 0 {
+		return text
+	}
+
+	var builder strings.Builder
+	cursor := 0
+	for _, span := range mergeSpans(spans) {
+		builder.WriteString(text[cursor:span[0]])
+		cursor = span[1]
+	}
+	builder.WriteString(text[cursor:])
+
+	// Collapse whitespace left behind by removed spans, keeping line structure
+	lines := strings.Split(builder.String(), "\n")
+	cleaned := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if collapsed := strings.Join(strings.Fields(line), " "); collapsed != "" {
+			cleaned = append(cleaned, collapsed)
+		}
+	}
+	return strings.Join(cleaned, "\n")
+}
+
+// mergeSpans sorts and merges overlapping [start, end) spans
+func mergeSpans(spans [][2]int) [][2]int {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	merged := [][2]int{spans[0]}
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1] {
+			if span[1] > last[1] {
+				last[1] = span[1]
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
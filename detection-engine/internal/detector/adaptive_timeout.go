@@ -0,0 +1,128 @@
+package detector
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptiveTimeout derives each model's call timeout from its recent latencies so
+// fast models fail fast and slow ones get slack, never exceeding the model's
+// configured timeout
+type AdaptiveTimeout struct {
+	Enabled    bool
+	Factor     float64       // Multiplier applied to the p99 latency
+	Min        time.Duration // Shortest timeout ever applied
+	MinSamples int           // Latencies needed before a model's timeout adapts
+	Window     int           // Most recent latencies kept per model
+}
+
+// DefaultAdaptiveTimeout returns the adaptive timeout settings used when enabled without tuning
+func DefaultAdaptiveTimeout() AdaptiveTimeout {
+	return AdaptiveTimeout{
+		Factor:     3,
+		Min:        time.Second,
+		MinSamples: 20,
+		Window:     100,
+	}
+}
+
+// withDefaults fills unset settings from DefaultAdaptiveTimeout
+func (a AdaptiveTimeout) withDefaults() AdaptiveTimeout {
+	defaults := DefaultAdaptiveTimeout()
+	if a.Factor <= 0 {
+		a.Factor = defaults.Factor
+	}
+	if a.Min <= 0 {
+		a.Min = defaults.Min
+	}
+	if a.MinSamples <= 0 {
+		a.MinSamples = defaults.MinSamples
+	}
+	if a.Window <= 0 {
+		a.Window = defaults.Window
+	}
+	return a
+}
+
+// latencyTracker keeps a rolling window of call latencies per model
+type latencyTracker struct {
+	settings AdaptiveTimeout
+	windows  map[string]*latencyWindow
+	mutex    sync.Mutex
+}
+
+// latencyWindow is a fixed-size ring of a model's most recent latencies
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// newLatencyTracker creates a tracker, nil when adaptive timeouts are disabled
+func newLatencyTracker(settings AdaptiveTimeout) *latencyTracker {
+	if !settings.Enabled {
+		return nil
+	}
+	return &latencyTracker{
+		settings: settings.withDefaults(),
+		windows:  make(map[string]*latencyWindow),
+	}
+}
+
+// observe records a call's latency when it says something about the model's speed:
+// successes and timeouts count, fast failures such as auth errors do not
+func (t *latencyTracker) observe(model string, latency time.Duration, err error) {
+	if t == nil || (err != nil && !errors.Is(err, ErrTimeout)) {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	window, exists := t.windows[model]
+	if !exists {
+		window = &latencyWindow{samples: make([]time.Duration, t.settings.Window)}
+		t.windows[model] = window
+	}
+	window.samples[window.next] = latency
+	window.next = (window.next + 1) % len(window.samples)
+	if window.next == 0 {
+		window.full = true
+	}
+}
+
+// timeout returns the adaptive timeout for a model: its p99 latency times the
+// factor, at least Min and at most limit. It returns 0 (no adaptive timeout)
+// until MinSamples latencies have been observed.
+func (t *latencyTracker) timeout(model string, limit time.Duration) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.mutex.Lock()
+	window, exists := t.windows[model]
+	var samples []time.Duration
+	if exists {
+		count := window.next
+		if window.full {
+			count = len(window.samples)
+		}
+		samples = append(samples, window.samples[:count]...)
+	}
+	t.mutex.Unlock()
+
+	if len(samples) < t.settings.MinSamples {
+		return 0
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	p99 := percentile(samples, 0.99)
+
+	timeout := max(time.Duration(float64(p99)*t.settings.Factor), t.settings.Min)
+	if limit > 0 {
+		timeout = min(timeout, limit)
+	}
+	return timeout
+}
@@ -0,0 +1,133 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// repeated returns n copies of latency
+func repeated(latency time.Duration, n int) []time.Duration {
+	samples := make([]time.Duration, n)
+	for i := range samples {
+		samples[i] = latency
+	}
+	return samples
+}
+
+func TestAdaptiveTimeoutFromSamples(t *testing.T) {
+	settings := AdaptiveTimeout{Enabled: true, Factor: 2, Min: 100 * time.Millisecond, MinSamples: 5, Window: 10}
+
+	spread := make([]time.Duration, 100)
+	for i := range spread {
+		spread[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := map[string]struct {
+		settings AdaptiveTimeout
+		samples  []time.Duration
+		limit    time.Duration
+		want     time.Duration
+	}{
+		"too few samples":   {settings, repeated(200*time.Millisecond, 4), 5 * time.Second, 0},
+		"p99 times factor":  {settings, repeated(200*time.Millisecond, 5), 5 * time.Second, 400 * time.Millisecond},
+		"p99 of a spread":   {AdaptiveTimeout{Enabled: true, Factor: 2, Min: time.Millisecond, MinSamples: 5, Window: 100}, spread, 5 * time.Second, 198 * time.Millisecond},
+		"capped by limit":   {settings, repeated(3*time.Second, 5), 5 * time.Second, 5 * time.Second},
+		"uncapped":          {settings, repeated(3*time.Second, 5), 0, 6 * time.Second},
+		"floored at min":    {settings, repeated(time.Millisecond, 5), 5 * time.Second, 100 * time.Millisecond},
+		"window rolls over": {settings, append(repeated(time.Second, 10), repeated(80*time.Millisecond, 10)...), 5 * time.Second, 160 * time.Millisecond},
+		"default settings":  {AdaptiveTimeout{Enabled: true}, repeated(500*time.Millisecond, 20), 15 * time.Second, 1500 * time.Millisecond},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tracker := newLatencyTracker(tt.settings)
+			for _, latency := range tt.samples {
+				tracker.observe("classifier", latency, nil)
+			}
+			if got := tracker.timeout("classifier", tt.limit); got != tt.want {
+				t.Errorf("timeout = %s, want %s", got, tt.want)
+			}
+			if got := tracker.timeout("unobserved", tt.limit); got != 0 {
+				t.Errorf("timeout for an unobserved model = %s, want 0", got)
+			}
+		})
+	}
+}
+
+func TestAdaptiveTimeoutIgnoresFastFailures(t *testing.T) {
+	tracker := newLatencyTracker(AdaptiveTimeout{Enabled: true, Factor: 2, Min: time.Millisecond, MinSamples: 5, Window: 10})
+
+	// Rejected keys fail fast and say nothing about the model's speed
+	auth := &ProviderError{Category: ErrorCategoryAuth, Message: "invalid key"}
+	for i := 0; i < 5; i++ {
+		tracker.observe("classifier", 5*time.Millisecond, auth)
+	}
+	if got := tracker.timeout("classifier", 5*time.Second); got != 0 {
+		t.Errorf("timeout after auth failures = %s, want none", got)
+	}
+
+	// Timeouts count, so a slowing model earns a longer timeout
+	for i := 0; i < 5; i++ {
+		tracker.observe("classifier", 400*time.Millisecond, ErrTimeout)
+	}
+	if got := tracker.timeout("classifier", 5*time.Second); got != 800*time.Millisecond {
+		t.Errorf("timeout after timeouts = %s, want 800ms", got)
+	}
+}
+
+func TestAdaptiveTimeoutDisabled(t *testing.T) {
+	tracker := newLatencyTracker(AdaptiveTimeout{Factor: 2, MinSamples: 1})
+	if tracker != nil {
+		t.Fatalf("tracker = %+v, want nil when disabled", tracker)
+	}
+	tracker.observe("classifier", time.Second, nil)
+	if got := tracker.timeout("classifier", 5*time.Second); got != 0 {
+		t.Errorf("timeout = %s, want none when disabled", got)
+	}
+}
+
+func TestAdaptiveTimeoutCutsOffSlowCall(t *testing.T) {
+	var delay atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(time.Duration(delay.Load())):
+		case <-r.Context().Done():
+			return
+		}
+		json.NewEncoder(w).Encode([][]HuggingFaceLabel{{{Label: "SAFE", Score: 0.99}}})
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv(testAPIKeyEnv, "test-key")
+	config := DefaultLLMDetectorConfig()
+	config.EndpointDelay = 0
+	config.Retry = RetryPolicy{MaxAttempts: 1}
+	config.AdaptiveTimeout = AdaptiveTimeout{Enabled: true, Factor: 2, Min: 50 * time.Millisecond, MinSamples: 5, Window: 10}
+	model := testModel("classifier", ProviderHuggingFace, server.URL)
+	config.Models = []ModelConfig{model}
+	detector := NewLLMDetectorWithConfig(config)
+
+	// Fast answers teach the detector the model's normal latency
+	for i := 0; i < 5; i++ {
+		if _, err := detector.detectWithSpecificEndpoint(context.Background(), "hello", model, variantScopeOriginal); err != nil {
+			t.Fatalf("warm-up call %d: %v", i, err)
+		}
+	}
+
+	// A stall well past the learned latency fails fast instead of waiting out the 5s limit
+	delay.Store(int64(2 * time.Second))
+	start := time.Now()
+	if _, err := detector.detectWithSpecificEndpoint(context.Background(), "hello", model, variantScopeOriginal); err == nil {
+		t.Fatal("stalled call succeeded, want it cut off by the adaptive timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stalled call took %s, want it cut off near the 50ms adaptive timeout", elapsed)
+	}
+}
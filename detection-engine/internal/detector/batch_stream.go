@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchItem is the outcome of one text in a streamed batch
+type BatchItem struct {
+	Index    int                `json:"index"`
+	Response *DetectionResponse `json:"result,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// copyConfig returns a shallow copy of config so a request can own it, nil for nil
+func copyConfig(config *DetectionConfig) *DetectionConfig {
+	if config == nil {
+		return nil
+	}
+	copied := *config
+	return &copied
+}
+
+// StreamBatch analyzes texts with up to workers concurrent detections and delivers each
+// outcome as soon as it completes. The channel is closed once every text is done, or once
+// ctx is cancelled, in which case texts not yet started are skipped.
+func StreamBatch(ctx context.Context, analyzer Analyzer, texts []string, config *DetectionConfig, workers int) <-chan BatchItem {
+	items := make(chan BatchItem, len(texts))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(texts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				item := BatchItem{Index: i}
+				response, err := analyzer.Analyze(ctx, &DetectionRequest{Text: texts[i], Config: copyConfig(config)})
+				if err != nil {
+					item.Error = err.Error()
+				} else {
+					item.Response = response
+				}
+				items <- item
+			}
+		}()
+	}
+
+	go func() {
+		defer close(items)
+		defer wg.Wait()
+		defer close(indexes)
+
+		for i := range texts {
+			select {
+			case <-ctx.Done():
+				return
+			case indexes <- i:
+			}
+		}
+	}()
+
+	return items
+}
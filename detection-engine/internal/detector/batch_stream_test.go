@@ -0,0 +1,36 @@
+package detector
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStreamBatchStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var analyzed atomic.Int32
+	analyzer := analyzerFunc(func(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error) {
+		analyzed.Add(1)
+		return &DetectionResponse{ThreatTypes: []string{}}, nil
+	})
+
+	texts := make([]string, 100)
+	for i := range texts {
+		texts[i] = "hello"
+	}
+	items := StreamBatch(ctx, analyzer, texts, nil, 1)
+
+	// The client goes away after the first result
+	<-items
+	cancel()
+	remaining := 0
+	for range items {
+		remaining++
+	}
+
+	if total := int(analyzed.Load()); total >= len(texts) || remaining+1 != total {
+		t.Errorf("analyzed %d of %d texts with %d streamed after cancelling, want outstanding work dropped", total, len(texts), remaining)
+	}
+}
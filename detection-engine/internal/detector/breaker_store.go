@@ -0,0 +1,121 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sharedStateTimeout bounds each round trip to the shared breaker store so a slow
+// store degrades to local breaker state instead of stalling detection
+const sharedStateTimeout = 200 * time.Millisecond
+
+// BreakerStateStore shares circuit breaker failure counts and open state between instances.
+// Errors are never fatal: breakers fall back to their local in-memory state.
+type BreakerStateStore interface {
+	// IncrementFailures atomically records a failure and returns the shared consecutive count
+	IncrementFailures(ctx context.Context, name string, ttl time.Duration) (int64, error)
+	// ResetFailures clears the shared consecutive failure count
+	ResetFailures(ctx context.Context, name string) error
+	// Open marks the breaker open for every instance until the given time
+	Open(ctx context.Context, name string, until time.Time) error
+	// OpenUntil returns when the shared open state ends, zero if the breaker isn't open
+	OpenUntil(ctx context.Context, name string) (time.Time, error)
+	// Clear removes all shared state for the breaker
+	Clear(ctx context.Context, name string) error
+}
+
+// RedisBreakerStore keeps breaker state in Redis using atomic counters and expiring keys
+type RedisBreakerStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisBreakerStore connects to Redis and verifies it is reachable
+func NewRedisBreakerStore(ctx context.Context, url, keyPrefix string) (*RedisBreakerStore, error) {
+	client, err := connectRedis(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisBreakerStore{client: client, keyPrefix: keyPrefix}, nil
+}
+
+// connectRedis opens a client for url and pings it so unreachable servers fail fast
+func connectRedis(ctx context.Context, url string) (*redis.Client, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis unreachable: %w", err)
+	}
+
+	return client, nil
+}
+
+// IncrementFailures increments the failure counter and refreshes its expiry in one transaction
+func (s *RedisBreakerStore) IncrementFailures(ctx context.Context, name string, ttl time.Duration) (int64, error) {
+	var failures *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(ctx, s.failuresKey(name))
+		pipe.PExpire(ctx, s.failuresKey(name), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return failures.Val(), nil
+}
+
+// ResetFailures deletes the failure counter
+func (s *RedisBreakerStore) ResetFailures(ctx context.Context, name string) error {
+	return s.client.Del(ctx, s.failuresKey(name)).Err()
+}
+
+// Open stores the reopen deadline with a matching expiry so the key disappears when it passes
+func (s *RedisBreakerStore) Open(ctx context.Context, name string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.openKey(name), until.UnixMilli(), ttl).Err()
+}
+
+// OpenUntil reads the reopen deadline, returning zero when no instance has opened the breaker
+func (s *RedisBreakerStore) OpenUntil(ctx context.Context, name string) (time.Time, error) {
+	millis, err := s.client.Get(ctx, s.openKey(name)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// Clear deletes both the failure counter and the open state
+func (s *RedisBreakerStore) Clear(ctx context.Context, name string) error {
+	return s.client.Del(ctx, s.failuresKey(name), s.openKey(name)).Err()
+}
+
+// Close releases the Redis connection pool
+func (s *RedisBreakerStore) Close() error {
+	return s.client.Close()
+}
+
+// failuresKey returns the key holding a breaker's shared failure count
+func (s *RedisBreakerStore) failuresKey(name string) string {
+	return s.keyPrefix + name + ":failures"
+}
+
+// openKey returns the key holding a breaker's shared reopen deadline
+func (s *RedisBreakerStore) openKey(name string) string {
+	return s.keyPrefix + name + ":open_until"
+}
@@ -0,0 +1,146 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisBreakerStore connects a breaker store to a fresh in-process Redis
+func newTestRedisBreakerStore(t *testing.T) (*RedisBreakerStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	store, err := NewRedisBreakerStore(context.Background(), "redis://"+server.Addr(), "breaker:")
+	if err != nil {
+		t.Fatalf("NewRedisBreakerStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+// newSharedBreaker creates a breaker that shares its state through store
+func newSharedBreaker(store BreakerStateStore) *CircuitBreaker {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "shared-model",
+		FailureThreshold: 3,
+		SuccessThreshold: 1,
+		Timeout:          time.Minute,
+		MaxTimeout:       time.Hour,
+	})
+	cb.SetStateStore(store)
+	return cb
+}
+
+func TestSharedBreakersConvergeOnOpen(t *testing.T) {
+	store, _ := newTestRedisBreakerStore(t)
+	first := newSharedBreaker(store)
+	second := newSharedBreaker(store)
+
+	// Neither instance sees enough failures on its own
+	first.Call(failWith(errUpstream))
+	first.Call(failWith(errUpstream))
+	if state := first.GetState(); state != CircuitClosed {
+		t.Fatalf("first state = %v after 2 of 3 failures, want closed", state)
+	}
+
+	second.Call(failWith(errUpstream))
+	if state := second.GetState(); state != CircuitOpen {
+		t.Fatalf("second state = %v after the third shared failure, want open", state)
+	}
+
+	// The first instance picks up the shared open state before calling the provider
+	called := false
+	err := first.Call(func() error {
+		called = true
+		return nil
+	})
+	if err != ErrCircuitOpen || called {
+		t.Errorf("first Call = %v (provider called: %v), want ErrCircuitOpen without a call", err, called)
+	}
+	if state := first.GetState(); state != CircuitOpen {
+		t.Errorf("first state = %v, want open like the second instance", state)
+	}
+}
+
+func TestSharedBreakerSuccessResetsFailures(t *testing.T) {
+	store, _ := newTestRedisBreakerStore(t)
+	first := newSharedBreaker(store)
+	second := newSharedBreaker(store)
+
+	first.Call(failWith(errUpstream))
+	first.Call(failWith(errUpstream))
+	second.Call(succeed)
+	second.Call(failWith(errUpstream))
+
+	if state := second.GetState(); state != CircuitClosed {
+		t.Errorf("state = %v, want closed: the success in between reset the shared count", state)
+	}
+}
+
+func TestSharedBreakerFallsBackWhenRedisIsDown(t *testing.T) {
+	store, server := newTestRedisBreakerStore(t)
+	cb := newSharedBreaker(store)
+	server.Close()
+
+	if err := cb.Call(succeed); err != nil {
+		t.Fatalf("Call with Redis down = %v, want local state to decide", err)
+	}
+	for i := 0; i < 3; i++ {
+		cb.Call(failWith(errUpstream))
+	}
+	if state := cb.GetState(); state != CircuitOpen {
+		t.Errorf("state = %v after 3 local failures with Redis down, want open", state)
+	}
+}
+
+func TestRedisBreakerStore(t *testing.T) {
+	store, server := newTestRedisBreakerStore(t)
+	ctx := context.Background()
+
+	for want := int64(1); want <= 2; want++ {
+		if got, err := store.IncrementFailures(ctx, "model", time.Minute); err != nil || got != want {
+			t.Fatalf("IncrementFailures = %d, %v, want %d", got, err, want)
+		}
+	}
+	if ttl := server.TTL("breaker:model:failures"); ttl != time.Minute {
+		t.Errorf("failure counter TTL = %v, want 1m", ttl)
+	}
+	server.FastForward(time.Minute)
+	if got, _ := store.IncrementFailures(ctx, "model", time.Minute); got != 1 {
+		t.Errorf("IncrementFailures after expiry = %d, want a fresh count", got)
+	}
+
+	if until, err := store.OpenUntil(ctx, "model"); err != nil || !until.IsZero() {
+		t.Errorf("OpenUntil before Open = %v, %v, want zero", until, err)
+	}
+	until := time.Now().Add(30 * time.Second).Truncate(time.Millisecond)
+	if err := store.Open(ctx, "model", until); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got, err := store.OpenUntil(ctx, "model"); err != nil || !got.Equal(until) {
+		t.Errorf("OpenUntil = %v, %v, want %v", got, err, until)
+	}
+
+	if err := store.Clear(ctx, "model"); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if server.Exists("breaker:model:failures") || server.Exists("breaker:model:open_until") {
+		t.Error("Clear left shared state behind")
+	}
+}
+
+func TestNewRedisBreakerStoreUnreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := NewRedisBreakerStore(context.Background(), "redis://"+addr, "breaker:"); err == nil {
+		t.Error("NewRedisBreakerStore connected to a stopped server")
+	}
+	if _, err := NewRedisBreakerStore(context.Background(), "not a url", "breaker:"); err == nil {
+		t.Error("NewRedisBreakerStore accepted an invalid url")
+	}
+}
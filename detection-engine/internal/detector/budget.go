@@ -0,0 +1,50 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when no time is left in the request's timeout budget for another model attempt
+var ErrBudgetExhausted = errors.New("request timeout budget exhausted")
+
+// requestDeadline returns when the caller's per-request timeout runs out, or the zero time if none was set
+func requestDeadline(config *DetectionConfig, startTime time.Time) time.Time {
+	if config == nil || config.TimeoutMs <= 0 {
+		return time.Time{}
+	}
+	return startTime.Add(time.Duration(config.TimeoutMs) * time.Millisecond)
+}
+
+// attemptTimeout returns the timeout for the next of remainingAttempts sequential model calls:
+// the model's configured timeout, capped by an even share of the time left before the budget
+// deadline and by the time left before the context's own deadline
+func attemptTimeout(ctx context.Context, model ModelConfig, deadline time.Time, remainingAttempts int) time.Duration {
+	timeout := model.Timeout
+	if !deadline.IsZero() {
+		share := time.Until(deadline) / time.Duration(max(remainingAttempts, 1))
+		if timeout <= 0 || share < timeout {
+			timeout = share
+		}
+	}
+
+	// Never start an attempt that would outlive the caller
+	if ctxDeadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(ctxDeadline); timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	return max(timeout, 0)
+}
+
+// capTimeouts returns copies of the models whose timeouts do not run past the budget or context deadline
+func capTimeouts(ctx context.Context, models []ModelConfig, deadline time.Time) []ModelConfig {
+	capped := make([]ModelConfig, len(models))
+	for i, model := range models {
+		model.Timeout = attemptTimeout(ctx, model, deadline, 1)
+		capped[i] = model
+	}
+	return capped
+}
@@ -0,0 +1,170 @@
+package detector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAttemptTimeout(t *testing.T) {
+	model := ModelConfig{Timeout: 2 * time.Second}
+
+	tests := map[string]struct {
+		model             ModelConfig
+		budget            time.Duration // 0 sets no request deadline
+		remainingAttempts int
+		want              time.Duration
+	}{
+		"no budget":               {model, 0, 3, 2 * time.Second},
+		"budget above the model":  {model, 10 * time.Second, 1, 2 * time.Second},
+		"budget below the model":  {model, time.Second, 1, time.Second},
+		"budget split evenly":     {model, 3 * time.Second, 3, time.Second},
+		"model without a timeout": {ModelConfig{}, 900 * time.Millisecond, 3, 300 * time.Millisecond},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var deadline time.Time
+			if tt.budget > 0 {
+				deadline = time.Now().Add(tt.budget)
+			}
+			got := attemptTimeout(context.Background(), tt.model, deadline, tt.remainingAttempts)
+			// time.Until runs slightly after the deadline was computed
+			if got > tt.want || got < tt.want-50*time.Millisecond {
+				t.Errorf("attemptTimeout = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := attemptTimeout(context.Background(), model, time.Now().Add(-time.Second), 1); got != 0 {
+		t.Errorf("attemptTimeout past the deadline = %v, want 0", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if got := attemptTimeout(ctx, model, time.Time{}, 1); got > 100*time.Millisecond {
+		t.Errorf("attemptTimeout under a 100ms context = %v, want it capped by the context", got)
+	}
+}
+
+func TestRequestDeadline(t *testing.T) {
+	start := time.Now()
+	if got := requestDeadline(&DetectionConfig{TimeoutMs: 250}, start); !got.Equal(start.Add(250 * time.Millisecond)) {
+		t.Errorf("requestDeadline = %v, want start + 250ms", got)
+	}
+	if got := requestDeadline(&DetectionConfig{}, start); !got.IsZero() {
+		t.Errorf("requestDeadline without timeout_ms = %v, want none", got)
+	}
+	if got := requestDeadline(nil, start); !got.IsZero() {
+		t.Errorf("requestDeadline without config = %v, want none", got)
+	}
+}
+
+// newSlowModelPipeline builds a pipeline whose "slow" model stalls for its whole timeout and whose
+// "fast" model answers at once, recording the timeout each attempt was given
+func newSlowModelPipeline(t *testing.T, slowTimeout time.Duration) (*FallbackPipeline, func() map[string]time.Duration) {
+	slow := testModel("slow", providerFake, "")
+	slow.Timeout = slowTimeout
+	fast := testModel("fast", providerFake, "")
+	fast.Priority = 2
+
+	var mutex sync.Mutex
+	timeouts := make(map[string]time.Duration)
+
+	pipeline := newTestFallbackPipeline(t, slow, fast)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		mutex.Lock()
+		timeouts[model.Name] = model.Timeout
+		mutex.Unlock()
+
+		if model.Name == "slow" {
+			time.Sleep(model.Timeout)
+			return nil, &ProviderError{Category: ErrorCategoryTimeout, Message: "timed out"}
+		}
+		return &DetectionResult{Method: MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+	}))
+
+	return pipeline, func() map[string]time.Duration {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return timeouts
+	}
+}
+
+func TestPerRequestTimeoutSplitsBudget(t *testing.T) {
+	pipeline, timeouts := newSlowModelPipeline(t, 10*time.Second)
+
+	start := time.Now()
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello", Config: &DetectionConfig{TimeoutMs: 400}})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	// The slow model gets half the budget, leaving the rest for the fallback
+	if response.Endpoint != "fast" {
+		t.Errorf("Endpoint = %q, want the fallback to answer within the budget", response.Endpoint)
+	}
+	got := timeouts()
+	if got["slow"] > 200*time.Millisecond || got["slow"] < 150*time.Millisecond {
+		t.Errorf("slow model timeout = %v, want about half of the 400ms budget", got["slow"])
+	}
+	if got["fast"] > 250*time.Millisecond || got["fast"] <= 0 {
+		t.Errorf("fast model timeout = %v, want the remaining budget", got["fast"])
+	}
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("Analyze took %v, want it within the 400ms budget", elapsed)
+	}
+}
+
+func TestPerRequestTimeoutNeverExceedsModelTimeout(t *testing.T) {
+	pipeline, timeouts := newSlowModelPipeline(t, 50*time.Millisecond)
+
+	if _, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello", Config: &DetectionConfig{TimeoutMs: 10000}}); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if got := timeouts()["slow"]; got != 50*time.Millisecond {
+		t.Errorf("slow model timeout = %v, want its configured 50ms despite the larger budget", got)
+	}
+}
+
+func TestFallbackStaysWithinCallerDeadline(t *testing.T) {
+	models := make([]ModelConfig, 3)
+	for i, name := range []string{"slow-1", "slow-2", "slow-3"} {
+		models[i] = testModel(name, providerFake, "")
+		models[i].Priority = i + 1
+		models[i].Timeout = 5 * time.Second
+	}
+
+	var mutex sync.Mutex
+	var attempted []string
+	pipeline := newTestFallbackPipeline(t, models...)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		mutex.Lock()
+		attempted = append(attempted, model.Name)
+		mutex.Unlock()
+
+		// Every model stalls for its whole timeout, as a provider client ignoring the context would
+		time.Sleep(model.Timeout)
+		return nil, &ProviderError{Category: ErrorCategoryTimeout, Message: "timed out"}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := pipeline.Analyze(ctx, &DetectionRequest{Text: "hello"})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Analyze succeeded, want an error when every model stalls")
+	}
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("Analyze took %v with a 300ms deadline and three 5s models, want it within the deadline", elapsed)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(attempted) == 0 || attempted[0] != "slow-1" {
+		t.Errorf("attempted = %v, want the models tried in priority order", attempted)
+	}
+}
@@ -0,0 +1,43 @@
+package detector
+
+// TextChunking controls how long inputs are split into overlapping windows for
+// classifiers that only accept a short input
+type TextChunking struct {
+	WindowSize int // Characters per window, 0 disables chunking
+	Overlap    int // Characters shared by consecutive windows so an attack on a boundary stays whole
+}
+
+// DefaultTextChunking returns the window settings used when none are configured
+func DefaultTextChunking() TextChunking {
+	return TextChunking{WindowSize: 500, Overlap: 100}
+}
+
+// textChunk is one window of the input with its character offsets
+type textChunk struct {
+	text       string
+	start, end int
+}
+
+// chunkText splits text into windows of at most WindowSize characters, each starting
+// WindowSize-Overlap characters after the previous one. Text that fits in one window,
+// or chunking that is disabled, yields a single chunk holding the whole text.
+func chunkText(text string, chunking TextChunking) []textChunk {
+	runes := []rune(text)
+	if chunking.WindowSize <= 0 || len(runes) <= chunking.WindowSize {
+		return []textChunk{{text: text, start: 0, end: len(runes)}}
+	}
+
+	stride := chunking.WindowSize - chunking.Overlap
+	if stride <= 0 {
+		stride = chunking.WindowSize
+	}
+
+	chunks := make([]textChunk, 0, len(runes)/stride+1)
+	for start := 0; ; start += stride {
+		end := min(start+chunking.WindowSize, len(runes))
+		chunks = append(chunks, textChunk{text: string(runes[start:end]), start: start, end: end})
+		if end == len(runes) {
+			return chunks
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package detector
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 	
@@ -16,6 +18,20 @@ const (
 	CircuitHalfOpen                     // Testing if service recovered
 )
 
+// String returns the human-readable name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "CLOSED"
+	case CircuitOpen:
+		return "OPEN"
+	case CircuitHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // CircuitBreaker implements the circuit breaker pattern for AI model endpoints
 type CircuitBreaker struct {
 	name                string
@@ -26,12 +42,36 @@ type CircuitBreaker struct {
 	consecutiveFailures int
 	consecutiveSuccesses int
 	lastFailureTime     time.Time
+	openUntil           time.Time // Provider-requested reopen time (Retry-After), zero if unset
 	state               CircuitState
 	mutex               sync.RWMutex
 	totalRequests       int64
 	successfulRequests  int64
 	failedRequests      int64
 	metricsCollector    *metrics.MetricsCollector
+
+	// Optional state shared with other instances, nil keeps state purely in memory
+	stateStore BreakerStateStore
+
+	// Half-open probing: at most halfOpenMaxRequests calls test a recovering provider at once
+	halfOpenMaxRequests int
+	halfOpenInFlight    int
+
+	// Rolling window of recent outcomes (true = failure), disabled when windowSize is 0
+	windowSize         int
+	windowFailureRatio float64
+	window             []bool
+	windowNext         int
+	windowFilled       int
+
+	// Transition hook, called outside the mutex with transitions queued while it was held
+	onStateChange      func(name string, from, to CircuitState)
+	pendingTransitions []circuitTransition
+}
+
+// circuitTransition is a state change waiting to be reported to the OnStateChange hook
+type circuitTransition struct {
+	from, to CircuitState
 }
 
 // CircuitBreakerConfig holds configuration for circuit breaker
@@ -41,34 +81,76 @@ type CircuitBreakerConfig struct {
 	SuccessThreshold int
 	Timeout          time.Duration
 	MaxTimeout       time.Duration
+
+	// Concurrent probe requests allowed while half-open (default 1)
+	HalfOpenMaxRequests int
+
+	// Optional rolling-window rule: open when the failure ratio over the last WindowSize
+	// requests reaches WindowFailureRatio, in addition to the consecutive-failure rule
+	WindowSize         int
+	WindowFailureRatio float64
+
+	// Optional hook invoked on every state transition, outside the breaker's lock
+	OnStateChange func(name string, from, to CircuitState)
 }
 
 // NewCircuitBreaker creates a new circuit breaker with the given configuration
 func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
-	return &CircuitBreaker{
+	cb := &CircuitBreaker{
 		name:             config.Name,
 		failureThreshold: config.FailureThreshold,
 		successThreshold: config.SuccessThreshold,
 		timeout:          config.Timeout,
 		maxTimeout:       config.MaxTimeout,
 		state:            CircuitClosed,
+
+		halfOpenMaxRequests: max(config.HalfOpenMaxRequests, 1),
+		onStateChange:       config.OnStateChange,
 	}
+	if config.WindowSize > 0 && config.WindowFailureRatio > 0 {
+		cb.windowSize = config.WindowSize
+		cb.windowFailureRatio = config.WindowFailureRatio
+		cb.window = make([]bool, config.WindowSize)
+	}
+	return cb
 }
 
 // Call executes a function through the circuit breaker
 func (cb *CircuitBreaker) Call(fn func() error) error {
-	if !cb.allowRequest() {
+	defer cb.flushTransitions()
+
+	cb.syncSharedState()
+	allowed, probe := cb.allowRequest()
+	if !allowed {
 		return ErrCircuitOpen
 	}
 
 	cb.incrementTotalRequests()
-	err := fn()
-	cb.recordResult(err == nil)
+	err := cb.callGuarded(fn, probe)
+	if isBreakerNeutral(err) {
+		// Misconfiguration or a cold start is not an outage - leave breaker state untouched
+		cb.releaseProbe(probe)
+		return err
+	}
+	oldState := cb.recordResult(err, probe)
+	cb.publishResult(err, oldState)
 	return err
 }
 
-// allowRequest determines if a request should be allowed through
-func (cb *CircuitBreaker) allowRequest() bool {
+// callGuarded runs fn, releasing a held half-open probe slot before re-raising a panic
+// so the breaker is not left refusing every later probe
+func (cb *CircuitBreaker) callGuarded(fn func() error, probe bool) error {
+	defer func() {
+		if r := recover(); r != nil {
+			cb.releaseProbe(probe)
+			panic(r)
+		}
+	}()
+	return fn()
+}
+
+// allowRequest determines if a request should be allowed through and whether it is a half-open probe
+func (cb *CircuitBreaker) allowRequest() (bool, bool) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
@@ -77,34 +159,69 @@ func (cb *CircuitBreaker) allowRequest() bool {
 
 	switch cb.state {
 	case CircuitClosed:
-		return true
+		return true, false
 	case CircuitOpen:
 		// Check if timeout has passed to try half-open
-		if now.Sub(cb.lastFailureTime) > cb.timeout {
+		if cb.openDurationElapsed(now) {
 			cb.state = CircuitHalfOpen
 			cb.consecutiveSuccesses = 0
+			cb.openUntil = time.Time{}
+			cb.halfOpenInFlight++
 			
 			// Record state transition
-			if cb.metricsCollector != nil {
-				cb.metricsCollector.RecordCircuitBreakerTransition(cb.name, cb.stateToString(oldState), cb.stateToString(cb.state))
-			}
+			cb.recordTransition(oldState, cb.state)
 			
-			return true
+			return true, true
 		}
-		return false
+		return false, false
 	case CircuitHalfOpen:
-		return true
+		// Only a few probes may test the provider until their results are known
+		if cb.halfOpenInFlight >= cb.halfOpenMaxRequests {
+			return false, false
+		}
+		cb.halfOpenInFlight++
+		return true, true
 	default:
-		return false
+		return false, false
 	}
 }
 
-// recordResult records the result of a request and updates circuit state
-func (cb *CircuitBreaker) recordResult(success bool) {
+// releaseProbe frees a half-open probe slot without recording a result
+func (cb *CircuitBreaker) releaseProbe(probe bool) {
+	if !probe {
+		return
+	}
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
+	cb.releaseProbeLocked()
+}
+
+// releaseProbeLocked frees a half-open probe slot; the caller must hold the mutex
+func (cb *CircuitBreaker) releaseProbeLocked() {
+	if cb.halfOpenInFlight > 0 {
+		cb.halfOpenInFlight--
+	}
+}
+
+// openDurationElapsed reports whether an open circuit has waited long enough to probe again
+func (cb *CircuitBreaker) openDurationElapsed(now time.Time) bool {
+	return !now.Before(cb.openDeadline())
+}
+
+// recordResult records the result of a request and updates circuit state, returning the previous state
+func (cb *CircuitBreaker) recordResult(err error, probe bool) CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if probe {
+		cb.releaseProbeLocked()
+	}
 
 	oldState := cb.state
+	success := err == nil
+	if cb.state == CircuitClosed {
+		cb.recordWindowOutcome(!success)
+	}
 
 	if success {
 		cb.consecutiveFailures = 0
@@ -122,8 +239,14 @@ func (cb *CircuitBreaker) recordResult(success bool) {
 		cb.failedRequests++
 		cb.lastFailureTime = time.Now()
 
-		// If failures exceed threshold, open circuit
-		if cb.consecutiveFailures >= cb.failureThreshold {
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			// Provider told us how long to back off - stay open at least that long,
+			// and no shorter than the current backoff, without escalating it
+			cb.state = CircuitOpen
+			cb.openUntil = cb.lastFailureTime.Add(max(rateLimitErr.RetryAfter, cb.timeout))
+		} else if cb.consecutiveFailures >= cb.failureThreshold || cb.windowTripped() {
+			// If failures exceed threshold, open circuit
 			cb.state = CircuitOpen
 			// Exponential backoff for timeout, but cap at maxTimeout
 			newTimeout := cb.timeout * time.Duration(cb.consecutiveFailures)
@@ -134,12 +257,167 @@ func (cb *CircuitBreaker) recordResult(success bool) {
 		}
 	}
 
+	// Each closed period judges its failure ratio afresh
+	if oldState != cb.state {
+		cb.clearWindow()
+	}
+
 	// Record state transition if state changed
-	if oldState != cb.state && cb.metricsCollector != nil {
-		fromState := cb.stateToString(oldState)
-		toState := cb.stateToString(cb.state)
-		cb.metricsCollector.RecordCircuitBreakerTransition(cb.name, fromState, toState)
+	cb.recordTransition(oldState, cb.state)
+
+	return oldState
+}
+
+// recordWindowOutcome adds a request outcome to the rolling window; the caller must hold the mutex
+func (cb *CircuitBreaker) recordWindowOutcome(failed bool) {
+	if cb.windowSize == 0 {
+		return
+	}
+	cb.window[cb.windowNext] = failed
+	cb.windowNext = (cb.windowNext + 1) % cb.windowSize
+	cb.windowFilled = min(cb.windowFilled+1, cb.windowSize)
+}
+
+// windowTripped reports whether a full rolling window has reached the failure ratio; the caller must hold the mutex
+func (cb *CircuitBreaker) windowTripped() bool {
+	if cb.windowSize == 0 || cb.windowFilled < cb.windowSize {
+		return false
+	}
+
+	failures := 0
+	for _, failed := range cb.window {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(cb.windowSize) >= cb.windowFailureRatio
+}
+
+// clearWindow forgets all outcomes in the rolling window; the caller must hold the mutex
+func (cb *CircuitBreaker) clearWindow() {
+	cb.windowNext = 0
+	cb.windowFilled = 0
+}
+
+// syncSharedState opens the breaker locally when another instance has opened it
+func (cb *CircuitBreaker) syncSharedState() {
+	store := cb.sharedStore()
+	if store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+
+	until, err := store.OpenUntil(ctx, cb.name)
+	if err != nil || !time.Now().Before(until) {
+		// Store unavailable or not open elsewhere - local state decides
+		return
 	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == CircuitOpen && !cb.openDeadline().Before(until) {
+		return
+	}
+
+	oldState := cb.state
+	cb.state = CircuitOpen
+	cb.openUntil = until
+	cb.recordTransition(oldState, cb.state)
+}
+
+// publishResult shares a request outcome with other instances and applies the shared failure count
+func (cb *CircuitBreaker) publishResult(err error, oldState CircuitState) {
+	store := cb.sharedStore()
+	if store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+
+	if err == nil {
+		if oldState != CircuitClosed && cb.GetState() == CircuitClosed {
+			// Recovered from half-open - let every instance resume
+			_ = store.Clear(ctx, cb.name)
+		} else {
+			_ = store.ResetFailures(ctx, cb.name)
+		}
+		return
+	}
+
+	failures, storeErr := store.IncrementFailures(ctx, cb.name, cb.maxTimeout)
+	if storeErr != nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	if cb.state != CircuitOpen && failures >= int64(cb.failureThreshold) {
+		// Failures across all instances reached the threshold
+		previous := cb.state
+		cb.state = CircuitOpen
+		cb.lastFailureTime = time.Now()
+		cb.recordTransition(previous, cb.state)
+	}
+	state, until := cb.state, cb.openDeadline()
+	cb.mutex.Unlock()
+
+	if state == CircuitOpen {
+		_ = store.Open(ctx, cb.name, until)
+	}
+}
+
+// recordTransition records a state change in metrics and queues it for the OnStateChange hook;
+// the caller must hold the mutex
+func (cb *CircuitBreaker) recordTransition(from, to CircuitState) {
+	if from == to {
+		return
+	}
+	if cb.metricsCollector != nil {
+		cb.metricsCollector.RecordCircuitBreakerTransition(cb.name, from.String(), to.String())
+	}
+	if cb.onStateChange != nil {
+		cb.pendingTransitions = append(cb.pendingTransitions, circuitTransition{from: from, to: to})
+	}
+}
+
+// flushTransitions reports queued transitions to the OnStateChange hook without holding the mutex,
+// so the hook may safely call back into the breaker
+func (cb *CircuitBreaker) flushTransitions() {
+	cb.mutex.Lock()
+	pending := cb.pendingTransitions
+	cb.pendingTransitions = nil
+	cb.mutex.Unlock()
+
+	for _, transition := range pending {
+		cb.onStateChange(cb.name, transition.from, transition.to)
+	}
+}
+
+// openDeadline returns when an open circuit will next allow a probe: after the backoff
+// timeout, or later when a provider-requested Retry-After asks for longer
+func (cb *CircuitBreaker) openDeadline() time.Time {
+	deadline := cb.lastFailureTime.Add(cb.timeout)
+	if cb.openUntil.After(deadline) {
+		return cb.openUntil
+	}
+	return deadline
+}
+
+// sharedStore returns the configured shared state store, if any
+func (cb *CircuitBreaker) sharedStore() BreakerStateStore {
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	return cb.stateStore
+}
+
+// SetStateStore shares this breaker's state with other instances through store
+func (cb *CircuitBreaker) SetStateStore(store BreakerStateStore) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.stateStore = store
 }
 
 // incrementTotalRequests safely increments the total request counter
@@ -158,17 +436,7 @@ func (cb *CircuitBreaker) GetState() CircuitState {
 
 // GetStateName returns the human-readable name of the current state
 func (cb *CircuitBreaker) GetStateName() string {
-	state := cb.GetState()
-	switch state {
-	case CircuitClosed:
-		return "CLOSED"
-	case CircuitOpen:
-		return "OPEN"
-	case CircuitHalfOpen:
-		return "HALF_OPEN"
-	default:
-		return "UNKNOWN"
-	}
+	return cb.GetState().String()
 }
 
 // GetStats returns statistics about the circuit breaker
@@ -187,6 +455,7 @@ func (cb *CircuitBreaker) GetStats() CircuitBreakerStats {
 		ConsecutiveFailures:  cb.consecutiveFailures,
 		ConsecutiveSuccesses: cb.consecutiveSuccesses,
 		LastFailureTime:      cb.lastFailureTime,
+		OpenUntil:            cb.openUntil,
 		Timeout:              cb.timeout,
 		TotalRequests:        cb.totalRequests,
 		SuccessfulRequests:   cb.successfulRequests,
@@ -203,6 +472,7 @@ type CircuitBreakerStats struct {
 	ConsecutiveFailures  int           `json:"consecutive_failures"`
 	ConsecutiveSuccesses int           `json:"consecutive_successes"`
 	LastFailureTime      time.Time     `json:"last_failure_time,omitempty"`
+	OpenUntil            time.Time     `json:"open_until,omitempty"`
 	Timeout              time.Duration `json:"timeout_duration"`
 	TotalRequests        int64         `json:"total_requests"`
 	SuccessfulRequests   int64         `json:"successful_requests"`
@@ -213,14 +483,26 @@ type CircuitBreakerStats struct {
 
 // Reset manually resets the circuit breaker to closed state
 func (cb *CircuitBreaker) Reset() {
+	defer cb.flushTransitions()
+
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
+	cb.recordTransition(cb.state, CircuitClosed)
 	cb.state = CircuitClosed
 	cb.consecutiveFailures = 0
 	cb.consecutiveSuccesses = 0
+	cb.openUntil = time.Time{}
+	cb.halfOpenInFlight = 0
+	cb.clearWindow()
 	// Reset timeout to original value would need to be stored separately
 	// For now, keep current timeout
+
+	if cb.stateStore != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+		defer cancel()
+		_ = cb.stateStore.Clear(ctx, cb.name)
+	}
 }
 
 // Custom errors for circuit breaker
@@ -244,17 +526,3 @@ func (cb *CircuitBreaker) SetMetricsCollector(collector *metrics.MetricsCollecto
 	defer cb.mutex.Unlock()
 	cb.metricsCollector = collector
 }
-
-// stateToString converts a CircuitState to its string representation
-func (cb *CircuitBreaker) stateToString(state CircuitState) string {
-	switch state {
-	case CircuitClosed:
-		return "CLOSED"
-	case CircuitOpen:
-		return "OPEN"
-	case CircuitHalfOpen:
-		return "HALF_OPEN"
-	default:
-		return "UNKNOWN"
-	}
-}
\ No newline at end of file
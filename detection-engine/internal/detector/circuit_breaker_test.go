@@ -0,0 +1,401 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// errUpstream is a generic provider failure
+var errUpstream = &ProviderError{Category: ErrorCategoryServer, StatusCode: 500, Message: "upstream failure"}
+
+// failWith returns a breaker call that fails with err
+func failWith(err error) func() error {
+	return func() error { return err }
+}
+
+// succeed is a breaker call that succeeds
+func succeed() error { return nil }
+
+func TestCircuitBreakerHonorsRetryAfter(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "rate-limited",
+		FailureThreshold: 5,
+		SuccessThreshold: 1,
+		Timeout:          time.Millisecond,
+		MaxTimeout:       time.Hour,
+	})
+
+	err := cb.Call(failWith(&RateLimitError{StatusCode: 429, RetryAfter: 100 * time.Millisecond}))
+	if !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("Call error = %v, want the rate limit error", err)
+	}
+	// A single 429 opens the breaker, below the failure threshold
+	if state := cb.GetState(); state != CircuitOpen {
+		t.Fatalf("state = %v after a 429 with Retry-After, want open", state)
+	}
+
+	// The backoff timeout has long passed, but the provider asked for longer
+	time.Sleep(20 * time.Millisecond)
+	if err := cb.Call(succeed); err != ErrCircuitOpen {
+		t.Fatalf("Call before Retry-After elapsed = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := cb.Call(succeed); err != nil {
+		t.Fatalf("Call after Retry-After elapsed = %v, want the probe to go through", err)
+	}
+	if state := cb.GetState(); state != CircuitClosed {
+		t.Errorf("state = %v after a successful probe, want closed", state)
+	}
+}
+
+func TestCircuitBreakerDoesNotCapRetryAfter(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "rate-limited",
+		FailureThreshold: 5,
+		SuccessThreshold: 1,
+		Timeout:          time.Millisecond,
+		MaxTimeout:       time.Minute,
+	})
+
+	before := time.Now()
+	cb.Call(failWith(&RateLimitError{StatusCode: 429, RetryAfter: 24 * time.Hour}))
+
+	// The provider's Retry-After is a floor, even past the max backoff timeout
+	openUntil := cb.GetStats().OpenUntil
+	if openUntil.Before(before.Add(24*time.Hour)) || openUntil.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("OpenUntil = %v, want about a day from now (the Retry-After)", openUntil)
+	}
+}
+
+func TestCircuitBreakerShortRetryAfterKeepsEscalatedBackoff(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "rate-limited",
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		Timeout:          100 * time.Millisecond,
+		MaxTimeout:       time.Hour,
+	})
+
+	// Two outages in a row escalate the backoff to 200ms
+	cb.Call(failWith(errUpstream))
+	time.Sleep(120 * time.Millisecond)
+	cb.Call(failWith(errUpstream))
+	if timeout := cb.GetStats().Timeout; timeout != 200*time.Millisecond {
+		t.Fatalf("Timeout = %s after two failures, want the escalated 200ms", timeout)
+	}
+
+	// The probe is rate limited with a Retry-After far shorter than the backoff
+	time.Sleep(220 * time.Millisecond)
+	cb.Call(failWith(&RateLimitError{StatusCode: 429, RetryAfter: 10 * time.Millisecond}))
+
+	time.Sleep(50 * time.Millisecond)
+	if err := cb.Call(succeed); err != ErrCircuitOpen {
+		t.Fatalf("Call after the Retry-After but within the backoff = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if err := cb.Call(succeed); err != nil {
+		t.Errorf("Call after the backoff elapsed = %v, want the probe to go through", err)
+	}
+}
+
+func TestCircuitBreakerServerErrorsKeepBackoff(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "flaky",
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		Timeout:          10 * time.Millisecond,
+		MaxTimeout:       time.Hour,
+	})
+
+	cb.Call(failWith(errUpstream))
+	if state := cb.GetState(); state != CircuitClosed {
+		t.Fatalf("state = %v after one 500, want closed below the failure threshold", state)
+	}
+	cb.Call(failWith(errUpstream))
+	if state := cb.GetState(); state != CircuitOpen {
+		t.Fatalf("state = %v after two 500s, want open", state)
+	}
+
+	stats := cb.GetStats()
+	if !stats.OpenUntil.IsZero() {
+		t.Errorf("OpenUntil = %v, want unset without a Retry-After", stats.OpenUntil)
+	}
+	if stats.Timeout != 20*time.Millisecond {
+		t.Errorf("Timeout = %v, want the backoff doubled to 20ms", stats.Timeout)
+	}
+}
+
+// newOpenBreaker returns a breaker that has just opened and half-opens after 10ms
+func newOpenBreaker(t *testing.T, config CircuitBreakerConfig) *CircuitBreaker {
+	t.Helper()
+
+	config.Name = "recovering"
+	config.FailureThreshold = 1
+	config.SuccessThreshold = 1
+	config.Timeout = 10 * time.Millisecond
+	config.MaxTimeout = time.Hour
+	cb := NewCircuitBreaker(config)
+
+	cb.Call(failWith(errUpstream))
+	if state := cb.GetState(); state != CircuitOpen {
+		t.Fatalf("state = %v after a failure at threshold 1, want open", state)
+	}
+	return cb
+}
+
+func TestCircuitBreakerHalfOpenProbeLimit(t *testing.T) {
+	tests := map[string]struct {
+		maxRequests int
+		wantProbes  int
+	}{
+		"default single probe": {0, 1},
+		"configured probes":    {3, 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cb := newOpenBreaker(t, CircuitBreakerConfig{HalfOpenMaxRequests: tt.maxRequests})
+			time.Sleep(20 * time.Millisecond)
+
+			const callers = 20
+			entered := make(chan struct{}, callers)
+			release := make(chan struct{})
+			results := make(chan error, callers)
+
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results <- cb.Call(func() error {
+						entered <- struct{}{}
+						<-release
+						return nil
+					})
+				}()
+			}
+
+			// Everyone but the probes is turned away while the probes are still running
+			for i := 0; i < callers-tt.wantProbes; i++ {
+				if err := <-results; err != ErrCircuitOpen {
+					t.Fatalf("Call during a half-open probe = %v, want ErrCircuitOpen", err)
+				}
+			}
+			for i := 0; i < tt.wantProbes; i++ {
+				<-entered
+			}
+			if extra := len(entered); extra != 0 {
+				t.Errorf("%d calls beyond the %d probes reached the recovering provider", extra, tt.wantProbes)
+			}
+
+			close(release)
+			wg.Wait()
+			close(results)
+			for err := range results {
+				if err != nil {
+					t.Errorf("probe = %v, want success", err)
+				}
+			}
+			if state := cb.GetState(); state != CircuitClosed {
+				t.Errorf("state = %v after successful probes, want closed", state)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	cb := newOpenBreaker(t, CircuitBreakerConfig{})
+	time.Sleep(20 * time.Millisecond)
+
+	if err := cb.Call(failWith(errUpstream)); err != errUpstream {
+		t.Fatalf("probe = %v, want the upstream error", err)
+	}
+	if state := cb.GetState(); state != CircuitOpen {
+		t.Errorf("state = %v after a failed probe, want open again", state)
+	}
+	if err := cb.Call(succeed); err != ErrCircuitOpen {
+		t.Errorf("Call right after a failed probe = %v, want ErrCircuitOpen", err)
+	}
+}
+
+// transitionRecorder collects the transitions reported to an OnStateChange hook
+type transitionRecorder struct {
+	mutex       sync.Mutex
+	transitions []string
+}
+
+// record is the OnStateChange hook
+func (r *transitionRecorder) record(name string, from, to CircuitState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.transitions = append(r.transitions, name+": "+from.String()+" -> "+to.String())
+}
+
+// recorded returns the transitions reported so far
+func (r *transitionRecorder) recorded() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.transitions...)
+}
+
+func TestCircuitBreakerStateChangeSequence(t *testing.T) {
+	recorder := &transitionRecorder{}
+	cb := newOpenBreaker(t, CircuitBreakerConfig{OnStateChange: recorder.record})
+
+	// A failed probe reopens the breaker, a successful one closes it
+	time.Sleep(20 * time.Millisecond)
+	cb.Call(failWith(errUpstream))
+	time.Sleep(30 * time.Millisecond) // The failed probe doubled the timeout to 20ms
+	cb.Call(succeed)
+
+	// Calls that leave the state alone report nothing
+	cb.Call(succeed)
+	cb.Reset()
+
+	want := []string{
+		"recovering: CLOSED -> OPEN",
+		"recovering: OPEN -> HALF_OPEN",
+		"recovering: HALF_OPEN -> OPEN",
+		"recovering: OPEN -> HALF_OPEN",
+		"recovering: HALF_OPEN -> CLOSED",
+	}
+	got := recorder.recorded()
+	if len(got) != len(want) {
+		t.Fatalf("transitions = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transition %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerStateChangeHookMayReenter(t *testing.T) {
+	var cb *CircuitBreaker
+	var states []CircuitState
+	cb = NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "reentrant",
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		Timeout:          time.Minute,
+		MaxTimeout:       time.Hour,
+		OnStateChange: func(name string, from, to CircuitState) {
+			// Calling back into the breaker would deadlock if the hook ran under its lock
+			states = append(states, cb.GetState())
+		},
+	})
+
+	cb.Call(failWith(errUpstream))
+	cb.Reset()
+	if len(states) != 2 || states[0] != CircuitOpen || states[1] != CircuitClosed {
+		t.Errorf("states seen by the hook = %v, want [OPEN CLOSED]", states)
+	}
+}
+
+func TestPipelineReportsBreakerTransitions(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "transition-reporter", err: errUpstream},
+		fakeModel{name: "backup", score: 0.1},
+	)
+	hook := test.NewLocal(pipeline.logger)
+	opened := circuitStateChanges.WithLabelValues("transition-reporter", "CLOSED", "OPEN")
+	before := counterValue(t, opened)
+
+	if _, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello"}); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if got := counterValue(t, opened) - before; got != 1 {
+		t.Errorf("detection_circuit_state_changes_total{CLOSED->OPEN} grew by %v, want 1", got)
+	}
+	var warned bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Circuit breaker opened" && entry.Level == logrus.WarnLevel && entry.Data["model"] == "transition-reporter" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("no warning logged when the breaker opened")
+	}
+}
+
+// counterValue reads the current value of a Prometheus counter
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+// alternate runs calls alternating failure and success, starting with a failure, and returns
+// the breaker state after each call
+func alternate(cb *CircuitBreaker, calls int) []CircuitState {
+	states := make([]CircuitState, calls)
+	for i := range states {
+		if i%2 == 0 {
+			cb.Call(failWith(errUpstream))
+		} else {
+			cb.Call(succeed)
+		}
+		states[i] = cb.GetState()
+	}
+	return states
+}
+
+func TestCircuitBreakerWindowTripsIntermittentFailures(t *testing.T) {
+	base := CircuitBreakerConfig{Name: "intermittent", FailureThreshold: 3, SuccessThreshold: 1, Timeout: time.Minute, MaxTimeout: time.Hour}
+	windowed := base
+	windowed.WindowSize = 4
+	windowed.WindowFailureRatio = 0.5
+
+	// A 50% error rate never strings three failures together
+	if states := alternate(NewCircuitBreaker(base), 20); states[len(states)-1] != CircuitClosed {
+		t.Errorf("consecutive-only breaker = %v after alternating calls, want closed", states)
+	}
+
+	// The window is full after four calls; the next failure finds the ratio at 0.5 and trips it
+	states := alternate(NewCircuitBreaker(windowed), 5)
+	for i, state := range states[:4] {
+		if state != CircuitClosed {
+			t.Errorf("state after call %d = %v, want closed until a failure lands in a full window", i+1, state)
+		}
+	}
+	if states[4] != CircuitOpen {
+		t.Errorf("state after 2 failures in the last 4 requests = %v, want open at the 0.5 ratio", states[4])
+	}
+}
+
+func TestCircuitBreakerWindowBelowRatio(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:               "mostly-healthy",
+		FailureThreshold:   3,
+		SuccessThreshold:   1,
+		Timeout:            time.Minute,
+		MaxTimeout:         time.Hour,
+		WindowSize:         4,
+		WindowFailureRatio: 0.5,
+	})
+
+	// One failure in every four requests stays under the ratio however long it runs
+	for i := 0; i < 20; i++ {
+		if i%4 == 0 {
+			cb.Call(failWith(errUpstream))
+		} else {
+			cb.Call(succeed)
+		}
+	}
+	if state := cb.GetState(); state != CircuitClosed {
+		t.Errorf("state = %v at a 25%% error rate, want closed", state)
+	}
+}
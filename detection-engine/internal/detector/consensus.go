@@ -0,0 +1,337 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/logging"
+)
+
+// defaultDisagreementThreshold is the score spread above which consensus models are considered to disagree
+const defaultDisagreementThreshold = 0.3
+
+// modelOutcome holds the result of querying a single model concurrently
+type modelOutcome struct {
+	model       ModelConfig
+	result      *DetectionResult
+	modelResult ModelResult
+	err         error
+}
+
+// queryModelsConcurrently dispatches the text to every model and streams outcomes as they finish
+func (p *FallbackPipeline) queryModelsConcurrently(ctx context.Context, models []ModelConfig, text string) <-chan modelOutcome {
+	outcomes := make(chan modelOutcome, len(models))
+	for _, model := range models {
+		go func(model ModelConfig) {
+			result, modelResult, err := p.callModel(ctx, model, text)
+			outcomes <- modelOutcome{model: model, result: result, modelResult: modelResult, err: err}
+		}(model)
+	}
+	return outcomes
+}
+
+// analyzeRace queries all enabled models concurrently and returns the first successful answer
+func (p *FallbackPipeline) analyzeRace(ctx context.Context, req *DetectionRequest, config *DetectionConfig, startTime time.Time) (*DetectionResponse, error) {
+	logger := logging.FromContext(ctx, p.logger)
+
+	// Losing models are cancelled once the race is won, so they stop spending quota
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	enabledModels := capTimeouts(ctx, p.modelRegistry.GetEnabledModels(), requestDeadline(config, startTime))
+	outcomes := p.queryModelsConcurrently(ctx, enabledModels, req.Text)
+
+	var lastError error
+	var attemptedModels []string
+	var modelResults []ModelResult
+
+	for i := 0; i < len(enabledModels); i++ {
+		select {
+		case <-ctx.Done():
+			return p.handleFailedAttempts(config, StrategyRace, startTime, attemptedModels, modelResults, ctx.Err())
+		case outcome := <-outcomes:
+			attemptedModels = append(attemptedModels, outcome.model.Name)
+			modelResults = append(modelResults, outcome.modelResult)
+
+			if outcome.err != nil {
+				lastError = outcome.err
+				continue
+			}
+
+			response := p.buildResponse(outcome.result, config, time.Since(startTime), outcome.model.Name)
+			if config.DetailedResponse {
+				response.ModelResults = modelResults
+				response.Strategy = StrategyRace
+			}
+			p.recordDetection(outcome.model.Name, response, time.Since(startTime))
+
+			logger.WithFields(logrus.Fields{
+				"model":        outcome.model.Name,
+				"confidence":   outcome.result.Score,
+				"is_malicious": response.IsMalicious,
+				"duration_ms":  response.ProcessingTimeMs,
+			}).Info("Race detection completed successfully")
+
+			return response, nil
+		}
+	}
+
+	return p.handleFailedAttempts(config, StrategyRace, startTime, attemptedModels, modelResults, lastError)
+}
+
+// analyzeConsensus queries all enabled models and decides maliciousness by quorum vote
+func (p *FallbackPipeline) analyzeConsensus(ctx context.Context, req *DetectionRequest, config *DetectionConfig, startTime time.Time) (*DetectionResponse, error) {
+	logger := logging.FromContext(ctx, p.logger)
+
+	enabledModels := capTimeouts(ctx, p.votingModels(), requestDeadline(config, startTime))
+	outcomes := p.queryModelsConcurrently(ctx, enabledModels, req.Text)
+
+	var lastError error
+	var attemptedModels []string
+	var modelResults []ModelResult
+	var votes []*DetectionResult
+
+collect:
+	for i := 0; i < len(enabledModels); i++ {
+		select {
+		case <-ctx.Done():
+			lastError = ctx.Err()
+			break collect
+		case outcome := <-outcomes:
+			attemptedModels = append(attemptedModels, outcome.model.Name)
+			modelResults = append(modelResults, outcome.modelResult)
+
+			if outcome.err != nil {
+				lastError = outcome.err
+				continue
+			}
+			votes = append(votes, outcome.result)
+		}
+	}
+
+	if len(votes) == 0 {
+		response, err := p.handleFailedAttempts(config, StrategyConsensus, startTime, attemptedModels, modelResults, lastError)
+		if config.MinBenignVotes > 0 && response != nil && !response.IsMalicious {
+			// An outage never satisfies a benign vote requirement, whatever the failure mode
+			p.requireBenignVotes(response, votes, config)
+			return response, nil
+		}
+		return response, err
+	}
+
+	response := p.buildConsensusResponse(votes, config, time.Since(startTime))
+	spread := scoreSpread(votes)
+	if p.tieBreaker != "" && (evenSplit(votes, config, p.confidenceThreshold) || spread > p.getDisagreementThreshold()) {
+		if modelResult, ok := p.breakTie(ctx, req, config, startTime, response); ok {
+			modelResults = append(modelResults, modelResult)
+		}
+	}
+	p.requireBenignVotes(response, votes, config)
+	response.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	if config.DetailedResponse {
+		response.ModelResults = modelResults
+		response.Strategy = StrategyConsensus
+		response.ModelDisagreement = spread
+		response.HighDisagreement = spread > p.getDisagreementThreshold()
+	}
+	p.recordDetection(string(StrategyConsensus), response, time.Since(startTime))
+
+	logger.WithFields(logrus.Fields{
+		"votes":        len(votes),
+		"confidence":   response.Confidence,
+		"is_malicious": response.IsMalicious,
+		"disagreement": response.Disagreement,
+		"score_spread": spread,
+		"tie_breaker":  response.TieBreaker,
+		"duration_ms":  response.ProcessingTimeMs,
+	}).Info("Consensus detection completed successfully")
+
+	return response, nil
+}
+
+// buildConsensusResponse aggregates model votes into a single response
+func (p *FallbackPipeline) buildConsensusResponse(votes []*DetectionResult, config *DetectionConfig, duration time.Duration) *DetectionResponse {
+	// Default quorum is a simple majority of the models that answered
+	quorum := config.Quorum
+	if quorum <= 0 {
+		quorum = len(votes)/2 + 1
+	}
+
+	maliciousVotes := 0
+	totalScore := 0.0
+	threatTypes := make([]string, 0)
+	seenThreats := make(map[ThreatType]bool)
+
+	for _, vote := range votes {
+		totalScore += vote.Score
+		if !votesMalicious(vote, config, p.confidenceThreshold) {
+			continue
+		}
+
+		maliciousVotes++
+		for _, threat := range vote.ThreatTypes {
+			if !seenThreats[threat] {
+				seenThreats[threat] = true
+				threatTypes = append(threatTypes, string(threat))
+			}
+		}
+	}
+
+	return &DetectionResponse{
+		IsMalicious:      maliciousVotes >= quorum,
+		Confidence:       totalScore / float64(len(votes)),
+		ThreatTypes:      threatTypes,
+		ProcessingTimeMs: duration.Milliseconds(),
+		Reason:           fmt.Sprintf("%d of %d models voted malicious (quorum %d)", maliciousVotes, len(votes), quorum),
+		Endpoint:         string(StrategyConsensus),
+		Disagreement:     maliciousVotes > 0 && maliciousVotes < len(votes),
+	}
+}
+
+// votesMalicious reports whether a model's vote counts as malicious under the request's thresholds
+func votesMalicious(vote *DetectionResult, config *DetectionConfig, defaultThreshold float64) bool {
+	return exceedsThreshold(vote.Score, threatStrings(vote.ThreatTypes), config, defaultThreshold)
+}
+
+// threatStrings converts threat types to their string form
+func threatStrings(threats []ThreatType) []string {
+	strs := make([]string, len(threats))
+	for i, threat := range threats {
+		strs[i] = string(threat)
+	}
+	return strs
+}
+
+// evenSplit reports whether exactly half of the votes were malicious
+func evenSplit(votes []*DetectionResult, config *DetectionConfig, defaultThreshold float64) bool {
+	maliciousVotes := 0
+	for _, vote := range votes {
+		if votesMalicious(vote, config, defaultThreshold) {
+			maliciousVotes++
+		}
+	}
+	return len(votes) > 0 && maliciousVotes*2 == len(votes)
+}
+
+// votingModels returns the enabled models that vote in consensus; the tie-breaker
+// is held back for split votes
+func (p *FallbackPipeline) votingModels() []ModelConfig {
+	enabledModels := p.modelRegistry.GetEnabledModels()
+	if p.tieBreaker == "" {
+		return enabledModels
+	}
+
+	voting := make([]ModelConfig, 0, len(enabledModels))
+	for _, model := range enabledModels {
+		if model.Name != p.tieBreaker {
+			voting = append(voting, model)
+		}
+	}
+	return voting
+}
+
+// breakTie asks the tie-breaker model, through its own circuit breaker, to decide a
+// split consensus and replaces the response's verdict with its own. When the
+// tie-breaker fails the quorum verdict stands; ok reports whether it was called.
+func (p *FallbackPipeline) breakTie(ctx context.Context, req *DetectionRequest, config *DetectionConfig, startTime time.Time, response *DetectionResponse) (ModelResult, bool) {
+	logger := logging.FromContext(ctx, p.logger)
+
+	model, err := p.modelRegistry.GetModelByName(p.tieBreaker)
+	if err != nil {
+		logger.WithError(err).Warn("Tie-breaker model not found, keeping the quorum verdict")
+		return ModelResult{}, false
+	}
+	model.Timeout = attemptTimeout(ctx, model, requestDeadline(config, startTime), 1)
+
+	result, modelResult, err := p.callModel(ctx, model, req.Text)
+	if err != nil {
+		logger.WithError(err).WithField("model", model.Name).Warn("Tie-breaker failed, keeping the quorum verdict")
+		return modelResult, true
+	}
+
+	response.IsMalicious = votesMalicious(result, config, p.confidenceThreshold)
+	response.Confidence = result.Score
+	response.ThreatTypes = []string{}
+	if response.IsMalicious {
+		response.ThreatTypes = threatStrings(result.ThreatTypes)
+	}
+	response.Reason = fmt.Sprintf("%s; tie broken by %s: %s", response.Reason, model.Name, result.Reason)
+	response.TieBreaker = model.Name
+	return modelResult, true
+}
+
+// validateMinBenignVotes checks that a benign vote requirement can be met by the requested strategy and mode
+func validateMinBenignVotes(config *DetectionConfig) error {
+	if config.MinBenignVotes < 0 {
+		return fmt.Errorf("invalid min_benign_votes %d: must not be negative", config.MinBenignVotes)
+	}
+	if config.MinBenignVotes == 0 {
+		return nil
+	}
+	if config.Strategy != "" && config.Strategy != StrategyConsensus {
+		return fmt.Errorf("invalid strategy %q for min_benign_votes: votes are only counted by %q", config.Strategy, StrategyConsensus)
+	}
+	if config.Mode == ModeFast {
+		return fmt.Errorf("invalid mode %q for min_benign_votes: fast mode calls a single model", config.Mode)
+	}
+	return nil
+}
+
+// requireBenignVotes turns a benign consensus verdict into a fail-closed block unless at
+// least MinBenignVotes models answered and voted benign; a tie-breaker that decided
+// the input is benign counts as one more benign vote
+func (p *FallbackPipeline) requireBenignVotes(response *DetectionResponse, votes []*DetectionResult, config *DetectionConfig) {
+	if config.MinBenignVotes <= 0 || response.IsMalicious {
+		return
+	}
+
+	benignVotes := 0
+	for _, vote := range votes {
+		if !votesMalicious(vote, config, p.confidenceThreshold) {
+			benignVotes++
+		}
+	}
+	if response.TieBreaker != "" {
+		benignVotes++
+	}
+	if benignVotes >= config.MinBenignVotes {
+		return
+	}
+
+	response.IsMalicious = true
+	response.Confidence = 1.0
+	response.FailedClosed = true
+	response.Reason = fmt.Sprintf("%d of %d required models voted benign - returning fail-closed malicious classification (%s)", benignVotes, config.MinBenignVotes, response.Reason)
+}
+
+// getDisagreementThreshold returns the configured spread threshold or the default when unset
+func (p *FallbackPipeline) getDisagreementThreshold() float64 {
+	if p.disagreementThreshold <= 0 {
+		return defaultDisagreementThreshold
+	}
+	return p.disagreementThreshold
+}
+
+// scoreSpread returns the standard deviation of the votes' scores: 0 when models
+// agree, up to 0.5 when they split between 0 and 1
+func scoreSpread(votes []*DetectionResult) float64 {
+	if len(votes) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, vote := range votes {
+		mean += vote.Score
+	}
+	mean /= float64(len(votes))
+
+	variance := 0.0
+	for _, vote := range votes {
+		variance += (vote.Score - mean) * (vote.Score - mean)
+	}
+	return math.Sqrt(variance / float64(len(votes)))
+}
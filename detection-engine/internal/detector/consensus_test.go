@@ -0,0 +1,221 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConsensusVoting(t *testing.T) {
+	malicious := []ThreatType{ThreatTypeJailbreak}
+
+	tests := map[string]struct {
+		models           []fakeModel
+		quorum           int
+		wantMalicious    bool
+		wantDisagreement bool
+	}{
+		"agreement": {
+			models: []fakeModel{
+				{name: "a", score: 0.9, threats: malicious},
+				{name: "b", score: 0.8, threats: malicious},
+				{name: "c", score: 0.95, threats: malicious},
+			},
+			wantMalicious: true,
+		},
+		"split with majority": {
+			models: []fakeModel{
+				{name: "a", score: 0.9, threats: malicious},
+				{name: "b", score: 0.8, threats: malicious},
+				{name: "c", score: 0.1},
+			},
+			wantMalicious:    true,
+			wantDisagreement: true,
+		},
+		"quorum not met": {
+			models: []fakeModel{
+				{name: "a", score: 0.9, threats: malicious},
+				{name: "b", score: 0.8, threats: malicious},
+				{name: "c", score: 0.1},
+			},
+			quorum:           3,
+			wantDisagreement: true,
+		},
+		"failed model does not vote": {
+			models: []fakeModel{
+				{name: "a", score: 0.9, threats: malicious},
+				{name: "b", err: &ProviderError{Category: ErrorCategoryServer, Message: "upstream failure"}},
+				{name: "c", score: 0.1},
+			},
+			wantDisagreement: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pipeline := newFakeProviderPipeline(t, tt.models...)
+
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+				Text:   "what is the capital of France",
+				Config: &DetectionConfig{Strategy: StrategyConsensus, Quorum: tt.quorum, DetailedResponse: true},
+			})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if response.IsMalicious != tt.wantMalicious {
+				t.Errorf("IsMalicious = %v, want %v (%s)", response.IsMalicious, tt.wantMalicious, response.Reason)
+			}
+			if response.Disagreement != tt.wantDisagreement {
+				t.Errorf("Disagreement = %v, want %v", response.Disagreement, tt.wantDisagreement)
+			}
+			if response.Strategy != StrategyConsensus || len(response.ModelResults) != len(tt.models) {
+				t.Errorf("strategy %q with %d model results, want consensus over all %d models", response.Strategy, len(response.ModelResults), len(tt.models))
+			}
+		})
+	}
+}
+
+func TestConsensusAveragesConfidence(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "a", score: 0.9, threats: []ThreatType{ThreatTypeJailbreak}},
+		fakeModel{name: "b", score: 0.5},
+	)
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{Strategy: StrategyConsensus},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.Confidence < 0.6999 || response.Confidence > 0.7001 {
+		t.Errorf("Confidence = %v, want the average 0.7", response.Confidence)
+	}
+}
+
+func TestScoreSpread(t *testing.T) {
+	tests := map[string]struct {
+		scores []float64
+		want   float64
+	}{
+		"single vote":     {[]float64{0.9}, 0},
+		"agreement":       {[]float64{0.7, 0.7, 0.7}, 0},
+		"opposite":        {[]float64{0, 1}, 0.5},
+		"strong disagree": {[]float64{0.9, 0.1}, 0.4},
+		"mild spread":     {[]float64{0.8, 0.9, 0.85}, math.Sqrt(0.05 * 0.05 * 2 / 3)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			votes := make([]*DetectionResult, len(tt.scores))
+			for i, score := range tt.scores {
+				votes[i] = &DetectionResult{Score: score}
+			}
+			if got := scoreSpread(votes); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("scoreSpread(%v) = %v, want %v", tt.scores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsensusReportsModelDisagreement(t *testing.T) {
+	tests := map[string]struct {
+		scores       []float64
+		threshold    float64 // 0 keeps the default
+		wantSpread   float64
+		wantHighFlag bool
+	}{
+		"high spread":            {scores: []float64{0.9, 0.1}, wantSpread: 0.4, wantHighFlag: true},
+		"low spread":             {scores: []float64{0.85, 0.75}, wantSpread: 0.05},
+		"below custom threshold": {scores: []float64{0.9, 0.1}, threshold: 0.45, wantSpread: 0.4},
+		"above custom threshold": {scores: []float64{0.85, 0.75}, threshold: 0.01, wantSpread: 0.05, wantHighFlag: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			models := make([]fakeModel, len(tt.scores))
+			for i, score := range tt.scores {
+				models[i] = fakeModel{name: fmt.Sprintf("model-%d", i), score: score, threats: []ThreatType{ThreatTypeInjection}}
+			}
+			pipeline := newFakeProviderPipeline(t, models...)
+			pipeline.SetDisagreementThreshold(tt.threshold)
+
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+				Text:   "what is the capital of France",
+				Config: &DetectionConfig{Strategy: StrategyConsensus, DetailedResponse: true},
+			})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if math.Abs(response.ModelDisagreement-tt.wantSpread) > 1e-9 || response.HighDisagreement != tt.wantHighFlag {
+				t.Errorf("disagreement = %v high %v, want %v high %v", response.ModelDisagreement, response.HighDisagreement, tt.wantSpread, tt.wantHighFlag)
+			}
+		})
+	}
+}
+
+func TestConsensusDisagreementOnlyInDetailedResponse(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "a", score: 0.95, threats: []ThreatType{ThreatTypeJailbreak}},
+		fakeModel{name: "b", score: 0.05},
+	)
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{Strategy: StrategyConsensus},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.ModelDisagreement != 0 || response.HighDisagreement {
+		t.Errorf("disagreement = %v high %v, want neither outside a detailed response", response.ModelDisagreement, response.HighDisagreement)
+	}
+}
+
+func TestRaceCancelsLosingModels(t *testing.T) {
+	fast := testModel("fast", providerFake, "")
+	slow := testModel("slow", providerFake, "")
+	slow.Priority = 2
+	pipeline := newTestFallbackPipeline(t, fast, slow)
+
+	cancelled := make(chan error, 1)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		if model.Name == "slow" {
+			select {
+			case <-ctx.Done():
+				cancelled <- ctx.Err()
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				cancelled <- nil
+				return &DetectionResult{Method: MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+			}
+		}
+		return &DetectionResult{Method: MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+	}))
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{Strategy: StrategyRace},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.Endpoint != "fast" {
+		t.Errorf("Endpoint = %q, want the fast winner", response.Endpoint)
+	}
+
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("slow model finished with %v, want its context cancelled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow model was not cancelled after the race was won")
+	}
+
+	// The cancelled loser is not charged with a failure
+	if stats := pipeline.circuitBreakerSnapshot()["slow"].GetStats(); stats.FailedRequests != 0 {
+		t.Errorf("slow breaker recorded %d failures, want the cancellation ignored", stats.FailedRequests)
+	}
+}
@@ -0,0 +1,79 @@
+package detector
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrCostBudgetExceeded is returned when a paid model is skipped because the daily cost budget is spent
+var ErrCostBudgetExceeded = &CircuitBreakerError{Message: "daily cost budget exhausted and no free model succeeded"}
+
+// CostBudget accumulates the cost of successful paid model calls against a daily cap that resets at UTC midnight
+type CostBudget struct {
+	limit float64
+	spent float64
+	day   time.Time // UTC midnight of the day being accumulated
+	now   func() time.Time
+	mutex sync.Mutex
+}
+
+// CostBudgetStatus is the budget snapshot reported in health output
+type CostBudgetStatus struct {
+	LimitUSD  float64   `json:"limit_usd"`
+	SpentUSD  float64   `json:"spent_usd"`
+	Exhausted bool      `json:"exhausted"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// NewCostBudget creates a daily budget of limit USD; a non-positive limit disables it
+func NewCostBudget(limit float64) *CostBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &CostBudget{limit: limit, now: time.Now}
+}
+
+// Exhausted reports whether today's spend has reached the limit; a nil budget never is
+func (b *CostBudget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+	return b.spent >= b.limit
+}
+
+// Record adds the cost of a successful call to today's spend
+func (b *CostBudget) Record(cost float64) {
+	if b == nil || cost <= 0 {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+	b.spent += cost
+}
+
+// Status returns today's spend against the limit
+func (b *CostBudget) Status() CostBudgetStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rollover()
+	return CostBudgetStatus{
+		LimitUSD:  b.limit,
+		SpentUSD:  b.spent,
+		Exhausted: b.spent >= b.limit,
+		ResetsAt:  b.day.AddDate(0, 0, 1),
+	}
+}
+
+// rollover starts a fresh day once UTC midnight has passed; the caller must hold the mutex
+func (b *CostBudget) rollover() {
+	year, month, day := b.now().UTC().Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if !today.Equal(b.day) {
+		b.day = today
+		b.spent = 0
+	}
+}
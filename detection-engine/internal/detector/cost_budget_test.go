@@ -0,0 +1,94 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestCostBudget returns a budget of limit USD on a fake clock set to just before UTC midnight
+func newTestCostBudget(limit float64) (*CostBudget, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 5, 1, 23, 0, 0, 0, time.UTC)}
+	budget := NewCostBudget(limit)
+	budget.now = clock.Now
+	return budget, clock
+}
+
+func TestCostBudgetResetsAtUTCMidnight(t *testing.T) {
+	budget, clock := newTestCostBudget(0.005)
+
+	budget.Record(0.002)
+	budget.Record(0)
+	if budget.Exhausted() {
+		t.Fatal("budget exhausted after spending 0.002 of 0.005")
+	}
+	budget.Record(0.002)
+	budget.Record(0.002)
+	if !budget.Exhausted() {
+		t.Fatal("budget not exhausted after spending 0.006 of 0.005")
+	}
+
+	status := budget.Status()
+	if math.Abs(status.SpentUSD-0.006) > 1e-9 || !status.Exhausted || !status.ResetsAt.Equal(time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("status = %+v, want 0.006 spent, exhausted, resetting at the next UTC midnight", status)
+	}
+
+	clock.Advance(time.Hour)
+	if budget.Exhausted() || budget.Status().SpentUSD != 0 {
+		t.Errorf("status after midnight = %+v, want a fresh day", budget.Status())
+	}
+}
+
+func TestCostBudgetDisabled(t *testing.T) {
+	budget := NewCostBudget(0)
+	if budget != nil {
+		t.Fatalf("NewCostBudget(0) = %+v, want nil", budget)
+	}
+	budget.Record(100)
+	if budget.Exhausted() {
+		t.Error("a disabled budget reported exhausted")
+	}
+}
+
+func TestCostBudgetSwitchesToFreeModels(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t, fakeModel{name: "paid", score: 0.1, cost: 0.002}, fakeModel{name: "free", score: 0.1})
+	budget, clock := newTestCostBudget(0.005)
+	pipeline.SetCostBudget(budget)
+
+	// Three paid calls cross the 0.005 cap
+	var endpoints []string
+	for i := 0; i < 5; i++ {
+		response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello"})
+		if err != nil {
+			t.Fatalf("Analyze %d: %v", i+1, err)
+		}
+		endpoints = append(endpoints, response.Endpoint)
+	}
+	want := []string{"paid", "paid", "paid", "free", "free"}
+	for i := range want {
+		if endpoints[i] != want[i] {
+			t.Fatalf("answered by %v, want %v", endpoints, want)
+		}
+	}
+
+	// Paid models resume once the day rolls over
+	clock.Advance(2 * time.Hour)
+	if response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello"}); err != nil || response.Endpoint != "paid" {
+		t.Errorf("Analyze after midnight = %v, %v, want the paid model again", response, err)
+	}
+}
+
+func TestCostBudgetExhaustedWithoutFreeModels(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t, fakeModel{name: "paid", score: 0.1, cost: 0.01})
+	budget, _ := newTestCostBudget(0.01)
+	pipeline.SetCostBudget(budget)
+
+	if _, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello"}); err != nil {
+		t.Fatalf("Analyze within budget: %v", err)
+	}
+	if _, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello"}); !errors.Is(err, ErrCostBudgetExceeded) {
+		t.Errorf("Analyze over budget = %v, want ErrCostBudgetExceeded", err)
+	}
+}
@@ -0,0 +1,78 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDispatchSaturated is returned when no outbound model dispatch slot frees up in time
+var ErrDispatchSaturated = errors.New("all outbound model dispatch slots are in use")
+
+// dispatchLimiter caps concurrent outbound model calls across all requests.
+// A nil limiter imposes no limit.
+type dispatchLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration // How long a call may wait for a slot before being shed
+}
+
+// newDispatchLimiter creates a limiter with size slots, or nil if size is not positive
+func newDispatchLimiter(size int, queueTimeout time.Duration) *dispatchLimiter {
+	if size <= 0 {
+		return nil
+	}
+	return &dispatchLimiter{
+		slots:        make(chan struct{}, size),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire takes a slot, queuing up to queueTimeout before giving up
+func (d *dispatchLimiter) acquire(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	// Fast path when a slot is free
+	select {
+	case d.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(d.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case d.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrDispatchSaturated
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a slot taken by acquire
+func (d *dispatchLimiter) release() {
+	if d == nil {
+		return
+	}
+	<-d.slots
+}
+
+// inUse returns the number of slots currently held
+func (d *dispatchLimiter) inUse() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.slots)
+}
+
+// capacity returns the total number of slots (0 means unlimited)
+func (d *dispatchLimiter) capacity() int {
+	if d == nil {
+		return 0
+	}
+	return cap(d.slots)
+}
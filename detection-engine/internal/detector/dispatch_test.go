@@ -0,0 +1,145 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingHandler signals started and then hangs until release is closed
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) func(http.ResponseWriter, *http.Request, int32) {
+	return func(w http.ResponseWriter, r *http.Request, call int32) {
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		writeHuggingFaceLabel(w, "SAFE", 0.99)
+	}
+}
+
+// newPooledLLMDetector builds a detector allowing size concurrent outbound calls
+func newPooledLLMDetector(t *testing.T, size int, queueTimeout time.Duration) *LLMDetector {
+	t.Helper()
+	t.Setenv(testAPIKeyEnv, "test-key")
+
+	config := DefaultLLMDetectorConfig()
+	config.EndpointDelay = 0
+	config.Retry = RetryPolicy{MaxAttempts: 1}
+	config.MaxConcurrentCalls = size
+	config.DispatchQueueTimeout = queueTimeout
+	return NewLLMDetectorWithConfig(config)
+}
+
+func TestDispatchShedsCallBeyondPoolSize(t *testing.T) {
+	const poolSize = 2
+	started := make(chan struct{}, poolSize+1)
+	release := make(chan struct{})
+	server, calls := newFakeHuggingFaceServer(t, blockingHandler(started, release))
+	model := testModel("pooled", ProviderHuggingFace, server.URL)
+	detector := newPooledLLMDetector(t, poolSize, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, poolSize)
+	for i := 0; i < poolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := detector.detectWithSpecificEndpoint(context.Background(), "hello", model, variantScopeOriginal)
+			errs <- err
+		}()
+	}
+	for i := 0; i < poolSize; i++ {
+		<-started
+	}
+
+	if inUse, capacity := detector.DispatchSlots(); inUse != poolSize || capacity != poolSize {
+		t.Errorf("DispatchSlots() = %d/%d, want %d/%d", inUse, capacity, poolSize, poolSize)
+	}
+
+	_, err := detector.detectWithSpecificEndpoint(context.Background(), "one too many", model, variantScopeOriginal)
+	if !errors.Is(err, ErrDispatchSaturated) {
+		t.Errorf("call beyond the pool = %v, want ErrDispatchSaturated", err)
+	}
+	if got := calls.Load(); got != poolSize {
+		t.Errorf("endpoint calls = %d, want %d: the shed call must not reach the provider", got, poolSize)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("pooled call failed: %v", err)
+		}
+	}
+	if inUse, _ := detector.DispatchSlots(); inUse != 0 {
+		t.Errorf("slots in use after every call finished = %d, want 0", inUse)
+	}
+}
+
+func TestDispatchQueuesCallUntilSlotFrees(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server, calls := newFakeHuggingFaceServer(t, blockingHandler(started, release))
+	model := testModel("pooled", ProviderHuggingFace, server.URL)
+	detector := newPooledLLMDetector(t, 1, 5*time.Second)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := detector.detectWithSpecificEndpoint(context.Background(), "first", model, variantScopeOriginal)
+		errs <- err
+	}()
+	<-started
+
+	go func() {
+		_, err := detector.detectWithSpecificEndpoint(context.Background(), "queued", model, variantScopeOriginal)
+		errs <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("endpoint calls = %d while the pool is full, want the second call queued", got)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("call %d failed: %v", i+1, err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("endpoint calls = %d, want the queued call to go through", got)
+	}
+}
+
+func TestDispatchLimiterAcquire(t *testing.T) {
+	limiter := newDispatchLimiter(1, time.Hour)
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire on an empty pool = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire with a cancelled context = %v, want context.Canceled", err)
+	}
+
+	limiter.release()
+	if got := limiter.inUse(); got != 0 {
+		t.Errorf("inUse() after release = %d, want 0", got)
+	}
+
+	// Without a pool size every call goes through
+	unlimited := newDispatchLimiter(0, time.Hour)
+	if err := unlimited.acquire(context.Background()); err != nil || unlimited.capacity() != 0 {
+		t.Errorf("unlimited limiter: acquire = %v, capacity = %d", err, unlimited.capacity())
+	}
+	unlimited.release()
+}
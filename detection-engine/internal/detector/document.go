@@ -0,0 +1,166 @@
+package detector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// documentRuleBoost raises heuristic rule weights for document content, where any
+// instruction aimed at the model is out of place rather than a user's own request
+const documentRuleBoost = 1.15
+
+// documentPassageThreshold is the passage score above which a passage is reported as suspicious;
+// weaker cues such as a lone tool mention only count alongside other evidence
+const documentPassageThreshold = 0.4
+
+// paragraphBreak separates the passages a document is scored in
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// DocumentDetectionRequest represents untrusted document content (e.g. RAG context) to scan for embedded instructions
+type DocumentDetectionRequest struct {
+	Document string           `json:"document" binding:"required"`
+	Config   *DetectionConfig `json:"config,omitempty"`
+}
+
+// DocumentScanner finds instructions hidden in content that should only ever be data
+type DocumentScanner struct {
+	heuristic  *HeuristicDetector
+	directives []documentPattern
+}
+
+// documentPattern is a weighted regex for instruction-like phrasing inside a document
+// (also used for unsafe tool argument values)
+type documentPattern struct {
+	threat      ThreatType
+	pattern     *regexp.Regexp
+	weight      float64
+	description string
+}
+
+// NewDocumentScanner creates a document scanner that also applies the heuristic detector's rules
+func NewDocumentScanner(heuristic *HeuristicDetector) *DocumentScanner {
+	return &DocumentScanner{
+		heuristic: heuristic,
+		directives: []documentPattern{
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(when|while|after|before|if you are)\s+(summariz|translat|answer|respond|read|process|analy[sz])\w*[^.\n]{0,60}\b(also|instead|additionally|make sure to|be sure to|you must)\b`), 0.7, "task-conditioned instruction"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(ignore|disregard|do not follow|don't follow)\s+(what\s+)?(the\s+)?(user|human|reader|requester)('s)?\b`), 0.8, "user override"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(ai|assistant|language model|llm|chatbot)\b\s*[:,]\s*(you must|please|ignore|do not|don't|always|never)\b`), 0.6, "model-addressed directive"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(do not|don't|never)\s+(tell|inform|mention|reveal|let)\s+(this\s+)?(to\s+)?(the\s+)?(user|human|reader)\b`), 0.6, "concealment from user"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(send|forward|e-?mail|post|upload)\b[^.\n]{0,40}\b(to|at)\s+\S+@\S+\.\w+`), 0.7, "tool invocation: send data"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(call|invoke|use|run|execute)\s+(the\s+)?[\w.-]+\s+(tool|function|plugin|api|command)\b`), 0.35, "tool invocation: call tool"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\b(visit|open|fetch|browse to|navigate to)\s+https?://\S+`), 0.35, "tool invocation: fetch URL"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`!\[[^\]]*\]\(https?://[^)\s]*\?[^)\s]*=[^)\s]*\)`), 0.6, "markdown image exfiltration"},
+		},
+	}
+}
+
+// Scan scores each paragraph of the document separately and reports suspicious
+// passages as matches. The document scores as its most suspicious passage, so
+// long benign documents don't dilute a single injected paragraph.
+func (s *DocumentScanner) Scan(req *DocumentDetectionRequest) *DetectionResult {
+	startTime := time.Now()
+
+	result := &DetectionResult{
+		Method:      MethodHeuristic,
+		Score:       0.0,
+		ThreatTypes: make([]ThreatType, 0),
+		Reason:      "document: no embedded instructions found",
+		Endpoint:    localEndpointName,
+	}
+
+	findings := make([]string, 0)
+	seenFindings := make(map[string]bool)
+	for _, passage := range documentPassages(req.Document) {
+		text := req.Document[passage[0]:passage[1]]
+		score, threats, descriptions := s.scorePassage(text)
+		if score < documentPassageThreshold {
+			continue
+		}
+
+		result.Score = max(result.Score, score)
+		for _, threat := range threats {
+			result.ThreatTypes = appendThreat(result.ThreatTypes, threat)
+		}
+		for _, description := range descriptions {
+			if !seenFindings[description] {
+				seenFindings[description] = true
+				findings = append(findings, description)
+			}
+		}
+		result.Matches = append(result.Matches, newMatch(req.Document, threats[0], passage[0], passage[1]))
+	}
+
+	if len(result.Matches) > 0 {
+		result.Reason = fmt.Sprintf("document: %d suspicious passage(s): %s", len(result.Matches), strings.Join(findings, ", "))
+	}
+
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// scorePassage combines directive and boosted heuristic matches in one passage using a noisy-OR,
+// returning the threat of the strongest match first
+func (s *DocumentScanner) scorePassage(text string) (float64, []ThreatType, []string) {
+	score, strongest := 0.0, 0.0
+	var top ThreatType
+	threats := make([]ThreatType, 0)
+	descriptions := make([]string, 0)
+
+	add := func(threat ThreatType, weight float64, description string) {
+		score = combineScores(score, weight)
+		if weight > strongest {
+			strongest, top = weight, threat
+		}
+		threats = appendThreat(threats, threat)
+		descriptions = append(descriptions, description)
+	}
+
+	for _, directive := range s.directives {
+		if directive.pattern.MatchString(text) {
+			add(directive.threat, directive.weight, directive.description)
+		}
+	}
+
+	seenRules := make(map[string]bool)
+	for _, match := range s.heuristic.match(text) {
+		if seenRules[match.rule.description] {
+			continue
+		}
+		seenRules[match.rule.description] = true
+		add(match.rule.threat, min(match.rule.weight*documentRuleBoost, 0.99), match.rule.description)
+	}
+
+	ordered := []ThreatType{top}
+	for _, threat := range threats {
+		ordered = appendThreat(ordered, threat)
+	}
+	return score, ordered, descriptions
+}
+
+// documentPassages splits a document into paragraphs, returning trimmed byte ranges
+func documentPassages(document string) [][2]int {
+	passages := make([][2]int, 0)
+	start := 0
+	for _, brk := range append(paragraphBreak.FindAllStringIndex(document, -1), []int{len(document), len(document)}) {
+		end := brk[0]
+		for start < end && isSpaceByte(document[start]) {
+			start++
+		}
+		trimmedEnd := end
+		for trimmedEnd > start && isSpaceByte(document[trimmedEnd-1]) {
+			trimmedEnd--
+		}
+		if trimmedEnd > start {
+			passages = append(passages, [2]int{start, trimmedEnd})
+		}
+		start = brk[1]
+	}
+	return passages
+}
+
+// isSpaceByte reports whether b is ASCII whitespace
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"sync"
+	"time"
+)
+
+// EndpointStats summarizes the calls made to a single LLM endpoint
+type EndpointStats struct {
+	Attempts         int64   `json:"attempts"`
+	Successes        int64   `json:"successes"`
+	Failures         int64   `json:"failures"`
+	AverageLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// endpointStatsRecorder counts calls per endpoint model
+type endpointStatsRecorder struct {
+	stats map[string]*endpointTally
+	mutex sync.Mutex
+}
+
+// endpointTally is the running total behind an endpoint's stats
+type endpointTally struct {
+	EndpointStats
+	totalLatency time.Duration
+}
+
+// newEndpointStatsRecorder creates an empty recorder
+func newEndpointStatsRecorder() *endpointStatsRecorder {
+	return &endpointStatsRecorder{stats: make(map[string]*endpointTally)}
+}
+
+// record counts one call to the endpoint, failed when err is non-nil
+func (r *endpointStatsRecorder) record(endpoint string, latency time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tally, exists := r.stats[endpoint]
+	if !exists {
+		tally = &endpointTally{}
+		r.stats[endpoint] = tally
+	}
+
+	tally.Attempts++
+	if err != nil {
+		tally.Failures++
+	} else {
+		tally.Successes++
+	}
+	tally.totalLatency += latency
+	tally.AverageLatencyMs = float64(tally.totalLatency.Milliseconds()) / float64(tally.Attempts)
+}
+
+// snapshot returns a copy of every endpoint's stats
+func (r *endpointStatsRecorder) snapshot() map[string]EndpointStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	snapshot := make(map[string]EndpointStats, len(r.stats))
+	for endpoint, tally := range r.stats {
+		snapshot[endpoint] = tally.EndpointStats
+	}
+	return snapshot
+}
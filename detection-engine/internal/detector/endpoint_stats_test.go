@@ -0,0 +1,78 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEndpointStatsRecorder(t *testing.T) {
+	recorder := newEndpointStatsRecorder()
+	recorder.record("model-a", 10*time.Millisecond, nil)
+	recorder.record("model-a", 30*time.Millisecond, errors.New("boom"))
+	recorder.record("model-b", 5*time.Millisecond, nil)
+
+	want := map[string]EndpointStats{
+		"model-a": {Attempts: 2, Successes: 1, Failures: 1, AverageLatencyMs: 20},
+		"model-b": {Attempts: 1, Successes: 1, AverageLatencyMs: 5},
+	}
+	snapshot := recorder.snapshot()
+	if len(snapshot) != len(want) {
+		t.Fatalf("snapshot = %+v, want %+v", snapshot, want)
+	}
+	for endpoint, stats := range want {
+		if snapshot[endpoint] != stats {
+			t.Errorf("%s stats = %+v, want %+v", endpoint, snapshot[endpoint], stats)
+		}
+	}
+
+	// Snapshots are copies that later calls don't change
+	recorder.record("model-b", 5*time.Millisecond, nil)
+	if snapshot["model-b"].Attempts != 1 {
+		t.Errorf("snapshot changed after a later call: %+v", snapshot["model-b"])
+	}
+}
+
+func TestEndpointStatsTrackFailingEndpoint(t *testing.T) {
+	failing, failingCalls := newHuggingFaceServer(t, http.StatusInternalServerError, "", 0)
+	healthy, healthyCalls := newHuggingFaceServer(t, http.StatusOK, "SAFE", 0.99)
+
+	failingModel := testModel("failing", ProviderHuggingFace, failing.URL)
+	healthyModel := testModel("healthy", ProviderHuggingFace, healthy.URL)
+	healthyModel.Priority = 2
+	detector := newTestLLMDetector(t, failingModel, healthyModel)
+
+	for i := 0; i < 3; i++ {
+		if _, err := detector.Detect(context.Background(), "hello there"); err != nil {
+			t.Fatalf("Detect %d: %v", i+1, err)
+		}
+	}
+	if failingCalls.Load() != 3 || healthyCalls.Load() != 3 {
+		t.Fatalf("calls = failing %d healthy %d, want 3 each", failingCalls.Load(), healthyCalls.Load())
+	}
+
+	stats := detector.EndpointStats()
+	if got := stats["failing"]; got.Attempts != 3 || got.Successes != 0 || got.Failures != 3 {
+		t.Errorf("failing stats = %+v, want 3 attempts all failed", got)
+	}
+	if got := stats["healthy"]; got.Attempts != 3 || got.Successes != 3 || got.Failures != 0 {
+		t.Errorf("healthy stats = %+v, want 3 attempts all succeeded", got)
+	}
+
+	// The diagnostics report the same counters per endpoint
+	diagnostic := NewPipelineWithDetector(newTestLogger(), detector).DiagnoseLLMEndpoints()
+	reported := make(map[string]EndpointStats)
+	for i := 0; i < 2; i++ {
+		endpoint, ok := diagnostic[fmt.Sprintf("endpoint_%d", i)].(map[string]interface{})
+		if !ok {
+			t.Fatalf("diagnostic endpoint_%d = %v, want an endpoint entry", i, diagnostic[fmt.Sprintf("endpoint_%d", i)])
+		}
+		reported[endpoint["model"].(string)] = endpoint["stats"].(EndpointStats)
+	}
+	if reported["failing"] != stats["failing"] || reported["healthy"] != stats["healthy"] {
+		t.Errorf("diagnosed stats = %+v, want %+v", reported, stats)
+	}
+}
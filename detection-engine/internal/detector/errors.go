@@ -0,0 +1,211 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorCategory classifies upstream provider failures
+type ErrorCategory string
+
+const (
+	ErrorCategoryAuth      ErrorCategory = "auth"       // Bad or missing API key (401/403)
+	ErrorCategoryTimeout   ErrorCategory = "timeout"    // Request deadline exceeded
+	ErrorCategoryRateLimit ErrorCategory = "rate_limit" // Provider throttling (429)
+	ErrorCategoryServer    ErrorCategory = "server"     // Provider outage (5xx)
+	ErrorCategoryLoading   ErrorCategory = "loading"    // Model cold-starting (HuggingFace 503)
+	ErrorCategoryQuota     ErrorCategory = "quota"      // Daily quota or credits exhausted
+	ErrorCategoryUnknown   ErrorCategory = "unknown"
+)
+
+// Sentinel provider errors for use with errors.Is
+var (
+	ErrAuth      = &ProviderError{Category: ErrorCategoryAuth, Message: "provider authentication failed"}
+	ErrTimeout   = &ProviderError{Category: ErrorCategoryTimeout, Message: "provider request timed out"}
+	ErrRateLimit = &ProviderError{Category: ErrorCategoryRateLimit, Message: "provider rate limit exceeded"}
+	ErrServer    = &ProviderError{Category: ErrorCategoryServer, Message: "provider server error"}
+	ErrQuota     = &ProviderError{Category: ErrorCategoryQuota, Message: "provider quota exhausted"}
+)
+
+// ProviderError represents a categorized failure returned by an upstream model provider
+type ProviderError struct {
+	Category   ErrorCategory
+	StatusCode int
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Category, e.Message)
+	}
+	return fmt.Sprintf("%s error: %s", e.Category, e.Message)
+}
+
+// Is matches any ProviderError of the same category
+func (e *ProviderError) Is(target error) bool {
+	t, ok := target.(*ProviderError)
+	return ok && t.Category == e.Category
+}
+
+// ErrorCategoryOf returns the category of a provider error, or unknown if uncategorized
+func ErrorCategoryOf(err error) ErrorCategory {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Category
+	}
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return ErrorCategoryRateLimit
+	}
+	var loadingErr *ModelLoadingError
+	if errors.As(err, &loadingErr) {
+		return ErrorCategoryLoading
+	}
+	return ErrorCategoryUnknown
+}
+
+// isBreakerNeutral reports whether an error says nothing about provider health
+// and therefore must not count as a circuit breaker failure. A cancelled request
+// (client gone, race already won, shutdown) is the caller's doing, not the provider's.
+func isBreakerNeutral(err error) bool {
+	var loadingErr *ModelLoadingError
+	return errors.Is(err, ErrAuth) || errors.Is(err, ErrQuota) || errors.Is(err, ErrDispatchSaturated) ||
+		errors.Is(err, context.Canceled) || errors.As(err, &loadingErr)
+}
+
+// ModelLoadingError is returned while a HuggingFace model is still cold-starting
+type ModelLoadingError struct {
+	EstimatedTime time.Duration
+	Message       string
+}
+
+func (e *ModelLoadingError) Error() string {
+	return fmt.Sprintf("model loading (estimated %s): %s", e.EstimatedTime, e.Message)
+}
+
+// HuggingFaceError is an {"error": ...} object returned by a HuggingFace endpoint in place of labels
+type HuggingFaceError struct {
+	Message  string   `json:"error"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (e *HuggingFaceError) Error() string {
+	if len(e.Warnings) > 0 {
+		return fmt.Sprintf("huggingface error: %s (%s)", e.Message, strings.Join(e.Warnings, "; "))
+	}
+	return "huggingface error: " + e.Message
+}
+
+// RateLimitError is returned when a provider responds with HTTP 429
+type RateLimitError struct {
+	StatusCode int
+	RetryAfter time.Duration // Provider-supplied backoff, zero if not given
+	Body       string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("API error %d: rate limited, retry after %s: %s", e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("API error %d: rate limited: %s", e.StatusCode, e.Body)
+}
+
+// Is allows errors.Is(err, ErrRateLimit) to match rate limit errors
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimit
+}
+
+// newAPIError builds an error from a non-200 provider response
+func newAPIError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	// Exhausted daily quota or credits persist far longer than ordinary throttling
+	if isQuotaExhausted(resp.StatusCode, string(body)) {
+		return &ProviderError{Category: ErrorCategoryQuota, StatusCode: resp.StatusCode, Message: string(body)}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Body:       string(body),
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &ProviderError{Category: ErrorCategoryAuth, StatusCode: resp.StatusCode, Message: string(body)}
+	case resp.StatusCode >= 500:
+		return &ProviderError{Category: ErrorCategoryServer, StatusCode: resp.StatusCode, Message: string(body)}
+	}
+
+	return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+}
+
+// quotaMarkers are response body fragments that identify quota or credit exhaustion
+var quotaMarkers = []string{
+	"insufficient_quota",
+	"exceeded your current quota",
+	"insufficient credits",
+	"free-models-per-day",
+	"perday",
+	"per day",
+}
+
+// isQuotaExhausted reports whether a provider response signals exhausted quota or credits
+// rather than short-lived throttling
+func isQuotaExhausted(statusCode int, body string) bool {
+	if statusCode == http.StatusPaymentRequired {
+		return true
+	}
+	if statusCode != http.StatusTooManyRequests && statusCode != http.StatusForbidden {
+		return false
+	}
+
+	lower := strings.ToLower(body)
+	for _, marker := range quotaMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRequestError categorizes a transport-level failure from the HTTP client
+func newRequestError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &ProviderError{Category: ErrorCategoryTimeout, Message: err.Error()}
+	}
+	return fmt.Errorf("request failed: %w", err)
+}
+
+// parseRetryAfter parses a Retry-After header given either as seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiResponse builds a provider response with the given status, body and headers
+func apiResponse(status int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestNewAPIErrorRateLimit(t *testing.T) {
+	err := newAPIError(apiResponse(http.StatusTooManyRequests, "slow down", http.Header{"Retry-After": {"7"}}))
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("newAPIError(429) = %T %v, want *RateLimitError", err, err)
+	}
+	if rateLimitErr.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %v, want 7s", rateLimitErr.RetryAfter)
+	}
+	if !errors.Is(err, ErrRateLimit) {
+		t.Error("errors.Is(err, ErrRateLimit) = false, want true")
+	}
+
+	if err := newAPIError(apiResponse(http.StatusInternalServerError, "boom", nil)); errors.As(err, &rateLimitErr) {
+		t.Errorf("newAPIError(500) = %v, want an error that is not a rate limit", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		want  time.Duration
+	}{
+		"seconds":  {"30", 30 * time.Second},
+		"empty":    {"", 0},
+		"zero":     {"0", 0},
+		"negative": {"-5", 0},
+		"garbage":  {"soon", 0},
+		"past":     {"Mon, 02 Jan 2006 15:04:05 GMT", 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want about a minute", date, got)
+	}
+}
+
+func TestNewAPIErrorCategories(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		body   string
+		want   ErrorCategory
+	}{
+		"unauthorized":        {http.StatusUnauthorized, "invalid api key", ErrorCategoryAuth},
+		"forbidden":           {http.StatusForbidden, "access denied", ErrorCategoryAuth},
+		"rate limited":        {http.StatusTooManyRequests, "slow down", ErrorCategoryRateLimit},
+		"quota exhausted":     {http.StatusTooManyRequests, `{"error":"You exceeded your current quota"}`, ErrorCategoryQuota},
+		"payment required":    {http.StatusPaymentRequired, "insufficient credits", ErrorCategoryQuota},
+		"internal error":      {http.StatusInternalServerError, "boom", ErrorCategoryServer},
+		"bad gateway":         {http.StatusBadGateway, "upstream down", ErrorCategoryServer},
+		"service unavailable": {http.StatusServiceUnavailable, "maintenance", ErrorCategoryServer},
+		"bad request":         {http.StatusBadRequest, "malformed", ErrorCategoryUnknown},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := newAPIError(apiResponse(tt.status, tt.body, nil))
+			if got := ErrorCategoryOf(err); got != tt.want {
+				t.Errorf("category of %d %q = %q, want %q (%v)", tt.status, tt.body, got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewRequestErrorCategories(t *testing.T) {
+	if got := ErrorCategoryOf(newRequestError(context.DeadlineExceeded)); got != ErrorCategoryTimeout {
+		t.Errorf("category of a deadline = %q, want timeout", got)
+	}
+	if err := newRequestError(context.DeadlineExceeded); !errors.Is(err, ErrTimeout) {
+		t.Errorf("errors.Is(%v, ErrTimeout) = false, want true", err)
+	}
+	if got := ErrorCategoryOf(newRequestError(errors.New("connection refused"))); got != ErrorCategoryUnknown {
+		t.Errorf("category of a refused connection = %q, want unknown", got)
+	}
+}
@@ -0,0 +1,45 @@
+package detector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// etagVerdict is the part of a response that identifies its representation for caching
+type etagVerdict struct {
+	IsMalicious bool     `json:"is_malicious"`
+	Confidence  float64  `json:"confidence"`
+	ThreatTypes []string `json:"threat_types"`
+	Severity    Severity `json:"severity"`
+	Action      Action   `json:"action"`
+}
+
+// ResponseETag derives a strong ETag from the normalized input, the per-request
+// config and the verdict, so a different threshold or verdict never reuses a tag.
+// Only the verdict fields are hashed: latency, reasons and per-model details vary
+// between calls that reach the same decision.
+func ResponseETag(req *DetectionRequest, response *DetectionResponse) string {
+	hash := sha256.New()
+	hash.Write([]byte(resultCacheKey(req)))
+	for _, message := range req.Messages {
+		hash.Write([]byte{0})
+		hash.Write([]byte(message.Role))
+		hash.Write([]byte{0})
+		hash.Write([]byte(message.Content))
+	}
+
+	verdict := etagVerdict{
+		IsMalicious: response.IsMalicious,
+		Confidence:  response.Confidence,
+		ThreatTypes: response.ThreatTypes,
+		Severity:    response.Severity,
+		Action:      response.Action,
+	}
+	if body, err := json.Marshal(verdict); err == nil {
+		hash.Write([]byte{0})
+		hash.Write(body)
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
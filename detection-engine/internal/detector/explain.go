@@ -0,0 +1,113 @@
+package detector
+
+import (
+	"fmt"
+)
+
+// explainThresholds are the candidate thresholds an explanation reports verdicts for
+var explainThresholds = []float64{0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Explanation breaks a detection down into the evidence behind its score, for tuning thresholds
+type Explanation struct {
+	Score            float64            `json:"score"`
+	Threshold        float64            `json:"threshold"` // Global threshold the verdict was decided with
+	ThreatThresholds map[string]float64 `json:"threat_thresholds,omitempty"`
+	Threats          []ThreatEvidence   `json:"threats"`
+	DecodedVariants  []string           `json:"decoded_variants,omitempty"` // Decodings analyzed alongside the original text
+	ModelScores      []ModelScore       `json:"model_scores,omitempty"`
+	ThresholdSweep   []ThresholdVerdict `json:"threshold_sweep"`
+}
+
+// explainRequest returns a copy of req that asks for per-model results, leaving the
+// caller's config untouched
+func explainRequest(req *DetectionRequest) *DetectionRequest {
+	config := *req.Config
+	config.DetailedResponse = true
+	explained := *req
+	explained.Config = &config
+	return &explained
+}
+
+// ThreatEvidence lists what contributed to one detected threat type
+type ThreatEvidence struct {
+	ThreatType string   `json:"threat_type"`
+	Evidence   []string `json:"evidence"`
+}
+
+// ModelScore is a single model's score in an explanation
+type ModelScore struct {
+	Model string  `json:"model"`
+	Score float64 `json:"score"`
+	Error string  `json:"error,omitempty"`
+}
+
+// ThresholdVerdict is the verdict the score would get at a candidate threshold
+type ThresholdVerdict struct {
+	Threshold   float64 `json:"threshold"`
+	IsMalicious bool    `json:"is_malicious"`
+}
+
+// explainDetection collects heuristic matches, decoded variants and per-model scores
+// for a response, together with the verdict at each candidate threshold
+func explainDetection(req *DetectionRequest, response *DetectionResponse, defaultThreshold float64, llmDetector *LLMDetector) *Explanation {
+	config := req.Config
+	if config == nil {
+		config = &DetectionConfig{}
+	}
+	threshold := config.ConfidenceThreshold
+	if threshold == 0 {
+		threshold = defaultThreshold
+	}
+
+	explanation := &Explanation{
+		Score:            response.Confidence,
+		Threshold:        threshold,
+		ThreatThresholds: config.ThreatThresholds,
+		Threats:          make([]ThreatEvidence, 0),
+		ThresholdSweep:   make([]ThresholdVerdict, 0, len(explainThresholds)),
+	}
+
+	evidence := make(map[ThreatType][]string)
+	order := make([]ThreatType, 0)
+	addEvidence := func(threat ThreatType, item string) {
+		if _, seen := evidence[threat]; !seen {
+			order = append(order, threat)
+		}
+		evidence[threat] = append(evidence[threat], item)
+	}
+
+	for _, threat := range response.ThreatTypes {
+		addEvidence(ThreatType(threat), fmt.Sprintf("reported by %s: %s", response.Endpoint, response.Reason))
+	}
+
+	text := requestText(req)
+	for _, match := range llmDetector.heuristic.match(text) {
+		addEvidence(match.rule.threat, fmt.Sprintf("heuristic rule %q (weight %.2f) matched %q", match.rule.description, match.rule.weight, text[match.start:match.end]))
+	}
+
+	for _, variant := range llmDetector.decodeVariants(text) {
+		explanation.DecodedVariants = append(explanation.DecodedVariants, variant.decoding)
+	}
+
+	for _, modelResult := range response.ModelResults {
+		explanation.ModelScores = append(explanation.ModelScores, ModelScore{Model: modelResult.Model, Score: modelResult.Score, Error: modelResult.Error})
+		for _, threat := range modelResult.ThreatTypes {
+			addEvidence(ThreatType(threat), fmt.Sprintf("model %s scored %.2f", modelResult.Model, modelResult.Score))
+		}
+	}
+
+	for _, threat := range order {
+		explanation.Threats = append(explanation.Threats, ThreatEvidence{ThreatType: string(threat), Evidence: evidence[threat]})
+	}
+
+	for _, candidate := range explainThresholds {
+		sweepConfig := *config
+		sweepConfig.ConfidenceThreshold = candidate
+		explanation.ThresholdSweep = append(explanation.ThresholdSweep, ThresholdVerdict{
+			Threshold:   candidate,
+			IsMalicious: exceedsThreshold(response.Confidence, response.ThreatTypes, &sweepConfig, candidate),
+		})
+	}
+
+	return explanation
+}
@@ -0,0 +1,28 @@
+package detector
+
+// FailureMode decides the verdict returned when no model could classify the input
+type FailureMode string
+
+const (
+	FailOpen   FailureMode = "open"   // Report the input as safe and return the error (default)
+	FailClosed FailureMode = "closed" // Report the input as malicious so callers block it
+)
+
+// verdict returns the classification, confidence and reason wording for a failed detection
+func (m FailureMode) verdict() (bool, float64, string) {
+	if m == FailClosed {
+		return true, 1.0, "fail-closed malicious classification"
+	}
+	return false, 0.5, "safe classification"
+}
+
+// settle returns a failure response under the failure mode. Fail-closed verdicts
+// are returned without the error so HTTP and gRPC clients receive the blocking
+// verdict instead of a 503; the failure is still described in the reason.
+func (m FailureMode) settle(response *DetectionResponse, err error) (*DetectionResponse, error) {
+	if m != FailClosed {
+		return response, err
+	}
+	response.FailedClosed = true
+	return response, nil
+}
@@ -0,0 +1,95 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFailureModeVerdict(t *testing.T) {
+	tests := map[FailureMode]struct {
+		wantMalicious  bool
+		wantConfidence float64
+	}{
+		"":         {false, 0.5},
+		FailOpen:   {false, 0.5},
+		FailClosed: {true, 1.0},
+	}
+	for mode, tt := range tests {
+		t.Run(string(mode), func(t *testing.T) {
+			isMalicious, confidence, classification := mode.verdict()
+			if isMalicious != tt.wantMalicious || confidence != tt.wantConfidence || classification == "" {
+				t.Errorf("verdict() = %v %v %q, want %v %v", isMalicious, confidence, classification, tt.wantMalicious, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestFallbackPipelineAllModelsFailed(t *testing.T) {
+	tests := map[FailureMode]struct {
+		wantMalicious bool
+		wantErr       bool
+		wantReason    string
+	}{
+		FailOpen:   {wantMalicious: false, wantErr: true, wantReason: "safe classification"},
+		FailClosed: {wantMalicious: true, wantErr: false, wantReason: "fail-closed malicious classification"},
+	}
+	for mode, tt := range tests {
+		t.Run(string(mode), func(t *testing.T) {
+			pipeline := newFakeProviderPipeline(t,
+				fakeModel{name: "first", err: errors.New("upstream down")},
+				fakeModel{name: "second", err: errors.New("upstream down")},
+			)
+			pipeline.SetFailureMode(mode)
+
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello there"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Analyze error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrAllModelsFailed) {
+				t.Errorf("Analyze error = %v, want ErrAllModelsFailed", err)
+			}
+			if response.IsMalicious != tt.wantMalicious || response.FailedClosed != (mode == FailClosed) {
+				t.Errorf("response = malicious %v failed closed %v, want %v %v", response.IsMalicious, response.FailedClosed, tt.wantMalicious, mode == FailClosed)
+			}
+			if !strings.Contains(response.Reason, tt.wantReason) || response.Endpoint != "fallback_failed" {
+				t.Errorf("response = reason %q endpoint %q, want %q from fallback_failed", response.Reason, response.Endpoint, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPipelineFailureModes(t *testing.T) {
+	failing, _ := newHuggingFaceServer(t, http.StatusInternalServerError, "", 0)
+
+	tests := map[string]struct {
+		keyEnv       string // API key variable of the only model
+		wantEndpoint string
+	}{
+		"no api key": {keyEnv: "DETECTOR_TEST_UNSET_KEY", wantEndpoint: "fallback"},
+		"llm error":  {keyEnv: testAPIKeyEnv, wantEndpoint: "error"},
+	}
+	for name, tt := range tests {
+		for _, mode := range []FailureMode{FailOpen, FailClosed} {
+			t.Run(name+"/"+string(mode), func(t *testing.T) {
+				model := testModel("failing", ProviderHuggingFace, failing.URL)
+				model.APIKeyEnvVar = tt.keyEnv
+				pipeline := NewPipelineWithDetector(newTestLogger(), newTestLLMDetector(t, model))
+				pipeline.SetFailureMode(mode)
+
+				response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello there"})
+				if closed := mode == FailClosed; (err == nil) != closed {
+					t.Fatalf("Analyze error = %v, want an error only when failing open", err)
+				}
+				if response.IsMalicious != (mode == FailClosed) || response.FailedClosed != (mode == FailClosed) {
+					t.Errorf("response = malicious %v failed closed %v, want both %v", response.IsMalicious, response.FailedClosed, mode == FailClosed)
+				}
+				if response.Endpoint != tt.wantEndpoint {
+					t.Errorf("Endpoint = %q, want %q", response.Endpoint, tt.wantEndpoint)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EndpointFanOut makes Detect query its endpoints concurrently instead of one after another
+type EndpointFanOut struct {
+	Enabled        bool
+	MaxConcurrency int // Endpoints called at once, 0 calls them all
+}
+
+// endpointOutcome is one endpoint's verdict, or its error, in a fan-out
+type endpointOutcome struct {
+	endpoint LLMEndpoint
+	verdict  *variantVerdict
+	err      error
+}
+
+// detectFanOut queries every endpoint concurrently under a shared context. The first
+// verdict scoring 0.8 or more wins and cancels the endpoints still running; otherwise
+// the highest-scoring verdict is returned once all endpoints have answered.
+func (l *LLMDetector) detectFanOut(ctx context.Context, variants []textVariant, text string, startTime time.Time) (*DetectionResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := l.fanOut.MaxConcurrency
+	if limit <= 0 || limit > len(l.endpoints) {
+		limit = len(l.endpoints)
+	}
+	slots := make(chan struct{}, limit)
+
+	// Buffered so endpoints finishing after an early return don't block
+	outcomes := make(chan endpointOutcome, len(l.endpoints))
+	for _, endpoint := range l.endpoints {
+		go func(endpoint LLMEndpoint) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				outcomes <- endpointOutcome{endpoint: endpoint, err: ctx.Err()}
+				return
+			}
+			verdict, err := l.analyzeVariants(ctx, endpoint, variants, text, false)
+			outcomes <- endpointOutcome{endpoint: endpoint, verdict: verdict, err: err}
+		}(endpoint)
+	}
+
+	var best *endpointOutcome
+	var lastError error
+	for range l.endpoints {
+		outcome := <-outcomes
+		if outcome.err != nil {
+			lastError = outcome.err
+			continue
+		}
+		if best == nil || outcome.verdict.score > best.verdict.score {
+			best = &outcome
+		}
+		if outcome.verdict.score >= 0.8 {
+			break
+		}
+	}
+
+	if best == nil {
+		return &DetectionResult{
+			Method:      MethodLLM,
+			Score:       0.5,
+			ThreatTypes: make([]ThreatType, 0),
+			Reason:      fmt.Sprintf("All LLM endpoints failed, last error: %v", lastError),
+			Duration:    time.Since(startTime),
+		}, fmt.Errorf("all LLM endpoints failed, last error: %w", lastError)
+	}
+
+	return &DetectionResult{
+		Method:      MethodLLM,
+		Score:       best.verdict.score,
+		ThreatTypes: best.verdict.threatTypes,
+		Reason:      best.verdict.reason,
+		Endpoint:    best.endpoint.Model,
+		Duration:    time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,115 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// captureGeminiRequest runs one detection against a fake Gemini endpoint configured with
+// generation and returns the raw JSON body it received
+func captureGeminiRequest(t *testing.T, generation GenerationSettings) map[string]json.RawMessage {
+	t.Helper()
+
+	bodies := make(chan map[string]json.RawMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode Gemini request: %v", err)
+		}
+		bodies <- body
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"SCORE:0.1 THREATS:none REASON:benign"}]}}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	model := testModel("gemini-test", ProviderGoogle, server.URL)
+	model.Generation = generation
+	detector := newTestLLMDetector(t, model)
+
+	if _, err := detector.detectWithSpecificEndpoint(context.Background(), "hello", model, variantScopeOriginal); err != nil {
+		t.Fatalf("detectWithSpecificEndpoint: %v", err)
+	}
+	return <-bodies
+}
+
+// decodeField unmarshals one field of a captured request body
+func decodeField(t *testing.T, body map[string]json.RawMessage, field string, target interface{}) {
+	t.Helper()
+
+	raw, ok := body[field]
+	if !ok {
+		t.Fatalf("request body has no %q field: %s", field, body)
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		t.Fatalf("decode %s: %v", field, err)
+	}
+}
+
+func TestGeminiRequestIncludesGenerationConfig(t *testing.T) {
+	tests := map[string]struct {
+		generation GenerationSettings
+		wantConfig map[string]interface{}
+		wantSafety []GeminiSafetySetting
+	}{
+		"defaults": {
+			generation: GenerationSettings{},
+			wantConfig: map[string]interface{}{
+				"temperature":      0.0,
+				"maxOutputTokens":  float64(defaultMaxOutputTokens),
+				"responseMimeType": "text/plain",
+			},
+			wantSafety: []GeminiSafetySetting{
+				{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
+				{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
+				{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
+				{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+			},
+		},
+		"configured": {
+			generation: GenerationSettings{
+				Temperature:     0.2,
+				MaxOutputTokens: 512,
+				SafetySettings:  []SafetySetting{{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_ONLY_HIGH"}},
+			},
+			wantConfig: map[string]interface{}{
+				"temperature":      0.2,
+				"maxOutputTokens":  512.0,
+				"responseMimeType": "text/plain",
+			},
+			wantSafety: []GeminiSafetySetting{
+				{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_ONLY_HIGH"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			body := captureGeminiRequest(t, tt.generation)
+
+			var config map[string]interface{}
+			decodeField(t, body, "generationConfig", &config)
+			if !reflect.DeepEqual(config, tt.wantConfig) {
+				t.Errorf("generationConfig = %v, want %v", config, tt.wantConfig)
+			}
+
+			var safety []GeminiSafetySetting
+			decodeField(t, body, "safetySettings", &safety)
+			if !reflect.DeepEqual(safety, tt.wantSafety) {
+				t.Errorf("safetySettings = %+v, want %+v", safety, tt.wantSafety)
+			}
+		})
+	}
+}
+
+func TestGeminiJSONModeRequestsSchema(t *testing.T) {
+	body := captureGeminiRequest(t, GenerationSettings{JSONMode: true})
+
+	var config GeminiGenerationConfig
+	decodeField(t, body, "generationConfig", &config)
+	if config.ResponseMimeType != "application/json" || config.ResponseSchema == nil {
+		t.Errorf("generationConfig = %+v, want a JSON response schema", config)
+	}
+}
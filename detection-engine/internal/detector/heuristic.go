@@ -0,0 +1,303 @@
+package detector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HeuristicDetector implements fast, offline regex-based detection of common injection patterns
+type HeuristicDetector struct {
+	rules      []heuristicRule
+	signatures *SignatureStore // Optional externally maintained signatures
+}
+
+// heuristicRule is a single weighted pattern associated with a threat type
+type heuristicRule struct {
+	threat      ThreatType
+	pattern     *regexp.Regexp
+	weight      float64 // Contribution to the score when matched (0-1)
+	description string
+}
+
+// heuristicMatch records where a rule matched within the analyzed text
+type heuristicMatch struct {
+	rule  heuristicRule
+	start int
+	end   int
+}
+
+// NewHeuristicDetector creates a heuristic detector with the built-in rule set
+func NewHeuristicDetector() *HeuristicDetector {
+	return NewHeuristicDetectorWithSignatures(nil)
+}
+
+// NewHeuristicDetectorWithSignatures creates a heuristic detector that also consults a signature store
+func NewHeuristicDetectorWithSignatures(signatures *SignatureStore) *HeuristicDetector {
+	return &HeuristicDetector{
+		rules:      getDefaultHeuristicRules(),
+		signatures: signatures,
+	}
+}
+
+// Detect scores the text and any decoded variants without making network calls
+func (h *HeuristicDetector) Detect(text string, decodedVariants []string) *DetectionResult {
+	startTime := time.Now()
+
+	matches := h.match(text)
+
+	// Only matches in the original text have meaningful offsets
+	spans := make([]Match, 0, len(matches))
+	for _, match := range matches {
+		spans = append(spans, newMatch(text, match.rule.threat, match.start, match.end))
+	}
+
+	// Payloads that only surface after decoding are encoding attacks in their own right
+	encodedPayload := false
+	for _, variant := range decodedVariants {
+		if variantMatches := h.match(variant); len(variantMatches) > 0 {
+			matches = append(matches, variantMatches...)
+			encodedPayload = true
+		}
+	}
+
+	// Instructions split across enumerated fragments only match once the fragments are joined
+	splitPayload := false
+	if joined := reassembleFragments(text); joined != "" {
+		seen := make(map[string]bool)
+		for _, match := range matches {
+			seen[match.rule.description] = true
+		}
+		for _, match := range h.match(joined) {
+			if !seen[match.rule.description] {
+				matches = append(matches, match)
+				splitPayload = true
+			}
+		}
+	}
+
+	result := h.scoreMatches(matches)
+	if encodedPayload {
+		result.Score = combineScores(result.Score, 0.5)
+		result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypeEncodingAttack)
+		result.Reason += " (payload hidden in encoded content)"
+	}
+	if splitPayload {
+		result.Score = combineScores(result.Score, 0.5)
+		result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypePayloadSplitting)
+		result.Reason += " (payload split across fragments)"
+	}
+
+	if len(spans) > 0 {
+		result.Matches = spans
+	}
+
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// match returns every rule match in the text
+func (h *HeuristicDetector) match(text string) []heuristicMatch {
+	matches := make([]heuristicMatch, 0)
+	rules := append(h.rules[:len(h.rules):len(h.rules)], h.signatures.Rules()...)
+	for _, rule := range rules {
+		for _, loc := range rule.pattern.FindAllStringIndex(text, -1) {
+			matches = append(matches, heuristicMatch{rule: rule, start: loc[0], end: loc[1]})
+		}
+	}
+	return matches
+}
+
+// scoreMatches combines rule matches into a detection result using a noisy-OR of rule weights
+func (h *HeuristicDetector) scoreMatches(matches []heuristicMatch) *DetectionResult {
+	result := &DetectionResult{
+		Method:      MethodHeuristic,
+		Score:       0.0,
+		ThreatTypes: make([]ThreatType, 0),
+		Reason:      "heuristic: no known injection patterns found",
+		Endpoint:    localEndpointName,
+	}
+
+	if len(matches) == 0 {
+		return result
+	}
+
+	// Each rule contributes once regardless of how often it matched
+	seenRules := make(map[string]bool)
+	descriptions := make([]string, 0)
+	for _, match := range matches {
+		if seenRules[match.rule.description] {
+			continue
+		}
+		seenRules[match.rule.description] = true
+
+		result.Score = combineScores(result.Score, match.rule.weight)
+		result.ThreatTypes = appendThreat(result.ThreatTypes, match.rule.threat)
+		descriptions = append(descriptions, match.rule.description)
+	}
+
+	result.Reason = fmt.Sprintf("heuristic: matched %s", strings.Join(descriptions, ", "))
+	return result
+}
+
+// combineScores merges two independent confidence scores (noisy-OR)
+func combineScores(a, b float64) float64 {
+	return 1 - (1-a)*(1-b)
+}
+
+// appendThreat adds a threat type if it is not already present
+func appendThreat(threats []ThreatType, threat ThreatType) []ThreatType {
+	for _, existing := range threats {
+		if existing == threat {
+			return threats
+		}
+	}
+	return append(threats, threat)
+}
+
+// getDefaultHeuristicRules returns the built-in rule set
+func getDefaultHeuristicRules() []heuristicRule {
+	return []heuristicRule{
+		// Injection / instruction override
+		{
+			threat:      ThreatTypeInjection,
+			pattern:     regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[^.\n]{0,40}\b(previous|prior|above|earlier|all|any|your|the)\b[^.\n]{0,40}\b(instructions?|rules|guidelines|prompts?|directions|directives)\b`),
+			weight:      0.85,
+			description: "instruction override",
+		},
+		{
+			// Non-ASCII words are left without \b, which only understands ASCII word characters
+			threat: ThreatTypeInjection,
+			pattern: regexp.MustCompile(`(?i)` +
+				`\b(ignora|ignorar|ignore|olvida|omite)\b[^.\n]{0,40}\b(instrucciones|reglas|indicaciones|directrices)\b|` + // es
+				`\b(ignore[rz]?|oublie[rz]?)\b[^.\n]{0,40}\b(instructions|r[eè]gles|consignes|directives)\b|` + // fr
+				`\b(ignoriere|ignorieren|vergiss|missachte)\b[^.\n]{0,40}\b(anweisungen|regeln|instruktionen|vorgaben)\b|` + // de
+				`\b(ignore|ignora|ignorar|esque[cç]a)\b[^.\n]{0,40}\b(instru[cç][oõ]es|regras|diretrizes)|` + // pt
+				`\b(ignora|dimentica)\b[^.\n]{0,40}\b(istruzioni|regole|direttive)\b|` + // it
+				`(игнорируй|проигнорируй|забудь)[^.\n]{0,40}(инструкции|правила|указания)|` + // ru
+				`(忽略|无视|忽视|忘记)[^。\n]{0,20}(指令|指示|规则|提示)`), // zh
+			weight:      0.85,
+			description: "instruction override (non-English)",
+		},
+		{
+			threat:      ThreatTypeInjection,
+			pattern:     regexp.MustCompile(`(?i)\b(end of (the )?(previous|prior) (task|instructions?|conversation)|new (task|instructions?)\s*:)`),
+			weight:      0.6,
+			description: "context switch",
+		},
+		{
+			threat:      ThreatTypeInjection,
+			pattern:     regexp.MustCompile(`\{\{[^}]*\}\}|\$\{[^}]*\}`),
+			weight:      0.3,
+			description: "template injection syntax",
+		},
+
+		// Jailbreaks
+		{
+			threat:      ThreatTypeJailbreak,
+			pattern:     regexp.MustCompile(`(?i)\b(DAN|do anything now|developer mode|jailbreak(ed)?|unrestricted mode|god mode)\b`),
+			weight:      0.75,
+			description: "known jailbreak persona",
+		},
+		{
+			threat:      ThreatTypeJailbreak,
+			pattern:     regexp.MustCompile(`(?i)\b(act as|pretend (to be|you are)|roleplay as|simulate being)\b[^.\n]{0,40}\b(unrestricted|unfiltered|uncensored|evil|without (any )?(restrictions|limits|guidelines|rules))\b`),
+			weight:      0.8,
+			description: "unrestricted role-play",
+		},
+		{
+			threat:      ThreatTypeJailbreak,
+			pattern:     regexp.MustCompile(`(?i)\bwithout (any )?(restrictions|limitations|filters|guidelines|censorship|safety)\b`),
+			weight:      0.5,
+			description: "safety bypass request",
+		},
+
+		// Refusal suppression
+		{
+			threat:      ThreatTypeRefusalSuppression,
+			pattern:     regexp.MustCompile(`(?i)\b(never|don'?t|do not|must not|you can'?t|you cannot|you are not allowed to)\s+(ever\s+)?(refuse|decline|say (that )?(you|i) (can'?t|cannot|won'?t|are unable|am unable)|tell me (that )?you (can'?t|cannot))\b`),
+			weight:      0.6,
+			description: "refusal suppression",
+		},
+		{
+			threat:      ThreatTypeRefusalSuppression,
+			pattern:     regexp.MustCompile(`(?i)\b(never|don'?t|do not|without)\s+(apologi[sz](e|ing)|(add|adding|include|including|give|giving) (any )?(warnings?|disclaimers?|caveats?|moral lectures?))\b`),
+			weight:      0.3,
+			description: "disclaimer suppression",
+		},
+		{
+			threat:      ThreatTypeRefusalSuppression,
+			pattern:     regexp.MustCompile(`(?i)\b(respond|reply|answer)\s+(only|solely|exclusively)\s+with\s+the\s+(answer|output|result)\b`),
+			weight:      0.2,
+			description: "answer-only constraint",
+		},
+
+		// System prompt leaks
+		{
+			threat:      ThreatTypeSystemPromptLeak,
+			pattern:     regexp.MustCompile(`(?i)\b(reveal|show|print|display|repeat|output|tell me|what (is|are))\b[^.\n]{0,30}\b(system prompt|initial (instructions|prompt)|original (instructions|prompt)|hidden (instructions|prompt)|your (instructions|system message))\b`),
+			weight:      0.8,
+			description: "system prompt extraction",
+		},
+		{
+			threat: ThreatTypeSystemPromptLeak,
+			pattern: regexp.MustCompile(`(?i)` +
+				`\b(muestra|mu[eé]strame|revela|dime|imprime)\b[^.\n]{0,30}\b(prompt del sistema|instrucciones (del sistema|iniciales|originales))|` + // es
+				`\b(montre|r[eé]v[eè]le|affiche|donne)(-moi)?\b[^.\n]{0,30}\b(prompt syst[eè]me|invite syst[eè]me|instructions (syst[eè]me|initiales|d'origine))|` + // fr
+				`\b(zeige?|verrate|gib)\b[^.\n]{0,30}\b(systemprompt|system-prompt|systemanweisungen|urspr[uü]nglichen anweisungen)|` + // de
+				`\b(mostre|revele|diga|imprima)\b[^.\n]{0,30}\b(prompt do sistema|instru[cç][oõ]es (do sistema|iniciais|originais))|` + // pt
+				`\b(mostra|rivela|dimmi|stampa)\b[^.\n]{0,30}\b(prompt di sistema|istruzioni (di sistema|iniziali|originali))|` + // it
+				`(покажи|раскрой|выведи)[^.\n]{0,30}(системн(ый|ые) (промпт|инструкции)|исходные инструкции)|` + // ru
+				`(显示|透露|告诉我|输出|打印)[^。\n]{0,20}(系统提示|系统指令|初始指令|原始指令)`), // zh
+			weight:      0.8,
+			description: "system prompt extraction (non-English)",
+		},
+
+		// Data extraction
+		{
+			threat:      ThreatTypeDataExtraction,
+			pattern:     regexp.MustCompile(`(?i)['"]\s*(or|and)\s+['"]?\d+['"]?\s*=\s*['"]?\d+`),
+			weight:      0.7,
+			description: "SQL tautology",
+		},
+		{
+			threat:      ThreatTypeDataExtraction,
+			pattern:     regexp.MustCompile(`(?i)\b(drop|truncate)\s+table\b|\bunion\s+(all\s+)?select\b`),
+			weight:      0.7,
+			description: "SQL injection statement",
+		},
+		{
+			threat:      ThreatTypeDataExtraction,
+			pattern:     regexp.MustCompile(`(?i)(\.\./){2,}|/etc/(passwd|shadow)|c:\\windows\\system32`),
+			weight:      0.7,
+			description: "path traversal",
+		},
+		{
+			threat:      ThreatTypeDataExtraction,
+			pattern:     regexp.MustCompile(`(?i)\b(dump|list|reveal|show|give me|send)\b[^.\n]{0,30}\b(passwords?|credentials|api[_ ]?keys?|secrets?|user data)\b`),
+			weight:      0.6,
+			description: "sensitive data request",
+		},
+
+		// Delimiter attacks
+		{
+			threat:      ThreatTypeDelimiterAttack,
+			pattern:     regexp.MustCompile(`(?i)<\|?(im_start|im_end|system|endoftext)\|?>`),
+			weight:      0.7,
+			description: "chat template token",
+		},
+		{
+			threat:      ThreatTypeDelimiterAttack,
+			pattern:     regexp.MustCompile(`(?im)^\s*(system|assistant)\s*:`),
+			weight:      0.5,
+			description: "role delimiter",
+		},
+		{
+			threat:      ThreatTypeDelimiterAttack,
+			pattern:     regexp.MustCompile(`-{5,}|#{5,}|={5,}`),
+			weight:      0.3,
+			description: "delimiter run",
+		},
+	}
+}
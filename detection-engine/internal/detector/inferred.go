@@ -0,0 +1,65 @@
+package detector
+
+import "strings"
+
+// reasonThreatKeywords map phrases in a model's reason to the threat they describe,
+// checked in order so the more specific threats win
+var reasonThreatKeywords = []struct {
+	threat   ThreatType
+	keywords []string
+}{
+	{ThreatTypeSystemPromptLeak, []string{"system prompt", "system_leak", "system_prompt_leak", "hidden instructions", "initial instructions"}},
+	{ThreatTypeDataExtraction, []string{"data_extraction", "data extraction", "exfiltrat", "credential", "password", "api key", "secret"}},
+	{ThreatTypeJailbreak, []string{"jailbreak", "developer mode", "dan mode", "do anything now", "role-play", "roleplay"}},
+	{ThreatTypeEncodingAttack, []string{"encoding_attack", "encoded", "base64", "rot13", "hex-encoded", "obfuscat"}},
+	{ThreatTypeDelimiterAttack, []string{"delimiter", "fake system message", "role marker"}},
+	{ThreatTypePayloadSplitting, []string{"payload_splitting", "payload splitting", "split across"}},
+	{ThreatTypeRefusalSuppression, []string{"refusal_suppression", "refusal suppression", "never refuse", "do not refuse"}},
+	{ThreatTypeInjection, []string{"injection", "ignore previous", "ignore all previous", "override"}},
+}
+
+// inferThreatTypes labels malicious responses that came back without threat types, which
+// happens when a model reports a score but no parseable threats. The label is taken from
+// the reason text, then from local heuristics on the input, and is injection otherwise.
+// Fail-closed verdicts are left alone since nothing was detected.
+func inferThreatTypes(response *DetectionResponse, req *DetectionRequest, llmDetector *LLMDetector) {
+	if !response.IsMalicious || len(response.ThreatTypes) > 0 || response.FailedClosed {
+		return
+	}
+
+	if threats := threatsFromReason(response.Reason); len(threats) > 0 {
+		response.ThreatTypes = threats
+		return
+	}
+
+	text := req.Text
+	if response.MessageIndex != nil {
+		text = req.Messages[*response.MessageIndex].Content
+	}
+	if text != "" && llmDetector != nil {
+		local := llmDetector.DetectLocal(text)
+		for _, threat := range local.ThreatTypes {
+			response.ThreatTypes = append(response.ThreatTypes, string(threat))
+		}
+		if len(response.ThreatTypes) > 0 {
+			return
+		}
+	}
+
+	response.ThreatTypes = []string{string(ThreatTypeInjection)}
+}
+
+// threatsFromReason returns the threats a reason describes, most specific first
+func threatsFromReason(reason string) []string {
+	lower := strings.ToLower(reason)
+	threats := make([]string, 0)
+	for _, entry := range reasonThreatKeywords {
+		for _, keyword := range entry.keywords {
+			if strings.Contains(lower, keyword) {
+				threats = append(threats, string(entry.threat))
+				break
+			}
+		}
+	}
+	return threats
+}
@@ -0,0 +1,218 @@
+package detector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrJobQueueFull is returned when too many batch jobs are already waiting
+var ErrJobQueueFull = errors.New("batch job queue is full")
+
+// JobStatus is the lifecycle state of an async batch job
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+)
+
+// Analyzer is implemented by the detection pipelines
+type Analyzer interface {
+	Analyze(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error)
+}
+
+// BatchJob is a snapshot of an async batch job
+type BatchJob struct {
+	ID          string               `json:"job_id"`
+	Status      JobStatus            `json:"status"`
+	Total       int                  `json:"total"`
+	Completed   int                  `json:"completed"`
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Results     []*DetectionResponse `json:"results,omitempty"` // Set once the job is done
+	Errors      []string             `json:"errors,omitempty"`
+}
+
+// batchJob is the mutable state of a queued job
+type batchJob struct {
+	BatchJob
+	texts  []string
+	config *DetectionConfig
+}
+
+// JobStore queues batch detection jobs, processes them in the background and
+// keeps finished jobs in memory until their TTL expires
+type JobStore struct {
+	analyzer Analyzer
+	workers  int // Items analyzed concurrently within a job
+	ttl      time.Duration
+
+	jobs  map[string]*batchJob
+	queue chan *batchJob
+	mutex sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewJobStore creates a job store and starts its background worker.
+// At most queueSize jobs wait for processing; finished jobs are kept for ttl.
+func NewJobStore(analyzer Analyzer, workers, queueSize int, ttl time.Duration) *JobStore {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &JobStore{
+		analyzer: analyzer,
+		workers:  workers,
+		ttl:      ttl,
+		jobs:     make(map[string]*batchJob),
+		queue:    make(chan *batchJob, queueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go store.run()
+	return store
+}
+
+// Submit enqueues texts for detection and returns the new job's ID
+func (s *JobStore) Submit(texts []string, config *DetectionConfig) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	job := &batchJob{
+		BatchJob: BatchJob{
+			ID:        id,
+			Status:    JobPending,
+			Total:     len(texts),
+			CreatedAt: time.Now(),
+		},
+		texts:  texts,
+		config: config,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.evictExpired(time.Now())
+
+	select {
+	case s.queue <- job:
+	default:
+		return "", ErrJobQueueFull
+	}
+	s.jobs[id] = job
+
+	return id, nil
+}
+
+// Get returns a snapshot of the job, false if it doesn't exist or has expired
+func (s *JobStore) Get(id string) (BatchJob, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.evictExpired(time.Now())
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return BatchJob{}, false
+	}
+	return job.BatchJob, true
+}
+
+// Stop cancels running work and waits for the background worker to exit
+func (s *JobStore) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// run processes queued jobs one at a time until the store is stopped
+func (s *JobStore) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.queue:
+			s.process(job)
+		}
+	}
+}
+
+// process analyzes every text in a job with up to s.workers concurrent detections
+func (s *JobStore) process(job *batchJob) {
+	s.mutex.Lock()
+	job.Status = JobRunning
+	s.mutex.Unlock()
+
+	results := make([]*DetectionResponse, len(job.texts))
+	errs := make([]string, len(job.texts))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.workers, len(job.texts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				response, err := s.analyzer.Analyze(s.ctx, &DetectionRequest{Text: job.texts[i], Config: copyConfig(job.config)})
+				if err != nil {
+					errs[i] = err.Error()
+				} else {
+					results[i] = response
+				}
+
+				s.mutex.Lock()
+				job.Completed++
+				s.mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := range job.texts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	completedAt := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job.Status = JobDone
+	job.CompletedAt = &completedAt
+	job.Results = results
+	job.Errors = errs
+	job.texts = nil // Don't hold input text longer than needed
+}
+
+// evictExpired removes finished jobs older than the TTL. Callers must hold the lock.
+func (s *JobStore) evictExpired(now time.Time) {
+	for id, job := range s.jobs {
+		if job.CompletedAt != nil && now.Sub(*job.CompletedAt) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// newJobID returns a random 128-bit hex job identifier
+func newJobID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
@@ -0,0 +1,126 @@
+package detector
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// providerKeyEnvVars lists the environment variables checked, in order, for a
+// provider's API keys when the model doesn't name its own variable
+var providerKeyEnvVars = map[ModelProvider][]string{
+	ProviderHuggingFace: {"HUGGINGFACE_API_KEY", "HF_API_KEY", "HF_TOKEN"},
+	ProviderGoogle:      {"GEMINI_API_KEY", "GOOGLE_API_KEY", "GOOGLE_GENERATIVE_AI_KEY"},
+	ProviderOpenAI:      {"OPENAI_API_KEY"},
+	ProviderAnthropic:   {"ANTHROPIC_API_KEY"},
+	ProviderOpenRouter:  {"OPENROUTER_API_KEY"},
+}
+
+// keyPools shares one pool per distinct key list so every endpoint using the
+// same keys sees the same rotation and rejected-key state
+var (
+	keyPools      = make(map[string]*KeyPool)
+	keyPoolsMutex sync.Mutex
+)
+
+// KeyPool rotates requests across a provider's API keys and skips keys the
+// provider rejected with an authentication error
+type KeyPool struct {
+	keys []string
+	next atomic.Uint64
+
+	rejected map[string]bool
+	mutex    sync.RWMutex
+}
+
+// NewKeyPool creates a pool from a comma-separated list of keys, nil if it holds none
+func NewKeyPool(raw string) *KeyPool {
+	var keys []string
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return &KeyPool{
+		keys:     keys,
+		rejected: make(map[string]bool),
+	}
+}
+
+// Size returns the number of keys in the pool
+func (p *KeyPool) Size() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.keys)
+}
+
+// Next returns the next key in round-robin order, skipping rejected keys.
+// Once every key has been rejected they are all tried again, so a key fixed
+// on the provider side is picked up without a restart.
+func (p *KeyPool) Next() string {
+	if p == nil {
+		return ""
+	}
+
+	start := p.next.Add(1) - 1
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for i := 0; i < len(p.keys); i++ {
+		key := p.keys[(start+uint64(i))%uint64(len(p.keys))]
+		if !p.rejected[key] {
+			return key
+		}
+	}
+	return p.keys[start%uint64(len(p.keys))]
+}
+
+// MarkRejected records that the provider refused the key. When that leaves no
+// usable keys the rejections are cleared so the next rotation retries them all.
+func (p *KeyPool) MarkRejected(key string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rejected[key] = true
+	if len(p.rejected) >= len(p.keys) {
+		p.rejected = make(map[string]bool)
+	}
+}
+
+// apiKeyPool returns the shared key pool for a model, reading its own
+// environment variable or falling back to the provider's defaults
+func apiKeyPool(provider ModelProvider, envVar string) *KeyPool {
+	raw := ""
+	if envVar != "" {
+		raw = os.Getenv(envVar)
+	} else {
+		for _, name := range providerKeyEnvVars[provider] {
+			if raw = os.Getenv(name); raw != "" {
+				break
+			}
+		}
+	}
+	if raw == "" {
+		return nil
+	}
+
+	keyPoolsMutex.Lock()
+	defer keyPoolsMutex.Unlock()
+
+	pool, exists := keyPools[raw]
+	if !exists {
+		pool = NewKeyPool(raw)
+		keyPools[raw] = pool
+	}
+	return pool
+}
@@ -0,0 +1,141 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestNewKeyPool(t *testing.T) {
+	tests := map[string]struct {
+		raw      string
+		wantSize int
+	}{
+		"single key":         {"k1", 1},
+		"comma separated":    {"k1,k2,k3", 3},
+		"spaces and empties": {" k1 , ,k2,", 2},
+		"empty":              {"", 0},
+		"only separators":    {" , ,", 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pool := NewKeyPool(tt.raw)
+			if pool.Size() != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", pool.Size(), tt.wantSize)
+			}
+			if tt.wantSize == 0 && (pool != nil || pool.Next() != "") {
+				t.Errorf("NewKeyPool(%q) = %+v, want a nil pool that yields no key", tt.raw, pool)
+			}
+		})
+	}
+}
+
+func TestKeyPoolRoundRobin(t *testing.T) {
+	pool := NewKeyPool("k1,k2,k3")
+
+	var order []string
+	for i := 0; i < 6; i++ {
+		order = append(order, pool.Next())
+	}
+	want := []string{"k1", "k2", "k3", "k1", "k2", "k3"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("rotation = %v, want %v", order, want)
+		}
+	}
+
+	// Concurrent requests still spread evenly across the keys
+	var mutex sync.Mutex
+	counts := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := pool.Next()
+			mutex.Lock()
+			counts[key]++
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, key := range []string{"k1", "k2", "k3"} {
+		if counts[key] != 100 {
+			t.Errorf("counts = %v, want 100 requests per key", counts)
+			break
+		}
+	}
+}
+
+func TestKeyPoolSkipsRejectedKeys(t *testing.T) {
+	pool := NewKeyPool("k1,k2,k3")
+	pool.MarkRejected("k2")
+
+	for i := 0; i < 6; i++ {
+		if key := pool.Next(); key == "k2" {
+			t.Fatalf("Next() returned the rejected key on call %d", i+1)
+		}
+	}
+
+	// Once every key is rejected they are all retried
+	pool.MarkRejected("k1")
+	pool.MarkRejected("k3")
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[pool.Next()] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("keys after all were rejected = %v, want every key tried again", seen)
+	}
+}
+
+func TestLLMDetectorFailsOverPastRejectedKey(t *testing.T) {
+	// A key list no other test uses, so this test gets its own shared pool
+	t.Setenv(testAPIKeyEnv, "revoked-key-580,valid-key-580")
+
+	var mutex sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body.Close()
+		auth := r.Header.Get("Authorization")
+		mutex.Lock()
+		seen = append(seen, auth)
+		mutex.Unlock()
+
+		if auth != "Bearer valid-key-580" {
+			http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode([][]HuggingFaceLabel{{{Label: "SAFE", Score: 0.99}}})
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultLLMDetectorConfig()
+	config.EndpointDelay = 0
+	config.Retry = RetryPolicy{MaxAttempts: 1}
+	model := testModel("rotating", ProviderHuggingFace, server.URL)
+	config.Models = []ModelConfig{model}
+	detector := NewLLMDetectorWithConfig(config)
+
+	for i := 0; i < 3; i++ {
+		if _, err := detector.detectWithSpecificEndpoint(context.Background(), "hello", model, variantScopeOriginal); err != nil {
+			t.Fatalf("detection %d: %v", i+1, err)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	want := []string{"Bearer revoked-key-580", "Bearer valid-key-580", "Bearer valid-key-580", "Bearer valid-key-580"}
+	if len(seen) != len(want) {
+		t.Fatalf("keys sent = %q, want %q", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("keys sent = %q, want the revoked key tried once then skipped", seen)
+			break
+		}
+	}
+}
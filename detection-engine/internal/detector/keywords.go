@@ -0,0 +1,47 @@
+package detector
+
+import "strings"
+
+// DecodeKeywords decides whether text recovered by a decoder reads like an
+// injection attempt rather than an accidental decode of ordinary input
+type DecodeKeywords struct {
+	Languages  map[string][]string // Keywords keyed by language code
+	MinMatches int                 // Distinct keywords from a single language needed to flag the text
+}
+
+// DefaultDecodeKeywords returns the keyword lists used when none are configured,
+// covering the "ignore/previous/instructions/system/reveal" concepts in major languages
+func DefaultDecodeKeywords() DecodeKeywords {
+	return DecodeKeywords{
+		Languages: map[string][]string{
+			"en": {"ignore", "instructions", "prompt", "system", "reveal", "show"},
+			"es": {"ignora", "instrucciones", "anteriores", "sistema", "revela", "muestra"},
+			"fr": {"ignore", "instructions", "précédentes", "système", "révèle", "affiche"},
+			"de": {"ignoriere", "anweisungen", "vorherigen", "system", "verrate", "zeige"},
+			"pt": {"ignore", "instruções", "anteriores", "sistema", "revele", "mostre"},
+			"it": {"ignora", "istruzioni", "precedenti", "sistema", "rivela", "mostra"},
+			"ru": {"игнорируй", "инструкции", "предыдущие", "систем", "раскрой", "покажи"},
+			"zh": {"忽略", "指令", "之前", "系统", "透露", "显示"},
+		},
+		MinMatches: 2,
+	}
+}
+
+// Matches reports whether any language has at least MinMatches of its keywords in the text
+func (k DecodeKeywords) Matches(text string) bool {
+	lower := strings.ToLower(text)
+	minMatches := max(k.MinMatches, 1)
+
+	for _, keywords := range k.Languages {
+		count := 0
+		for _, keyword := range keywords {
+			if keyword != "" && strings.Contains(lower, strings.ToLower(keyword)) {
+				count++
+			}
+		}
+		if count >= minMatches {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"strings"
+	"unicode"
+)
+
+// leetSubstitutions maps common leetspeak characters back to the letters they stand in for
+var leetSubstitutions = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+// normalizeLeetspeak rewrites leetspeak words ("1gn0re pr3v10us") to plain letters.
+// Only words where substitutions sit between letters are rewritten, so numbers
+// ("404") and alphanumeric names with a numeric suffix ("base64") stay intact.
+func normalizeLeetspeak(text string) string {
+	var normalized strings.Builder
+	normalized.Grow(len(text))
+
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !isLeetWordRune(runes[start]) {
+			normalized.WriteRune(runes[start])
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(runes) && isLeetWordRune(runes[end]) {
+			end++
+		}
+
+		word := runes[start:end]
+		if hasEmbeddedSubstitution(word) {
+			for _, r := range word {
+				if letter, ok := leetSubstitutions[r]; ok {
+					r = letter
+				}
+				normalized.WriteRune(r)
+			}
+		} else {
+			normalized.WriteString(string(word))
+		}
+		start = end
+	}
+
+	return normalized.String()
+}
+
+// isLeetWordRune reports whether r can be part of a leetspeak word
+func isLeetWordRune(r rune) bool {
+	if unicode.IsLetter(r) {
+		return true
+	}
+	_, ok := leetSubstitutions[r]
+	return ok
+}
+
+// hasEmbeddedSubstitution reports whether a run of substitution characters in
+// the word has a letter on both sides
+func hasEmbeddedSubstitution(word []rune) bool {
+	seenLetter := false
+	pending := false
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			if pending && seenLetter {
+				return true
+			}
+			seenLetter = true
+			pending = false
+		} else {
+			pending = true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package detector
+
+import "unicode/utf8"
+
+// Match locates a detected threat within the analyzed text.
+// Start and End are Unicode code point offsets, End exclusive.
+type Match struct {
+	ThreatType string `json:"threat_type"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	Snippet    string `json:"snippet"`
+
+	// Set for tool-call requests: the key path of the argument the match is in
+	Argument string `json:"argument,omitempty"`
+}
+
+// newMatch converts byte offsets into text to a code point based match
+func newMatch(text string, threat ThreatType, start, end int) Match {
+	runeStart := utf8.RuneCountInString(text[:start])
+	return Match{
+		ThreatType: string(threat),
+		Start:      runeStart,
+		End:        runeStart + utf8.RuneCountInString(text[start:end]),
+		Snippet:    text[start:end],
+	}
+}
+
+// annotateMatches reports whole-text matches for malicious results that lack precise spans,
+// such as LLM verdicts which classify the input as a whole
+func annotateMatches(response *DetectionResponse, req *DetectionRequest) {
+	if !response.IsMalicious || len(response.Matches) > 0 {
+		return
+	}
+
+	text := req.Text
+	if response.MessageIndex != nil {
+		text = req.Messages[*response.MessageIndex].Content
+	}
+	if text == "" {
+		return
+	}
+
+	for _, threat := range response.ThreatTypes {
+		response.Matches = append(response.Matches, newMatch(text, ThreatType(threat), 0, len(text)))
+	}
+}
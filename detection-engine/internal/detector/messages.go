@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTextAndMessages is returned when a request sets both text and messages
+var ErrTextAndMessages = errors.New("text and messages are mutually exclusive")
+
+// roleWeights scales message scores by how likely the role is to carry attacker-controlled content
+var roleWeights = map[string]float64{
+	"user":      1.0,
+	"tool":      1.0,
+	"system":    0.8,
+	"assistant": 0.6,
+}
+
+// Validate checks that the request uses either text or messages, not both, and that its
+// config is valid, returning a *FieldError naming the offending field
+func (r *DetectionRequest) Validate() error {
+	if r.Text != "" && len(r.Messages) > 0 {
+		return fieldError("messages", ErrTextAndMessages)
+	}
+	return r.Config.Validate()
+}
+
+// roleWeight returns the score weight for a message role; unknown roles are treated as untrusted
+func roleWeight(role string) float64 {
+	if weight, exists := roleWeights[role]; exists {
+		return weight
+	}
+	return 1.0
+}
+
+// analyzeMessages scans each message individually and reports the highest role-weighted result
+func analyzeMessages(ctx context.Context, req *DetectionRequest, config *DetectionConfig, analyze func(context.Context, *DetectionRequest) (*DetectionResponse, error)) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	var best *DetectionResponse
+	bestIndex := -1
+	bestScore := -1.0
+
+	for i, message := range req.Messages {
+		if message.Content == "" {
+			continue
+		}
+
+		response, err := analyze(ctx, &DetectionRequest{Text: message.Content, Config: config})
+		if err != nil {
+			return response, err
+		}
+
+		weighted := response.Confidence * roleWeight(message.Role)
+		if weighted > bestScore {
+			best, bestIndex, bestScore = response, i, weighted
+		}
+	}
+
+	if best == nil {
+		return &DetectionResponse{
+			IsMalicious:      false,
+			Confidence:       0.0,
+			ThreatTypes:      []string{},
+			ProcessingTimeMs: time.Since(startTime).Milliseconds(),
+			Reason:           "Empty input - not malicious",
+			Endpoint:         "none",
+		}, nil
+	}
+
+	best.Confidence = bestScore
+	best.IsMalicious = exceedsThreshold(bestScore, best.ThreatTypes, config, config.ConfidenceThreshold)
+	best.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	if best.IsMalicious {
+		best.MessageIndex = &bestIndex
+		best.MessageRole = req.Messages[bestIndex].Role
+	}
+
+	return best, nil
+}
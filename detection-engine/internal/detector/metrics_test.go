@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyPercentiles(t *testing.T) {
+	metrics := NewMetrics()
+
+	// 1ms to 100ms in random order: the nearest-rank percentiles are exact
+	for _, i := range rand.Perm(100) {
+		metrics.RecordSuccess(time.Duration(i+1)*time.Millisecond, &DetectionResponse{})
+	}
+
+	got := metrics.GetLatencyPercentiles()
+	want := LatencyPercentiles{P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond}
+	if got != want {
+		t.Errorf("GetLatencyPercentiles() = %+v, want %+v", got, want)
+	}
+	if avg := metrics.GetAverageLatency(); avg != 50500*time.Microsecond {
+		t.Errorf("GetAverageLatency() = %v, want 50.5ms alongside the percentiles", avg)
+	}
+}
+
+func TestLatencyPercentilesSkewedTail(t *testing.T) {
+	metrics := NewMetrics()
+	for i := 0; i < 98; i++ {
+		metrics.RecordSuccess(10*time.Millisecond, &DetectionResponse{})
+	}
+	metrics.RecordFailure(2 * time.Second)
+	metrics.RecordFailure(3 * time.Second)
+
+	got := metrics.GetLatencyPercentiles()
+	if got.P50 != 10*time.Millisecond || got.P95 != 10*time.Millisecond || got.P99 != 2*time.Second {
+		t.Errorf("GetLatencyPercentiles() = %+v, want p50/p95 of 10ms and p99 of 2s", got)
+	}
+}
+
+func TestLatencyPercentilesEmpty(t *testing.T) {
+	if got := NewMetrics().GetLatencyPercentiles(); got != (LatencyPercentiles{}) {
+		t.Errorf("GetLatencyPercentiles() with no requests = %+v, want zeros", got)
+	}
+}
+
+func TestLatencyReservoirKeepsRecentSamples(t *testing.T) {
+	metrics := NewMetrics()
+	for i := 0; i < latencyReservoirSize; i++ {
+		metrics.RecordSuccess(time.Second, &DetectionResponse{})
+	}
+	for i := 0; i < latencyReservoirSize; i++ {
+		metrics.RecordSuccess(time.Millisecond, &DetectionResponse{})
+	}
+
+	if got := metrics.GetLatencyPercentiles(); got.P99 != time.Millisecond {
+		t.Errorf("p99 = %v, want 1ms once the old samples were overwritten", got.P99)
+	}
+	if len(metrics.latencies) != latencyReservoirSize {
+		t.Errorf("reservoir holds %d samples, want at most %d", len(metrics.latencies), latencyReservoirSize)
+	}
+}
+
+func TestLatencyPercentilesConcurrent(t *testing.T) {
+	metrics := NewMetrics()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				metrics.RecordSuccess(time.Millisecond, &DetectionResponse{})
+				metrics.GetLatencyPercentiles()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := metrics.GetLatencyPercentiles(); got.P50 != time.Millisecond {
+		t.Errorf("p50 = %v, want 1ms", got.P50)
+	}
+}
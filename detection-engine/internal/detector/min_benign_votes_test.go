@@ -0,0 +1,148 @@
+package detector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMinBenignVotes(t *testing.T) {
+	outage := &ProviderError{Category: ErrorCategoryServer, Message: "upstream failure"}
+	malicious := []ThreatType{ThreatTypeJailbreak}
+
+	tests := map[string]struct {
+		models           []fakeModel
+		minBenignVotes   int
+		wantMalicious    bool
+		wantFailedClosed bool
+	}{
+		"enough benign votes": {
+			models:         []fakeModel{{name: "a", score: 0.1}, {name: "b", score: 0.2}, {name: "c", score: 0.05}},
+			minBenignVotes: 2,
+		},
+		"exactly enough benign votes": {
+			models:         []fakeModel{{name: "a", score: 0.1}, {name: "b", score: 0.2}, {name: "c", err: outage}},
+			minBenignVotes: 2,
+		},
+		"not enough votes during an outage": {
+			models:           []fakeModel{{name: "a", score: 0.1}, {name: "b", err: outage}, {name: "c", err: outage}},
+			minBenignVotes:   2,
+			wantMalicious:    true,
+			wantFailedClosed: true,
+		},
+		"every model down": {
+			models:           []fakeModel{{name: "a", err: outage}, {name: "b", err: outage}},
+			minBenignVotes:   1,
+			wantMalicious:    true,
+			wantFailedClosed: true,
+		},
+		"benign majority short of the requirement": {
+			models:           []fakeModel{{name: "a", score: 0.1}, {name: "b", score: 0.2}, {name: "c", score: 0.9, threats: malicious}},
+			minBenignVotes:   3,
+			wantMalicious:    true,
+			wantFailedClosed: true,
+		},
+		"all malicious": {
+			models:         []fakeModel{{name: "a", score: 0.9, threats: malicious}, {name: "b", score: 0.95, threats: malicious}},
+			minBenignVotes: 2,
+			wantMalicious:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pipeline := newFakeProviderPipeline(t, tt.models...)
+
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+				Text:   "what is the capital of France",
+				Config: &DetectionConfig{MinBenignVotes: tt.minBenignVotes},
+			})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if response.IsMalicious != tt.wantMalicious || response.FailedClosed != tt.wantFailedClosed {
+				t.Errorf("malicious %v, failed closed %v, want %v and %v (%s)", response.IsMalicious, response.FailedClosed, tt.wantMalicious, tt.wantFailedClosed, response.Reason)
+			}
+			if tt.wantFailedClosed && response.Confidence != 1.0 {
+				t.Errorf("Confidence = %v, want 1.0 for a fail-closed block", response.Confidence)
+			}
+		})
+	}
+}
+
+func TestMinBenignVotesPipelineDefault(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "a", score: 0.1},
+		fakeModel{name: "b", err: &ProviderError{Category: ErrorCategoryServer, Message: "upstream failure"}},
+	)
+	pipeline.SetMinBenignVotes(2)
+
+	// The requirement switches a fast, first-strategy default to a full consensus vote
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{Mode: ModeFast, DetailedResponse: true},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.Strategy != StrategyConsensus || len(response.ModelResults) != 2 {
+		t.Errorf("strategy %q with %d model results, want consensus over both models", response.Strategy, len(response.ModelResults))
+	}
+	if !response.IsMalicious || !response.FailedClosed {
+		t.Errorf("response = %+v, want a fail-closed block with one benign vote of two required", response)
+	}
+
+	// A request's own requirement takes precedence over the pipeline's
+	response, err = pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{MinBenignVotes: 1},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.IsMalicious {
+		t.Errorf("response = %+v, want benign with the request's single required vote", response)
+	}
+}
+
+func TestMinBenignVotesCountsTieBreaker(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "a", score: 0.9, threats: []ThreatType{ThreatTypeJailbreak}},
+		fakeModel{name: "b", score: 0.1},
+		fakeModel{name: "judge", score: 0.05},
+	)
+	pipeline.SetTieBreaker("judge")
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{MinBenignVotes: 2},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.IsMalicious || response.TieBreaker != "judge" {
+		t.Errorf("response = %+v, want the benign tie-breaker to supply the second vote", response)
+	}
+}
+
+func TestValidateMinBenignVotes(t *testing.T) {
+	tests := map[string]struct {
+		config  DetectionConfig
+		wantErr bool
+	}{
+		"unset":            {DetectionConfig{}, false},
+		"consensus":        {DetectionConfig{MinBenignVotes: 2, Strategy: StrategyConsensus}, false},
+		"implied strategy": {DetectionConfig{MinBenignVotes: 2}, false},
+		"thorough":         {DetectionConfig{MinBenignVotes: 2, Mode: ModeThorough}, false},
+		"negative":         {DetectionConfig{MinBenignVotes: -1}, true},
+		"first strategy":   {DetectionConfig{MinBenignVotes: 2, Strategy: StrategyFirst}, true},
+		"race strategy":    {DetectionConfig{MinBenignVotes: 2, Strategy: StrategyRace}, true},
+		"fast mode":        {DetectionConfig{MinBenignVotes: 2, Mode: ModeFast}, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := validateMinBenignVotes(&tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("validateMinBenignVotes = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+)
+
+// DetectionMode is a preset trading latency for coverage
+type DetectionMode string
+
+const (
+	ModeFast     DetectionMode = "fast"     // Local heuristics, then one classifier on the original text
+	ModeBalanced DetectionMode = "balanced" // Priority fallback over the original text and its decodings (default)
+	ModeThorough DetectionMode = "thorough" // Consensus across all healthy models, every decoded variant analyzed
+)
+
+// variantScope controls which decoded variants of the input are sent to a model
+type variantScope int
+
+const (
+	variantScopeDefault  variantScope = iota // Original plus decodings; max aggregation stops at a confident hit
+	variantScopeOriginal                     // Original text only
+	variantScopeAll                          // Original plus decodings, every variant analyzed
+)
+
+// variantScopeKey carries the request's variant scope to provider detectors
+type variantScopeKey struct{}
+
+// validateMode checks that a mode is supported and compatible with the requested strategy
+func validateMode(mode DetectionMode, strategy AggregationStrategy) error {
+	switch mode {
+	case "", ModeBalanced, ModeThorough:
+		return nil
+	case ModeFast:
+		if strategy != "" && strategy != StrategyFirst {
+			return fmt.Errorf("invalid strategy %q for mode %q: fast mode calls a single model", strategy, mode)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid mode %q: must be %q, %q or %q", mode, ModeFast, ModeBalanced, ModeThorough)
+}
+
+// resolveMode fills in the settings implied by the config's mode; settings given
+// explicitly on the request take precedence over the preset
+func resolveMode(config *DetectionConfig) {
+	if config.Mode == ModeThorough && config.Strategy == "" {
+		config.Strategy = StrategyConsensus
+	}
+}
+
+// modeVariantScope returns the decoded variant coverage of a mode
+func modeVariantScope(mode DetectionMode) variantScope {
+	switch mode {
+	case ModeFast:
+		return variantScopeOriginal
+	case ModeThorough:
+		return variantScopeAll
+	}
+	return variantScopeDefault
+}
+
+// withVariantScope attaches a variant scope to ctx
+func withVariantScope(ctx context.Context, scope variantScope) context.Context {
+	return context.WithValue(ctx, variantScopeKey{}, scope)
+}
+
+// variantScopeFrom returns the variant scope attached to ctx, the default scope when none is
+func variantScopeFrom(ctx context.Context) variantScope {
+	if scope, ok := ctx.Value(variantScopeKey{}).(variantScope); ok {
+		return scope
+	}
+	return variantScopeDefault
+}
+
+// fastModeModel picks the model fast mode calls: the highest-priority classifier,
+// or the highest-priority model when no classifier is enabled
+func fastModeModel(enabledModels []ModelConfig) (ModelConfig, bool) {
+	for _, model := range enabledModels {
+		if model.Type == ModelTypeClassification {
+			return model, true
+		}
+	}
+	if len(enabledModels) > 0 {
+		return enabledModels[0], true
+	}
+	return ModelConfig{}, false
+}
+
+// mergeFastModeResult folds the heuristic result into the classifier's so fast mode
+// keeps whichever signal scored higher and the threats either one found
+func mergeFastModeResult(classifier, heuristic *DetectionResult) *DetectionResult {
+	merged := *classifier
+	merged.ThreatTypes = append([]ThreatType(nil), classifier.ThreatTypes...)
+	for _, threat := range heuristic.ThreatTypes {
+		merged.ThreatTypes = appendThreat(merged.ThreatTypes, threat)
+	}
+	if heuristic.Score > classifier.Score {
+		merged.Score = heuristic.Score
+		merged.Reason = fmt.Sprintf("local heuristics scored %.2f over %s's %.2f: %s", heuristic.Score, classifier.Endpoint, classifier.Score, heuristic.Reason)
+		merged.Matches = heuristic.Matches
+	}
+	return &merged
+}
@@ -1,8 +1,13 @@
 package detector
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
 // ModelType represents different types of AI models
@@ -23,39 +28,83 @@ const (
 	ProviderAnthropic   ModelProvider = "anthropic"
 	ProviderGrok        ModelProvider = "grok"
 	ProviderOpenRouter  ModelProvider = "openrouter"
+
+	// Any API implementing the OpenAI chat-completions schema (LM Studio, vLLM, Together, Groq, ...)
+	ProviderOpenAICompatible ModelProvider = "openai-compatible"
 )
 
 // ModelConfig defines configuration for any AI model
 type ModelConfig struct {
-	Name            string        `json:"name"`             // Human-readable name
-	Provider        ModelProvider `json:"provider"`         // Service provider
-	Type            ModelType     `json:"type"`             // Model type
-	Model           string        `json:"model"`            // Model identifier
-	URL             string        `json:"url,omitempty"`    // API endpoint
-	APIKeyEnvVar    string        `json:"api_key_env"`      // Environment variable for API key
-	Timeout         time.Duration `json:"timeout"`          // Request timeout
-	Priority        int           `json:"priority"`         // Fallback priority (1=highest)
-	CostPerRequest  float64       `json:"cost_per_request"` // Cost in USD per request
-	ExpectedLatency time.Duration `json:"expected_latency"` // Expected response time
-	AccuracyScore   float64       `json:"accuracy_score"`   // Model accuracy (0-1)
-	Enabled         bool          `json:"enabled"`          // Whether model is active
-	CircuitBreaker  CBConfig      `json:"circuit_breaker"`  // Circuit breaker config
+	Name            string        `json:"name" mapstructure:"name"`                         // Human-readable name
+	Provider        ModelProvider `json:"provider" mapstructure:"provider"`                 // Service provider
+	Type            ModelType     `json:"type" mapstructure:"type"`                         // Model type
+	Model           string        `json:"model" mapstructure:"model"`                       // Model identifier
+	URL             string        `json:"url,omitempty" mapstructure:"url"`                 // API endpoint
+	APIKeyEnvVar    string        `json:"api_key_env" mapstructure:"api_key_env"`           // Environment variable for API key
+	Timeout         time.Duration `json:"timeout" mapstructure:"timeout"`                   // Request timeout
+	Priority        int           `json:"priority" mapstructure:"priority"`                 // Fallback priority (1=highest)
+	CostPerRequest  float64       `json:"cost_per_request" mapstructure:"cost_per_request"` // Cost in USD per request
+	ExpectedLatency time.Duration `json:"expected_latency" mapstructure:"expected_latency"` // Expected response time
+	AccuracyScore   float64       `json:"accuracy_score" mapstructure:"accuracy_score"`     // Model accuracy (0-1)
+	Enabled         bool          `json:"enabled" mapstructure:"enabled"`                   // Whether model is active
+	CircuitBreaker  CBConfig      `json:"circuit_breaker" mapstructure:"circuit_breaker"`   // Circuit breaker config
+
+	// How long to skip the model after its quota is exhausted; zero waits until the next UTC day
+	QuotaCooldown time.Duration `json:"quota_cooldown,omitempty" mapstructure:"quota_cooldown"`
+
+	// API key header for openai-compatible providers: AuthHeader defaults to "Authorization"
+	// with AuthScheme "Bearer"; an empty scheme with a custom header sends the bare key
+	AuthHeader string `json:"auth_header,omitempty" mapstructure:"auth_header"`
+	AuthScheme string `json:"auth_scheme,omitempty" mapstructure:"auth_scheme"`
+
+	// Sampling, safety and output format settings for providers that accept them
+	Generation GenerationSettings `json:"generation,omitempty" mapstructure:"generation"`
+
+	// Instructions for generative models, replacing the detection-wide system prompt
+	SystemPrompt string `json:"system_prompt,omitempty" mapstructure:"system_prompt"`
+}
+
+// GenerationSettings tunes how a generative model produces its analysis
+type GenerationSettings struct {
+	Temperature     float64         `json:"temperature" mapstructure:"temperature"`                       // 0 keeps verdicts deterministic
+	MaxOutputTokens int             `json:"max_output_tokens,omitempty" mapstructure:"max_output_tokens"` // 0 uses defaultMaxOutputTokens
+	SafetySettings  []SafetySetting `json:"safety_settings,omitempty" mapstructure:"safety_settings"`     // Empty disables blocking so attacks can be analyzed
+
+	// Request a schema-constrained JSON verdict instead of the SCORE/THREATS/REASON line (Gemini, OpenRouter)
+	JSONMode bool `json:"json_mode,omitempty" mapstructure:"json_mode"`
+}
+
+// SafetySetting sets the blocking threshold for one provider harm category
+type SafetySetting struct {
+	Category  string `json:"category" mapstructure:"category"`   // e.g. HARM_CATEGORY_DANGEROUS_CONTENT
+	Threshold string `json:"threshold" mapstructure:"threshold"` // e.g. BLOCK_NONE, BLOCK_ONLY_HIGH
 }
 
 // CBConfig holds circuit breaker configuration for a model
 type CBConfig struct {
-	FailureThreshold int           `json:"failure_threshold"`
-	SuccessThreshold int           `json:"success_threshold"`
-	Timeout          time.Duration `json:"timeout"`
-	MaxTimeout       time.Duration `json:"max_timeout"`
+	FailureThreshold int           `json:"failure_threshold" mapstructure:"failure_threshold"`
+	SuccessThreshold int           `json:"success_threshold" mapstructure:"success_threshold"`
+	Timeout          time.Duration `json:"timeout" mapstructure:"timeout"`
+	MaxTimeout       time.Duration `json:"max_timeout" mapstructure:"max_timeout"`
+
+	// Concurrent probe requests allowed while half-open (default 1)
+	HalfOpenMaxRequests int `json:"half_open_max_requests,omitempty" mapstructure:"half_open_max_requests"`
+
+	// Optional rolling-window rule: open when the failure ratio over the last WindowSize requests reaches WindowFailureRatio
+	WindowSize         int     `json:"window_size,omitempty" mapstructure:"window_size"`
+	WindowFailureRatio float64 `json:"window_failure_ratio,omitempty" mapstructure:"window_failure_ratio"`
 }
 
 // ModelRegistry manages available AI models and their configurations
 type ModelRegistry struct {
 	models        []ModelConfig
 	enabledModels []ModelConfig
+	mutex         sync.RWMutex
 }
 
+// ErrModelNotFound is returned when a model name is not in the registry
+var ErrModelNotFound = errors.New("model not found")
+
 // NewModelRegistry creates a new model registry with startup-friendly configurations
 func NewModelRegistry() *ModelRegistry {
 	registry := &ModelRegistry{
@@ -67,32 +116,154 @@ func NewModelRegistry() *ModelRegistry {
 
 // LoadFromConfig loads model configurations from external source
 func (r *ModelRegistry) LoadFromConfig(configs []ModelConfig) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	r.models = configs
 	r.refreshEnabledModels()
 }
 
+// LoadModelConfigsFromFile reads model configurations from a YAML or JSON file.
+// The file must contain a top-level "models" list. A missing file returns an
+// error wrapping os.ErrNotExist so callers can fall back to built-in defaults.
+func LoadModelConfigsFromFile(path string) ([]ModelConfig, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("model config file %s: %w", path, err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read model config file %s: %v", path, err)
+	}
+
+	var configs []ModelConfig
+	if err := v.UnmarshalKey("models", &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse model config file %s: %v", path, err)
+	}
+
+	if err := ValidateModelConfigs(configs); err != nil {
+		return nil, fmt.Errorf("invalid model config file %s: %v", path, err)
+	}
+
+	return configs, nil
+}
+
+// Defaults for limits a model config file leaves unset, matching the built-in GenAI models
+const (
+	defaultModelTimeout      = 15 * time.Second
+	defaultFailureThreshold  = 3
+	defaultSuccessThreshold  = 2
+	defaultBreakerTimeout    = 60 * time.Second
+	defaultBreakerMaxTimeout = 10 * time.Minute
+)
+
+// ValidateModelConfigs checks that every model has the required fields and a unique name,
+// filling unset timeouts and circuit breaker limits with the built-in defaults
+func ValidateModelConfigs(configs []ModelConfig) error {
+	if len(configs) == 0 {
+		return fmt.Errorf("no models defined")
+	}
+
+	seen := make(map[string]bool, len(configs))
+	for i := range configs {
+		model := &configs[i]
+		if model.Name == "" {
+			return fmt.Errorf("model %d: name is required", i)
+		}
+		if model.Provider == "" {
+			return fmt.Errorf("model %s: provider is required", model.Name)
+		}
+		if model.Priority <= 0 {
+			return fmt.Errorf("model %s: priority must be a positive integer", model.Name)
+		}
+		if seen[model.Name] {
+			return fmt.Errorf("model %s: duplicate name", model.Name)
+		}
+		seen[model.Name] = true
+
+		if err := applyModelDefaults(model); err != nil {
+			return fmt.Errorf("model %s: %v", model.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyModelDefaults fills zero timeouts and breaker limits, rejecting negative ones:
+// a zero timeout would leave no attempt budget and a zero threshold would open the
+// breaker on the first failure
+func applyModelDefaults(model *ModelConfig) error {
+	breaker := &model.CircuitBreaker
+	switch {
+	case model.Timeout < 0:
+		return fmt.Errorf("timeout must not be negative")
+	case breaker.FailureThreshold < 0:
+		return fmt.Errorf("circuit_breaker.failure_threshold must not be negative")
+	case breaker.SuccessThreshold < 0:
+		return fmt.Errorf("circuit_breaker.success_threshold must not be negative")
+	case breaker.Timeout < 0:
+		return fmt.Errorf("circuit_breaker.timeout must not be negative")
+	case breaker.MaxTimeout < 0:
+		return fmt.Errorf("circuit_breaker.max_timeout must not be negative")
+	}
+
+	if model.Timeout == 0 {
+		model.Timeout = defaultModelTimeout
+	}
+	if breaker.FailureThreshold == 0 {
+		breaker.FailureThreshold = defaultFailureThreshold
+	}
+	if breaker.SuccessThreshold == 0 {
+		breaker.SuccessThreshold = defaultSuccessThreshold
+	}
+	if breaker.Timeout == 0 {
+		breaker.Timeout = defaultBreakerTimeout
+	}
+	if breaker.MaxTimeout == 0 {
+		breaker.MaxTimeout = max(defaultBreakerMaxTimeout, breaker.Timeout)
+	}
+	if breaker.MaxTimeout < breaker.Timeout {
+		return fmt.Errorf("circuit_breaker.max_timeout %v is shorter than circuit_breaker.timeout %v", breaker.MaxTimeout, breaker.Timeout)
+	}
+	return nil
+}
+
 // GetEnabledModels returns models sorted by priority (1=highest priority)
 func (r *ModelRegistry) GetEnabledModels() []ModelConfig {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	return r.enabledModels
 }
 
 // GetModelByName returns model configuration by name
 func (r *ModelRegistry) GetModelByName(name string) (ModelConfig, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	for _, model := range r.models {
 		if model.Name == name {
 			return model, nil
 		}
 	}
-	return ModelConfig{}, fmt.Errorf("model %s not found", name)
+	return ModelConfig{}, fmt.Errorf("%w: %s", ErrModelNotFound, name)
 }
 
 // GetAllModels returns all model configurations (enabled and disabled)
 func (r *ModelRegistry) GetAllModels() []ModelConfig {
-	return r.models
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	models := make([]ModelConfig, len(r.models))
+	copy(models, r.models)
+	return models
 }
 
 // EnableModel enables a model by name
 func (r *ModelRegistry) EnableModel(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	for i := range r.models {
 		if r.models[i].Name == name {
 			r.models[i].Enabled = true
@@ -100,11 +271,14 @@ func (r *ModelRegistry) EnableModel(name string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("model %s not found", name)
+	return fmt.Errorf("%w: %s", ErrModelNotFound, name)
 }
 
 // DisableModel disables a model by name
 func (r *ModelRegistry) DisableModel(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	for i := range r.models {
 		if r.models[i].Name == name {
 			r.models[i].Enabled = false
@@ -112,11 +286,14 @@ func (r *ModelRegistry) DisableModel(name string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("model %s not found", name)
+	return fmt.Errorf("%w: %s", ErrModelNotFound, name)
 }
 
 // UpdateModelPriority changes the priority of a model
 func (r *ModelRegistry) UpdateModelPriority(name string, newPriority int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	for i := range r.models {
 		if r.models[i].Name == name {
 			r.models[i].Priority = newPriority
@@ -124,10 +301,11 @@ func (r *ModelRegistry) UpdateModelPriority(name string, newPriority int) error
 			return nil
 		}
 	}
-	return fmt.Errorf("model %s not found", name)
+	return fmt.Errorf("%w: %s", ErrModelNotFound, name)
 }
 
-// refreshEnabledModels updates the enabled models list and sorts by priority
+// refreshEnabledModels updates the enabled models list and sorts by priority.
+// Callers must hold the write lock.
 func (r *ModelRegistry) refreshEnabledModels() {
 	r.enabledModels = make([]ModelConfig, 0)
 
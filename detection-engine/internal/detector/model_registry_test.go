@@ -0,0 +1,183 @@
+package detector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeModelsFile writes content to a models file named name in a temporary directory
+func writeModelsFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestLoadModelConfigsFromFile(t *testing.T) {
+	path := writeModelsFile(t, "models.yaml", `
+models:
+  - name: primary
+    provider: openrouter
+    type: genai
+    model: vendor/model
+    url: https://example.com/v1/chat/completions
+    api_key_env: PRIMARY_KEY
+    timeout: 5s
+    priority: 1
+    enabled: true
+    circuit_breaker:
+      failure_threshold: 4
+      success_threshold: 1
+      timeout: 30s
+      max_timeout: 5m
+  - name: secondary
+    provider: huggingface
+    priority: 2
+    enabled: true
+`)
+
+	configs, err := LoadModelConfigsFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadModelConfigsFromFile: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("got %d models, want 2", len(configs))
+	}
+
+	primary := configs[0]
+	if primary.Name != "primary" || primary.Provider != ProviderOpenRouter || primary.Timeout != 5*time.Second {
+		t.Errorf("primary = %+v, want the file's values", primary)
+	}
+	if primary.CircuitBreaker.FailureThreshold != 4 || primary.CircuitBreaker.MaxTimeout != 5*time.Minute {
+		t.Errorf("primary breaker = %+v, want the file's values", primary.CircuitBreaker)
+	}
+
+	// Limits the file leaves out get the built-in defaults
+	secondary := configs[1]
+	if secondary.Timeout != defaultModelTimeout {
+		t.Errorf("secondary timeout = %v, want the default %v", secondary.Timeout, defaultModelTimeout)
+	}
+	want := CBConfig{
+		FailureThreshold: defaultFailureThreshold,
+		SuccessThreshold: defaultSuccessThreshold,
+		Timeout:          defaultBreakerTimeout,
+		MaxTimeout:       defaultBreakerMaxTimeout,
+	}
+	if secondary.CircuitBreaker != want {
+		t.Errorf("secondary breaker = %+v, want the defaults %+v", secondary.CircuitBreaker, want)
+	}
+}
+
+func TestLoadModelConfigsFromJSONFile(t *testing.T) {
+	path := writeModelsFile(t, "models.json", `{"models": [{"name": "only", "provider": "google", "priority": 1, "enabled": true}]}`)
+
+	configs, err := LoadModelConfigsFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadModelConfigsFromFile: %v", err)
+	}
+	if len(configs) != 1 || configs[0].Name != "only" || configs[0].Provider != ProviderGoogle {
+		t.Errorf("configs = %+v, want the single google model", configs)
+	}
+}
+
+func TestLoadModelConfigsFromExampleFile(t *testing.T) {
+	configs, err := LoadModelConfigsFromFile("../../configs/models.example.yaml")
+	if err != nil {
+		t.Fatalf("the shipped example does not load: %v", err)
+	}
+	if len(configs) == 0 {
+		t.Fatal("the shipped example defines no models")
+	}
+}
+
+func TestLoadModelConfigsFromMissingFile(t *testing.T) {
+	_, err := LoadModelConfigsFromFile(filepath.Join(t.TempDir(), "models.yaml"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error = %v, want one wrapping os.ErrNotExist so the built-in models are used", err)
+	}
+
+	registry := NewModelRegistry()
+	if len(registry.GetAllModels()) == 0 {
+		t.Error("the built-in registry has no models to fall back to")
+	}
+}
+
+func TestLoadModelConfigsFromInvalidFile(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		want    string // Fragment the error must contain
+	}{
+		"malformed": {
+			content: "models: [name: broken",
+			want:    "failed to read",
+		},
+		"no models": {
+			content: "other: true\n",
+			want:    "no models defined",
+		},
+		"missing name": {
+			content: "models:\n  - provider: google\n    priority: 1\n",
+			want:    "name is required",
+		},
+		"missing provider": {
+			content: "models:\n  - name: a\n    priority: 1\n",
+			want:    "model a: provider is required",
+		},
+		"missing priority": {
+			content: "models:\n  - name: a\n    provider: google\n",
+			want:    "model a: priority must be a positive integer",
+		},
+		"duplicate name": {
+			content: "models:\n  - name: a\n    provider: google\n    priority: 1\n  - name: a\n    provider: google\n    priority: 2\n",
+			want:    "model a: duplicate name",
+		},
+		"negative timeout": {
+			content: "models:\n  - name: a\n    provider: google\n    priority: 1\n    timeout: -1s\n",
+			want:    "timeout must not be negative",
+		},
+		"max timeout below timeout": {
+			content: "models:\n  - name: a\n    provider: google\n    priority: 1\n    circuit_breaker:\n      timeout: 5m\n      max_timeout: 1m\n",
+			want:    "max_timeout 1m0s is shorter than circuit_breaker.timeout 5m0s",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeModelsFile(t, "models.yaml", tt.content)
+			_, err := LoadModelConfigsFromFile(path)
+			if err == nil {
+				t.Fatal("LoadModelConfigsFromFile returned no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), path) {
+				t.Errorf("error = %q, want it to name %s and contain %q", err, path, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadModelConfigsWindowBreaker(t *testing.T) {
+	path := writeModelsFile(t, "models.yaml", `
+models:
+  - name: flaky
+    provider: openrouter
+    priority: 1
+    enabled: true
+    circuit_breaker:
+      failure_threshold: 5
+      window_size: 20
+      window_failure_ratio: 0.5
+`)
+
+	configs, err := LoadModelConfigsFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadModelConfigsFromFile: %v", err)
+	}
+	if breaker := configs[0].CircuitBreaker; breaker.WindowSize != 20 || breaker.WindowFailureRatio != 0.5 {
+		t.Errorf("breaker = %+v, want the model's own rolling window", breaker)
+	}
+}
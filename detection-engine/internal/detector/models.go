@@ -4,14 +4,65 @@ import "time"
 
 // DetectionRequest represents an incoming prompt analysis request
 type DetectionRequest struct {
-	Text   string           `json:"text"`
-	Config *DetectionConfig `json:"config,omitempty"`
+	Text     string           `json:"text"`
+	Messages []Message        `json:"messages,omitempty"` // Role-tagged alternative to Text
+	Config   *DetectionConfig `json:"config,omitempty"`
+}
+
+// Message is a single role-tagged chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SessionDetectionRequest represents a multi-turn conversation analysis request
+type SessionDetectionRequest struct {
+	SessionID string           `json:"session_id" binding:"required"`
+	Messages  []Message        `json:"messages" binding:"required,min=1"`
+	Config    *DetectionConfig `json:"config,omitempty"`
+}
+
+// SessionDetectionResponse combines the latest turn result with the session's cumulative risk
+type SessionDetectionResponse struct {
+	SessionID    string             `json:"session_id"`
+	IsMalicious  bool               `json:"is_malicious"` // Turn is malicious or cumulative risk crossed the threshold
+	TurnResult   *DetectionResponse `json:"turn_result"`
+	SessionRisk  SessionRisk        `json:"session_risk"`
+	TurnsInScope int                `json:"turns_in_scope"` // Recent messages analyzed together
+}
+
+// OutputDetectionRequest represents a request to scan model-generated output for leakage
+type OutputDetectionRequest struct {
+	Output       string           `json:"output" binding:"required"`
+	SystemPrompt string           `json:"system_prompt,omitempty"` // Known system prompt for exact-match leak detection
+	Canaries     []string         `json:"canaries,omitempty"`      // Canary tokens embedded in the system prompt
+	Config       *DetectionConfig `json:"config,omitempty"`
 }
 
 // DetectionConfig allows per-request configuration (simplified for LLM-only)
 type DetectionConfig struct {
-	ConfidenceThreshold float64 `json:"confidence_threshold,omitempty"`
-	DetailedResponse    bool    `json:"detailed_response,omitempty"`
+	ConfidenceThreshold float64             `json:"confidence_threshold,omitempty"`
+	DetailedResponse    bool                `json:"detailed_response,omitempty"`
+	Strategy            AggregationStrategy `json:"strategy,omitempty"`   // "first" (default), "race" or "consensus"
+	Quorum              int                 `json:"quorum,omitempty"`     // Malicious votes required in consensus mode (default: majority)
+	LocalOnly           *bool               `json:"local_only,omitempty"` // Override server local-only mode for this request
+	Action              Action              `json:"action,omitempty"`     // "flag" (default), "block" or "sanitize"
+
+	// Per-threat-type overrides of ConfidenceThreshold, keyed by threat type
+	ThreatThresholds map[string]float64 `json:"threat_thresholds,omitempty"`
+
+	// Overall latency budget for this request; caps each model call and is split across fallback attempts
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// Attach an explanation of the score with a threshold sweep; implies DetailedResponse
+	Explain bool `json:"explain,omitempty"`
+
+	// Latency/coverage preset: "fast", "balanced" (default) or "thorough"
+	Mode DetectionMode `json:"mode,omitempty"`
+
+	// Benign votes from models that answered required before the input is reported
+	// safe; fewer (including outages) fail closed. Implies the consensus strategy.
+	MinBenignVotes int `json:"min_benign_votes,omitempty"`
 }
 
 // DetectionResponse represents the analysis result (simplified for LLM-only)
@@ -22,6 +73,49 @@ type DetectionResponse struct {
 	ProcessingTimeMs int64    `json:"processing_time_ms"`
 	Reason           string   `json:"reason,omitempty"`
 	Endpoint         string   `json:"endpoint,omitempty"`
+	Disagreement     bool     `json:"disagreement,omitempty"` // Consensus votes were split
+	Matches          []Match  `json:"matches,omitempty"`      // Offsets of detected threats in the input
+
+	// Graded from the score and threat types: none, low, medium, high or critical
+	Severity Severity `json:"severity,omitempty"`
+
+	// Set when the request specifies an action
+	Action            Action    `json:"action,omitempty"`
+	Blocked           bool      `json:"blocked,omitempty"`
+	SanitizedText     string    `json:"sanitized_text,omitempty"`
+	SanitizedMessages []Message `json:"sanitized_messages,omitempty"`
+
+	// Set when no model answered and on_failure=closed forced a malicious verdict
+	FailedClosed bool `json:"failed_closed,omitempty"`
+
+	// Set for message-based requests when a message triggered detection
+	MessageIndex *int   `json:"message_index,omitempty"`
+	MessageRole  string `json:"message_role,omitempty"`
+
+	// Populated only when DetailedResponse is requested
+	ModelResults []ModelResult       `json:"model_results,omitempty"`
+	Strategy     AggregationStrategy `json:"strategy,omitempty"`
+
+	// Standard deviation of consensus model scores, populated only when DetailedResponse is requested
+	ModelDisagreement float64 `json:"model_disagreement,omitempty"`
+	HighDisagreement  bool    `json:"high_disagreement,omitempty"` // Spread exceeds the disagreement threshold
+
+	// Model that decided a split consensus vote
+	TieBreaker string `json:"tie_breaker,omitempty"`
+
+	// Populated only when Explain is requested
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// ModelResult captures a single model's vote for detailed responses
+type ModelResult struct {
+	Model         string   `json:"model"`
+	Score         float64  `json:"score"`
+	ThreatTypes   []string `json:"threat_types"`
+	Reason        string   `json:"reason,omitempty"`
+	LatencyMs     int64    `json:"latency_ms"`
+	Error         string   `json:"error,omitempty"`
+	ErrorCategory string   `json:"error_category,omitempty"`
 }
 
 // ThreatType represents different types of prompt injection threats
@@ -34,13 +128,30 @@ const (
 	ThreatTypeDataExtraction   ThreatType = "data_extraction"
 	ThreatTypeEncodingAttack   ThreatType = "encoding_attack"
 	ThreatTypeDelimiterAttack  ThreatType = "delimiter_attack"
+	ThreatTypePayloadSplitting ThreatType = "payload_splitting" // Instructions split across enumerated fragments
+
+	// Instructions forbidding the model to refuse, apologize or add disclaimers
+	ThreatTypeRefusalSuppression ThreatType = "refusal_suppression"
 )
 
 // DetectionMethod represents different detection approaches (LLM-only)
 type DetectionMethod string
 
 const (
-	MethodLLM DetectionMethod = "llm"
+	MethodLLM       DetectionMethod = "llm"
+	MethodHeuristic DetectionMethod = "heuristic" // Local regex rules, no network calls
+)
+
+// localEndpointName is reported as the endpoint for results produced by local detection
+const localEndpointName = "local"
+
+// AggregationStrategy represents how results from multiple models are combined
+type AggregationStrategy string
+
+const (
+	StrategyFirst     AggregationStrategy = "first"     // First model to answer wins (priority fallback)
+	StrategyRace      AggregationStrategy = "race"      // All models queried concurrently, fastest answer wins
+	StrategyConsensus AggregationStrategy = "consensus" // All models queried, majority vote decides
 )
 
 // DetectionResult represents the result from LLM detection
@@ -49,7 +160,9 @@ type DetectionResult struct {
 	Score       float64         `json:"score"`
 	ThreatTypes []ThreatType    `json:"threat_types"`
 	Reason      string          `json:"reason,omitempty"`
+	Endpoint    string          `json:"endpoint,omitempty"` // Model that produced the result
 	Duration    time.Duration   `json:"duration"`
+	Matches     []Match         `json:"matches,omitempty"` // Spans found in the original text (heuristic only)
 }
 
 // HealthStatus represents the health status of the detection engine with circuit breakers
@@ -63,6 +176,19 @@ type HealthStatus struct {
 	TotalModels      int                            `json:"total_models"`
 	CircuitBreakers  map[string]CircuitBreakerStats `json:"circuit_breakers,omitempty"`
 	APIKeyConfigured bool                           `json:"api_key_configured"`
+	LocalDetection   bool                           `json:"local_detection"`
+
+	// Models skipped after authentication failures, keyed by model name
+	MisconfiguredModels map[string]string `json:"misconfigured_models,omitempty"`
+
+	// Models skipped after exhausting their quota, keyed by model name, with when they resume
+	QuotaExhaustedUntil map[string]time.Time `json:"quota_exhausted_until,omitempty"`
+
+	// Daily spend on paid models, when a budget is configured
+	CostBudget *CostBudgetStatus `json:"cost_budget,omitempty"`
+
+	// Latest active health probe per endpoint, when probing is enabled
+	Probes map[string]ProbeResult `json:"probes,omitempty"`
 	
 	// Legacy fields for backward compatibility
 	LLMEndpoints     []string      `json:"llm_endpoints,omitempty"`
@@ -0,0 +1,97 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// chatCompletionsPath is appended to OpenAI-compatible base URLs that don't already name the endpoint
+const chatCompletionsPath = "/chat/completions"
+
+// chatCompletionsURL resolves a base URL such as http://localhost:1234/v1 to its chat-completions endpoint
+func chatCompletionsURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if strings.HasSuffix(baseURL, chatCompletionsPath) {
+		return baseURL
+	}
+	return baseURL + chatCompletionsPath
+}
+
+// setAuthHeader attaches the endpoint's API key using its configured header style.
+// The default is "Authorization: Bearer <key>"; keyless endpoints send nothing.
+func setAuthHeader(req *http.Request, endpoint LLMEndpoint) {
+	if endpoint.APIKey == "" {
+		return
+	}
+
+	header, scheme := endpoint.AuthHeader, endpoint.AuthScheme
+	if header == "" {
+		header, scheme = "Authorization", "Bearer"
+	}
+
+	value := endpoint.APIKey
+	if scheme != "" {
+		value = scheme + " " + value
+	}
+	req.Header.Set(header, value)
+}
+
+// callOpenAICompatible makes request to any API implementing the OpenAI chat-completions schema
+// (OpenAI, LM Studio, vLLM, Together, Groq, ...)
+func (l *LLMDetector) callOpenAICompatible(ctx context.Context, endpoint LLMEndpoint, prompt string) (string, error) {
+	reqBody := OpenRouterRequest{
+		Model: endpoint.Model,
+		Messages: []OpenRouterMessage{
+			{
+				Role:    "system",
+				Content: l.systemPromptFor(endpoint),
+			},
+			{
+				Role:    "user",
+				Content: "Text to analyze:\n" + prompt,
+			},
+		},
+	}
+	if endpoint.Generation.JSONMode {
+		reqBody.Messages[0].Content += jsonModeInstruction
+		reqBody.ResponseFormat = openAIAnalysisFormat
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", chatCompletionsURL(endpoint.URL), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	setAuthHeader(req, endpoint)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", newRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", newAPIError(resp)
+	}
+
+	var response OpenRouterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,133 @@
+package detector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"prompt-injection-detection/internal/canary"
+)
+
+// outputShingleSize is the word n-gram length used to spot partial system prompt echoes
+const outputShingleSize = 8
+
+// OutputScanner inspects model-generated text for leaked instructions and secrets
+type OutputScanner struct {
+	secretPatterns []outputPattern
+	leakPhrases    []outputPattern
+}
+
+// outputPattern is a weighted regex indicating leakage in model output
+type outputPattern struct {
+	pattern     *regexp.Regexp
+	weight      float64
+	description string
+}
+
+// NewOutputScanner creates an output scanner with the built-in secret and leak patterns
+func NewOutputScanner() *OutputScanner {
+	return &OutputScanner{
+		secretPatterns: []outputPattern{
+			{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), 0.9, "OpenAI-style API key"},
+			{regexp.MustCompile(`\bhf_[A-Za-z0-9]{30,}`), 0.9, "HuggingFace token"},
+			{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), 0.9, "AWS access key"},
+			{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`), 0.9, "GitHub token"},
+			{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`), 0.95, "private key"},
+			{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), 0.8, "JWT"},
+			{regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key)\s*[:=]\s*\S{6,}`), 0.7, "credential assignment"},
+		},
+		leakPhrases: []outputPattern{
+			{regexp.MustCompile(`(?i)\b(my|the) (system prompt|initial instructions|hidden instructions) (is|are|says?)\b`), 0.7, "system prompt disclosure"},
+			{regexp.MustCompile(`(?i)\bI (was|am|have been) (instructed|told|programmed) to\b`), 0.4, "instruction disclosure"},
+		},
+	}
+}
+
+// Scan analyzes generated output, using the known system prompt and canaries for exact-match leakage
+func (s *OutputScanner) Scan(req *OutputDetectionRequest) *DetectionResult {
+	startTime := time.Now()
+
+	result := &DetectionResult{
+		Method:      MethodHeuristic,
+		Score:       0.0,
+		ThreatTypes: make([]ThreatType, 0),
+		Endpoint:    localEndpointName,
+	}
+	findings := make([]string, 0)
+
+	// Canary echoes, even obfuscated ones, are deterministic proof of leakage
+	for _, token := range req.Canaries {
+		if canary.Contains(req.Output, token) {
+			result.Score = 1.0
+			result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypeSystemPromptLeak)
+			findings = append(findings, "canary token echoed")
+			break
+		}
+	}
+
+	if req.SystemPrompt != "" {
+		if leakRatio := systemPromptOverlap(req.Output, req.SystemPrompt); leakRatio > 0 {
+			result.Score = combineScores(result.Score, 0.6+0.4*leakRatio)
+			result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypeSystemPromptLeak)
+			findings = append(findings, fmt.Sprintf("%.0f%% of system prompt echoed", leakRatio*100))
+		}
+	}
+
+	for _, leak := range s.leakPhrases {
+		if leak.pattern.MatchString(req.Output) {
+			result.Score = combineScores(result.Score, leak.weight)
+			result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypeSystemPromptLeak)
+			findings = append(findings, leak.description)
+		}
+	}
+
+	for _, secret := range s.secretPatterns {
+		if secret.pattern.MatchString(req.Output) {
+			result.Score = combineScores(result.Score, secret.weight)
+			result.ThreatTypes = appendThreat(result.ThreatTypes, ThreatTypeDataExtraction)
+			findings = append(findings, secret.description)
+		}
+	}
+
+	if len(findings) == 0 {
+		result.Reason = "output: no leakage detected"
+	} else {
+		result.Reason = fmt.Sprintf("output: %s", strings.Join(findings, ", "))
+	}
+
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// systemPromptOverlap returns the fraction of the system prompt reproduced in the output (0 if none)
+func systemPromptOverlap(output, systemPrompt string) float64 {
+	normalizedOutput := normalizeForOverlap(output)
+	normalizedPrompt := normalizeForOverlap(systemPrompt)
+
+	if strings.Contains(normalizedOutput, normalizedPrompt) {
+		return 1.0
+	}
+
+	promptWords := strings.Fields(normalizedPrompt)
+	if len(promptWords) < outputShingleSize {
+		return 0
+	}
+
+	total := 0
+	matched := 0
+	for i := 0; i+outputShingleSize <= len(promptWords); i++ {
+		total++
+		shingle := strings.Join(promptWords[i:i+outputShingleSize], " ")
+		if strings.Contains(normalizedOutput, shingle) {
+			matched++
+		}
+	}
+
+	return float64(matched) / float64(total)
+}
+
+// normalizeForOverlap lowercases text and collapses whitespace so formatting changes don't hide echoes
+func normalizeForOverlap(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -3,10 +3,18 @@ package detector
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"prompt-injection-detection/internal/logging"
+	"prompt-injection-detection/internal/redact"
+	"prompt-injection-detection/internal/version"
 )
 
 // Pipeline orchestrates LLM-based prompt injection detection
@@ -17,7 +25,43 @@ type Pipeline struct {
 
 	// Configuration
 	confidenceThreshold float64
+	localOnly           bool // Skip LLM calls and use heuristic detection only
 	startTime           time.Time
+
+	// Leakage detection for model-generated output
+	outputScanner *OutputScanner
+
+	// Embedded instruction detection for untrusted document content
+	documentScanner *DocumentScanner
+
+	// Command/SQL injection and path traversal detection for tool call arguments
+	toolCallScanner *ToolCallScanner
+
+	// Verdict returned when the LLM is unavailable or fails
+	failureMode FailureMode
+
+	// Score bands responses are graded into
+	severity SeverityThresholds
+
+	// Background reachability checks of the LLM endpoints, nil when disabled
+	prober *HealthProber
+
+	// Most recent startup/on-demand self-test
+	selfTest selfTestResults
+
+	// Optional cache of responses for repeated text, nil disables caching
+	resultCache ResultCache
+
+	// Opt-in log of the most recent detections, nil disables capture
+	recent *RecentDetections
+
+	// Optional receiver of completed detections, e.g. webhook alerting
+	notifier DetectionNotifier
+
+	// Persists a record of every completed detection, NopSink when not configured
+	resultSink ResultSink
+	recordText bool // Include the analyzed text in sink records
+	redactor   *redact.Redactor
 }
 
 // Metrics tracks detection performance
@@ -29,18 +73,43 @@ type Metrics struct {
 	TotalLatency       time.Duration
 	DetectionsByThreat map[ThreatType]int64
 	mutex              sync.RWMutex
+
+	// Ring buffer of the most recent latencies for percentile reporting
+	latencies    []time.Duration
+	latencyIndex int
+
+	// Request, failure and detection counts over recent time windows
+	windows *rollingCounters
+}
+
+// latencyReservoirSize bounds how many recent latencies percentiles are computed over
+const latencyReservoirSize = 2048
+
+// LatencyPercentiles summarizes the tail of recent request latencies
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
 }
 
 // NewPipeline creates a new LLM-only detection pipeline
 func NewPipeline(logger *logrus.Logger) *Pipeline {
-	llmDetector := NewLLMDetector()
+	return NewPipelineWithDetector(logger, NewLLMDetector())
+}
 
+// NewPipelineWithDetector creates an LLM-only detection pipeline over a configured detector
+func NewPipelineWithDetector(logger *logrus.Logger, llmDetector *LLMDetector) *Pipeline {
 	pipeline := &Pipeline{
 		llmDetector:         llmDetector,
 		logger:              logger,
 		metrics:             NewMetrics(),
 		confidenceThreshold: 0.6, // Adjusted for LLM-based detection
 		startTime:           time.Now(),
+		outputScanner:       NewOutputScanner(),
+		documentScanner:     NewDocumentScanner(llmDetector.heuristic),
+		toolCallScanner:     NewToolCallScanner(),
+		resultSink:          NopSink{},
+		redactor:            redact.Default(),
 	}
 
 	if llmDetector.IsAvailable() {
@@ -52,28 +121,85 @@ func NewPipeline(logger *logrus.Logger) *Pipeline {
 	return pipeline
 }
 
-// Analyze processes a detection request using LLM-only approach
+// Analyze processes a detection request and applies the requested action to the result
 func (p *Pipeline) Analyze(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error) {
+	ctx, span := tracer.Start(ctx, "pipeline.analyze")
+	explain := req.Config != nil && req.Config.Explain
+	if explain {
+		req = explainRequest(req)
+	}
+	response, err := analyzeCached(ctx, p.resultCache, req, p.analyze)
+	if err == nil {
+		inferThreatTypes(response, req, p.llmDetector)
+		if explain {
+			response.Explanation = explainDetection(req, response, p.confidenceThreshold, p.llmDetector)
+		}
+		annotateMatches(response, req)
+		response.Severity = p.severity.withDefaults().classify(response)
+		applyAction(response, req, req.Config, p.llmDetector.heuristic)
+		p.recent.Record(requestText(req), response)
+		record := newDetectionRecord(ctx, requestText(req), response, p.recordText, p.redactor)
+		if err := p.resultSink.Record(ctx, record); err != nil {
+			logging.FromContext(ctx, p.logger).WithError(err).Warn("Failed to record detection result")
+		}
+		// Fail-closed verdicts reflect a model outage, not a detected attack
+		if p.notifier != nil && !response.FailedClosed {
+			p.notifier.NotifyDetection(ctx, requestText(req), response)
+		}
+	}
+	endPipelineSpan(span, response, err)
+	return response, err
+}
+
+// analyze processes a detection request using LLM-only approach
+func (p *Pipeline) analyze(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error) {
 	startTime := time.Now()
 
 	// Validate input
-	if len(req.Text) == 0 {
+	if len(req.Text) == 0 && len(req.Messages) == 0 {
 		return p.handleEmptyInput(startTime), nil
 	}
 
 	// Apply request-specific configuration
 	config := p.applyConfig(req.Config)
 
+	// Role-tagged messages are scanned one at a time
+	if len(req.Messages) > 0 {
+		return analyzeMessages(ctx, req, config, p.analyze)
+	}
+
+	// Local mode never touches the network
+	if p.useLocalDetection(config) {
+		result := p.llmDetector.DetectLocal(req.Text)
+		response := p.buildResponse(result, config, time.Since(startTime))
+		p.metrics.RecordSuccess(time.Since(startTime), response)
+		return response, nil
+	}
+
+	// Fast mode answers decisive heuristic hits without calling the LLM
+	if config.Mode == ModeFast {
+		if result := p.llmDetector.DetectLocal(req.Text); result.Score >= config.ConfidenceThreshold {
+			response := p.buildResponse(result, config, time.Since(startTime))
+			p.metrics.RecordSuccess(time.Since(startTime), response)
+			return response, nil
+		}
+	}
+
 	// Check if LLM is available
 	if !p.llmDetector.IsAvailable() {
-		return p.handleUnavailableLLM(startTime), fmt.Errorf("LLM detection unavailable - no API key configured")
+		return p.failureMode.settle(p.handleUnavailableLLM(startTime), fmt.Errorf("LLM detection unavailable - no API key configured"))
 	}
 
 	// Perform LLM detection
-	result, err := p.llmDetector.Detect(req.Text)
+	modelCtx, span := tracer.Start(ctx, "model.call", trace.WithAttributes(attribute.String("model.name", "llm")))
+	result, err := p.llmDetector.Detect(modelCtx, req.Text)
+	if result != nil {
+		span.SetAttributes(attribute.String("model.endpoint", result.Endpoint))
+	}
+	endModelSpan(span, err)
 	if err != nil {
 		p.metrics.RecordFailure(time.Since(startTime))
-		return p.handleLLMError(startTime, err), err
+		return p.failureMode.settle(p.handleLLMError(ctx, startTime, err), err)
 	}
 
 	// Build response
@@ -85,6 +211,148 @@ func (p *Pipeline) Analyze(ctx context.Context, req *DetectionRequest) (*Detecti
 	return response, nil
 }
 
+// SetRecentDetections enables capture of recent detections for GET /v1/detections/recent
+func (p *Pipeline) SetRecentDetections(recent *RecentDetections) {
+	p.recent = recent
+}
+
+// RecentDetections returns the recent detection log, nil when capture is disabled
+func (p *Pipeline) RecentDetections() *RecentDetections {
+	return p.recent
+}
+
+// SetRedactor sets the patterns masked in text the pipeline stores, such as sink records
+func (p *Pipeline) SetRedactor(redactor *redact.Redactor) {
+	p.redactor = redactor
+}
+
+// SetResultSink persists a record of every completed detection to sink, with the
+// analyzed text only when includeText is set. Wrap slow sinks in an AsyncSink so
+// recording never delays the response.
+func (p *Pipeline) SetResultSink(sink ResultSink, includeText bool) {
+	p.resultSink = sink
+	p.recordText = includeText
+}
+
+// SetNotifier registers a receiver for every completed detection
+func (p *Pipeline) SetNotifier(notifier DetectionNotifier) {
+	p.notifier = notifier
+}
+
+// SetResultCache enables caching of detection responses for repeated text
+func (p *Pipeline) SetResultCache(cache ResultCache) {
+	p.resultCache = cache
+}
+
+// SetSeverityThresholds sets the minimum score for each response severity
+func (p *Pipeline) SetSeverityThresholds(thresholds SeverityThresholds) {
+	p.severity = thresholds
+}
+
+// SetHealthProber makes the prober check this pipeline's LLM endpoints and
+// report their reachability in health and diagnostics
+func (p *Pipeline) SetHealthProber(prober *HealthProber) {
+	p.prober = prober
+	if prober == nil {
+		return
+	}
+	prober.setTargets(func() []probeTarget {
+		targets := make([]probeTarget, 0, len(p.llmDetector.endpoints))
+		for _, endpoint := range p.llmDetector.endpoints {
+			endpoint := endpoint
+			targets = append(targets, probeTarget{
+				name:  endpoint.Model,
+				probe: func(ctx context.Context) error { return p.llmDetector.probeEndpoint(ctx, endpoint) },
+			})
+		}
+		return targets
+	})
+}
+
+// SetFailureMode chooses whether LLM failures classify the input as safe (open) or malicious (closed)
+func (p *Pipeline) SetFailureMode(mode FailureMode) {
+	p.failureMode = mode
+}
+
+// SetLocalOnly switches the pipeline between LLM and local heuristic detection
+func (p *Pipeline) SetLocalOnly(localOnly bool) {
+	p.localOnly = localOnly
+	if localOnly {
+		p.logger.Info("Local-only detection mode enabled - LLM endpoints will not be called")
+	}
+}
+
+// AnalyzeOutput scans model-generated output for leaked system prompts, canaries and secrets
+func (p *Pipeline) AnalyzeOutput(ctx context.Context, req *OutputDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.outputScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime))
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.metrics.RecordSuccess(time.Since(startTime), response)
+
+	return response, nil
+}
+
+// AnalyzeDocument scans untrusted document content, such as retrieved RAG context, for embedded instructions
+func (p *Pipeline) AnalyzeDocument(ctx context.Context, req *DocumentDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.documentScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime))
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.metrics.RecordSuccess(time.Since(startTime), response)
+
+	return response, nil
+}
+
+// AnalyzeToolCall scans the arguments of a model-chosen tool call for injection and path traversal
+func (p *Pipeline) AnalyzeToolCall(ctx context.Context, req *ToolCallDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.toolCallScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime))
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.metrics.RecordSuccess(time.Since(startTime), response)
+
+	return response, nil
+}
+
+// Readiness reports whether the pipeline can currently serve detections, with a short reason
+func (p *Pipeline) Readiness() (bool, string) {
+	if p.localOnly {
+		return true, "local detection active"
+	}
+	if !p.llmDetector.IsAvailable() {
+		return false, "no API key configured"
+	}
+	return true, "LLM endpoints configured"
+}
+
+// DispatchSlots returns the outbound model call slots in use and the total available (0 means unlimited)
+func (p *Pipeline) DispatchSlots() (int, int) {
+	return p.llmDetector.DispatchSlots()
+}
+
+// useLocalDetection reports whether a request should be served by local detection
+func (p *Pipeline) useLocalDetection(config *DetectionConfig) bool {
+	if config.LocalOnly != nil {
+		return *config.LocalOnly
+	}
+	return p.localOnly
+}
+
+// IsAvailable reports whether the pipeline can produce real detections
+func (p *Pipeline) IsAvailable() bool {
+	return p.localOnly || p.llmDetector.IsAvailable()
+}
+
 // handleEmptyInput returns appropriate response for empty input
 func (p *Pipeline) handleEmptyInput(startTime time.Time) *DetectionResponse {
 	return &DetectionResponse{
@@ -97,28 +365,30 @@ func (p *Pipeline) handleEmptyInput(startTime time.Time) *DetectionResponse {
 	}
 }
 
-// handleUnavailableLLM returns conservative response when LLM is unavailable
+// handleUnavailableLLM returns the failure-mode verdict when LLM is unavailable
 func (p *Pipeline) handleUnavailableLLM(startTime time.Time) *DetectionResponse {
+	isMalicious, confidence, classification := p.failureMode.verdict()
 	return &DetectionResponse{
-		IsMalicious:      false,
-		Confidence:       0.5, // Conservative uncertainty
+		IsMalicious:      isMalicious,
+		Confidence:       confidence,
 		ThreatTypes:      []string{},
 		ProcessingTimeMs: time.Since(startTime).Milliseconds(),
-		Reason:           "LLM unavailable - conservative safe classification",
+		Reason:           "LLM unavailable - conservative " + classification,
 		Endpoint:         "fallback",
 	}
 }
 
 // handleLLMError returns appropriate response when LLM fails
-func (p *Pipeline) handleLLMError(startTime time.Time, err error) *DetectionResponse {
-	p.logger.WithError(err).Error("LLM detection failed")
+func (p *Pipeline) handleLLMError(ctx context.Context, startTime time.Time, err error) *DetectionResponse {
+	logging.FromContext(ctx, p.logger).WithError(err).Error("LLM detection failed")
 
+	isMalicious, confidence, classification := p.failureMode.verdict()
 	return &DetectionResponse{
-		IsMalicious:      false,
-		Confidence:       0.5, // Conservative uncertainty
+		IsMalicious:      isMalicious,
+		Confidence:       confidence,
 		ThreatTypes:      []string{},
 		ProcessingTimeMs: time.Since(startTime).Milliseconds(),
-		Reason:           fmt.Sprintf("LLM error: %s - conservative safe classification", err.Error()),
+		Reason:           fmt.Sprintf("LLM error: %s - conservative %s", err.Error(), classification),
 		Endpoint:         "error",
 	}
 }
@@ -137,7 +407,7 @@ func (p *Pipeline) buildResponse(result *DetectionResult, config *DetectionConfi
 		threshold = p.confidenceThreshold
 	}
 
-	isMalicious := result.Score >= threshold
+	isMalicious := exceedsThreshold(result.Score, threatTypes, config, p.confidenceThreshold)
 
 	response := &DetectionResponse{
 		IsMalicious:      isMalicious,
@@ -145,7 +415,8 @@ func (p *Pipeline) buildResponse(result *DetectionResult, config *DetectionConfi
 		ThreatTypes:      threatTypes,
 		ProcessingTimeMs: duration.Milliseconds(),
 		Reason:           result.Reason,
-		Endpoint:         "huggingface", // Could be dynamic based on which endpoint was used
+		Endpoint:         result.Endpoint,
+		Matches:          result.Matches,
 	}
 
 	p.logger.WithFields(logrus.Fields{
@@ -155,21 +426,49 @@ func (p *Pipeline) buildResponse(result *DetectionResult, config *DetectionConfi
 		"threat_types": threatTypes,
 		"duration_ms":  duration.Milliseconds(),
 		"reason":       result.Reason,
+		"endpoint":     result.Endpoint,
 	}).Debug("LLM detection completed")
 
 	return response
 }
 
+// exceedsThreshold reports whether score crosses the threshold of any detected threat type,
+// using the global threshold for types without an override
+func exceedsThreshold(score float64, threatTypes []string, config *DetectionConfig, defaultThreshold float64) bool {
+	threshold := config.ConfidenceThreshold
+	if threshold == 0 {
+		threshold = defaultThreshold
+	}
+
+	if len(threatTypes) == 0 {
+		return score >= threshold
+	}
+
+	for _, threat := range threatTypes {
+		threatThreshold := threshold
+		if override, exists := config.ThreatThresholds[threat]; exists {
+			threatThreshold = override
+		}
+		if score >= threatThreshold {
+			return true
+		}
+	}
+	return false
+}
+
 // applyConfig applies request-specific configuration with defaults
-func (p *Pipeline) applyConfig(config *DetectionConfig) *DetectionConfig {
-	if config == nil {
-		config = &DetectionConfig{}
+func (p *Pipeline) applyConfig(requested *DetectionConfig) *DetectionConfig {
+	// Work on a copy: the caller's config may be shared by concurrent requests
+	config := &DetectionConfig{}
+	if requested != nil {
+		*config = *requested
 	}
 
 	// Set defaults if not specified
 	if config.ConfidenceThreshold == 0 {
 		config.ConfidenceThreshold = p.confidenceThreshold
 	}
+	resolveMode(config)
 
 	return config
 }
@@ -187,19 +486,37 @@ func (p *Pipeline) GetHealth() *HealthStatus {
 	}
 	apiKeyConfigured := p.llmDetector.IsAvailable()
 
+	probes := p.prober.Results()
+	unreachable := 0
+	for _, endpoint := range p.llmDetector.endpoints {
+		if p.prober.unreachable(endpoint.Model) {
+			unreachable++
+		}
+	}
+
 	status := "healthy"
-	if !apiKeyConfigured {
+	switch {
+	case p.localOnly:
+		// Local detection serves every request
+	case !apiKeyConfigured:
 		status = "degraded - no API key"
+	case unreachable > 0 && unreachable == len(p.llmDetector.endpoints):
+		status = "degraded - LLM endpoints unreachable"
+	case unreachable > 0:
+		status = "degraded - some LLM endpoints unreachable"
 	}
 
 	return &HealthStatus{
 		Status:           status,
-		Version:          "2.1.0-specialized-models",
+		Version:          version.Version,
 		Uptime:           time.Since(p.startTime),
 		RequestsServed:   p.metrics.GetRequestsTotal(),
 		AverageLatency:   p.metrics.GetAverageLatency(),
 		LLMEndpoints:     endpoints,
 		APIKeyConfigured: apiKeyConfigured,
+		LocalDetection:   p.localOnly,
+
+		Probes: probes,
 	}
 }
 
@@ -213,21 +530,35 @@ func (p *Pipeline) DiagnoseLLMEndpoints() map[string]interface{} {
 	}
 
 	// Test cloud LLM endpoints
+	stats := p.llmDetector.EndpointStats()
+	probes := p.prober.Results()
 	for i, endpoint := range p.llmDetector.endpoints {
 		name := fmt.Sprintf("endpoint_%d", i)
-		diagnostic[name] = map[string]interface{}{
-			"status":  "available",
+		status := "available"
+		if p.prober.unreachable(endpoint.Model) {
+			status = "unreachable"
+		}
+		endpointDiagnostic := map[string]interface{}{
+			"status":  status,
 			"type":    endpoint.Type,
 			"model":   endpoint.Model,
 			"url":     endpoint.URL,
 			"timeout": endpoint.Timeout.String(),
+			"stats":   stats[endpoint.Model],
+		}
+		if probe, probed := probes[endpoint.Model]; probed {
+			endpointDiagnostic["probe"] = probe
 		}
+		diagnostic[name] = endpointDiagnostic
 	}
 
 	diagnostic["api_key_configured"] = p.llmDetector.IsAvailable()
 	diagnostic["total_endpoints"] = len(p.llmDetector.endpoints)
+	diagnostic["local_detection"] = p.localOnly
 
-	if p.llmDetector.IsAvailable() {
+	if p.localOnly {
+		diagnostic["status"] = "Local-only mode - heuristic detection active"
+	} else if p.llmDetector.IsAvailable() {
 		diagnostic["status"] = "LLM endpoints ready"
 	} else {
 		diagnostic["status"] = "No API key - set HUGGINGFACE_API_KEY environment variable"
@@ -240,6 +571,8 @@ func (p *Pipeline) DiagnoseLLMEndpoints() map[string]interface{} {
 func NewMetrics() *Metrics {
 	return &Metrics{
 		DetectionsByThreat: make(map[ThreatType]int64),
+		latencies:          make([]time.Duration, 0, latencyReservoirSize),
+		windows:            newRollingCounters(time.Now),
 	}
 }
 
@@ -252,6 +585,8 @@ func (m *Metrics) RecordSuccess(duration time.Duration, response *DetectionRespo
 	m.RequestsSuccessful++
 	m.TotalLatency += duration
 	m.AverageLatency = m.TotalLatency / time.Duration(m.RequestsTotal)
+	m.recordLatency(duration)
+	m.windows.record(false, response.IsMalicious)
 
 	// Record threat type statistics
 	for _, threatStr := range response.ThreatTypes {
@@ -269,6 +604,54 @@ func (m *Metrics) RecordFailure(duration time.Duration) {
 	m.RequestsFailed++
 	m.TotalLatency += duration
 	m.AverageLatency = m.TotalLatency / time.Duration(m.RequestsTotal)
+	m.recordLatency(duration)
+	m.windows.record(true, false)
+}
+
+// GetWindowCounts returns request, failure and malicious counts for the 1m, 5m and 1h windows
+func (m *Metrics) GetWindowCounts() map[string]WindowCounts {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.windows.snapshot()
+}
+
+// recordLatency adds a sample to the reservoir, overwriting the oldest once full.
+// Callers must hold the write lock.
+func (m *Metrics) recordLatency(duration time.Duration) {
+	if len(m.latencies) < latencyReservoirSize {
+		m.latencies = append(m.latencies, duration)
+		return
+	}
+	m.latencies[m.latencyIndex] = duration
+	m.latencyIndex = (m.latencyIndex + 1) % latencyReservoirSize
+}
+
+// GetLatencyPercentiles returns p50/p95/p99 over the most recent requests
+func (m *Metrics) GetLatencyPercentiles() LatencyPercentiles {
+	m.mutex.RLock()
+	samples := make([]time.Duration, len(m.latencies))
+	copy(samples, m.latencies)
+	m.mutex.RUnlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	return LatencyPercentiles{
+		P50: percentile(samples, 0.50),
+		P95: percentile(samples, 0.95),
+		P99: percentile(samples, 0.99),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted samples, zero when empty
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
 }
 
 // GetRequestsTotal returns total requests processed
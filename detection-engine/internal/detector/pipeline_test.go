@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExceedsThresholdPerThreatType(t *testing.T) {
+	config := &DetectionConfig{
+		ConfidenceThreshold: 0.7,
+		ThreatThresholds: map[string]float64{
+			string(ThreatTypeDataExtraction):  0.3,
+			string(ThreatTypeDelimiterAttack): 0.95,
+		},
+	}
+
+	tests := map[string]struct {
+		score   float64
+		threats []string
+		want    bool
+	}{
+		"strict override met":         {0.4, []string{"data_extraction"}, true},
+		"strict override not met":     {0.2, []string{"data_extraction"}, false},
+		"lenient override not met":    {0.9, []string{"delimiter_attack"}, false},
+		"lenient override met":        {0.96, []string{"delimiter_attack"}, true},
+		"unlisted type uses global":   {0.75, []string{"jailbreak"}, true},
+		"unlisted type below global":  {0.65, []string{"jailbreak"}, false},
+		"any type crossing is enough": {0.4, []string{"delimiter_attack", "data_extraction"}, true},
+		"no threats uses global":      {0.7, nil, true},
+		"no threats below global":     {0.5, nil, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := exceedsThreshold(tt.score, tt.threats, config, 0.5); got != tt.want {
+				t.Errorf("exceedsThreshold(%v, %v) = %v, want %v", tt.score, tt.threats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceedsThresholdFallsBackToDefault(t *testing.T) {
+	config := &DetectionConfig{ThreatThresholds: map[string]float64{"jailbreak": 0.9}}
+
+	if !exceedsThreshold(0.6, []string{"injection"}, config, 0.5) {
+		t.Error("score 0.6 with default threshold 0.5 not flagged")
+	}
+	if exceedsThreshold(0.6, []string{"jailbreak"}, config, 0.5) {
+		t.Error("score 0.6 flagged despite the jailbreak override of 0.9")
+	}
+}
+
+func TestAnalyzeAppliesThreatThresholds(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t, fakeModel{name: "model", score: 0.6, threats: []ThreatType{ThreatTypeDelimiterAttack}})
+
+	tests := map[string]struct {
+		config *DetectionConfig
+		want   bool
+	}{
+		"global threshold":  {&DetectionConfig{ConfidenceThreshold: 0.7}, false},
+		"stricter override": {&DetectionConfig{ConfidenceThreshold: 0.7, ThreatThresholds: map[string]float64{"delimiter_attack": 0.5}}, true},
+		"other type only":   {&DetectionConfig{ConfidenceThreshold: 0.7, ThreatThresholds: map[string]float64{"data_extraction": 0.1}}, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello there", Config: tt.config})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if response.IsMalicious != tt.want {
+				t.Errorf("IsMalicious = %v, want %v", response.IsMalicious, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateThreatThresholds(t *testing.T) {
+	config := &DetectionConfig{ThreatThresholds: map[string]float64{"jailbreak": 1.5}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a threat threshold above 1")
+	}
+}
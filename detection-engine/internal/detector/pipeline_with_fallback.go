@@ -2,31 +2,115 @@ package detector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"prompt-injection-detection/internal/logging"
+	"prompt-injection-detection/internal/redact"
 	"prompt-injection-detection/internal/metrics"
+	"prompt-injection-detection/internal/version"
 )
 
+// circuitStateChanges counts circuit breaker transitions per model
+var circuitStateChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "detection_circuit_state_changes_total",
+	Help: "Circuit breaker state transitions by model",
+}, []string{"model", "from", "to"})
+
 // FallbackPipeline orchestrates multiple AI models with circuit breaker fallback
 type FallbackPipeline struct {
 	modelRegistry     *ModelRegistry
 	circuitBreakers   map[string]*CircuitBreaker
+	breakersMutex     sync.RWMutex
 	llmDetector       *LLMDetector
 	logger            *logrus.Logger
 	metrics           *Metrics
 	metricsCollector  *metrics.MetricsCollector
 
+	// Detector implementations keyed by model provider
+	providers *ProviderRegistry
+
+	// Models that failed authentication, skipped until their breaker is reset
+	misconfiguredModels map[string]string
+	misconfiguredMutex  sync.RWMutex
+
+	// Models whose quota is exhausted, skipped until the recorded time
+	quotaExhausted map[string]time.Time
+	quotaMutex     sync.RWMutex
+
+	// Cumulative risk for multi-turn conversations
+	sessions *SessionStore
+
+	// Breaker state shared across replicas, nil keeps breakers in memory
+	breakerStore BreakerStateStore
+
+	// Optional cache of responses for repeated text, nil disables caching
+	resultCache ResultCache
+
+	// Opt-in log of the most recent detections, nil disables capture
+	recent *RecentDetections
+
+	// Optional receiver of completed detections, e.g. webhook alerting
+	notifier DetectionNotifier
+
+	// Persists a record of every completed detection, NopSink when not configured
+	resultSink ResultSink
+	recordText bool // Include the analyzed text in sink records
+	redactor   *redact.Redactor
+
+	// Daily spend cap for paid models, nil leaves spend unlimited
+	costBudget *CostBudget
+
+	// Verdict returned when every model fails
+	failureMode FailureMode
+
+	// Consensus score spread above which models are flagged as disagreeing
+	disagreementThreshold float64
+
+	// Model held out of consensus voting that decides split votes, "" disables
+	tieBreaker string
+
+	// Benign votes required before an input is reported safe, 0 disables
+	minBenignVotes int
+
+	// Score bands responses are graded into
+	severity SeverityThresholds
+
+	// Background reachability checks of the enabled models, nil when disabled
+	prober *HealthProber
+
 	// Configuration
 	confidenceThreshold float64
+	localOnly           bool // Skip model calls and use heuristic detection only
 	startTime           time.Time
+
+	// Leakage detection for model-generated output
+	outputScanner *OutputScanner
+
+	// Embedded instruction detection for untrusted document content
+	documentScanner *DocumentScanner
+
+	// Command/SQL injection and path traversal detection for tool call arguments
+	toolCallScanner *ToolCallScanner
+
+	// Most recent startup/on-demand self-test
+	selfTest selfTestResults
 }
 
 // NewFallbackPipeline creates a new pipeline with circuit breaker fallback system
 func NewFallbackPipeline(logger *logrus.Logger) *FallbackPipeline {
-	modelRegistry := NewModelRegistry()
-	llmDetector := NewLLMDetector()
+	return NewFallbackPipelineWithRegistry(logger, NewModelRegistry(), NewLLMDetector())
+}
+
+// NewFallbackPipelineWithRegistry creates a fallback pipeline over an existing model registry and detector
+func NewFallbackPipelineWithRegistry(logger *logrus.Logger, modelRegistry *ModelRegistry, llmDetector *LLMDetector) *FallbackPipeline {
 	
 	pipeline := &FallbackPipeline{
 		modelRegistry:       modelRegistry,
@@ -35,9 +119,19 @@ func NewFallbackPipeline(logger *logrus.Logger) *FallbackPipeline {
 		logger:              logger,
 		metrics:             NewMetrics(),
 		metricsCollector:    metrics.NewMetricsCollector(),
+		misconfiguredModels: make(map[string]string),
+		quotaExhausted:      make(map[string]time.Time),
+		sessions:            NewSessionStore(defaultSessionTTL),
 		confidenceThreshold: 0.6,
 		startTime:           time.Now(),
+		outputScanner:       NewOutputScanner(),
+		documentScanner:     NewDocumentScanner(llmDetector.heuristic),
+		toolCallScanner:     NewToolCallScanner(),
+		providers:           NewProviderRegistry(),
+		resultSink:          NopSink{},
+		redactor:            redact.Default(),
 	}
+	registerEndpointProviders(pipeline.providers, llmDetector)
 
 	// Initialize circuit breakers for each enabled model
 	pipeline.initializeCircuitBreakers()
@@ -56,26 +150,75 @@ func (p *FallbackPipeline) initializeCircuitBreakers() {
 	enabledModels := p.modelRegistry.GetEnabledModels()
 	
 	for _, model := range enabledModels {
-		cbConfig := CircuitBreakerConfig{
-			Name:             model.Name,
-			FailureThreshold: model.CircuitBreaker.FailureThreshold,
-			SuccessThreshold: model.CircuitBreaker.SuccessThreshold,
-			Timeout:          model.CircuitBreaker.Timeout,
-			MaxTimeout:       model.CircuitBreaker.MaxTimeout,
-		}
-		
-		cb := NewCircuitBreaker(cbConfig)
-		cb.SetMetricsCollector(p.metricsCollector)
-		p.circuitBreakers[model.Name] = cb
-		p.logger.WithFields(logrus.Fields{
-			"model":             model.Name,
-			"provider":          model.Provider,
-			"failure_threshold": model.CircuitBreaker.FailureThreshold,
-			"timeout":           model.CircuitBreaker.Timeout,
-		}).Info("Circuit breaker initialized for model")
+		p.ensureCircuitBreaker(model)
 	}
 }
 
+// ensureCircuitBreaker returns the circuit breaker for a model, creating it if missing
+func (p *FallbackPipeline) ensureCircuitBreaker(model ModelConfig) *CircuitBreaker {
+	p.breakersMutex.Lock()
+	defer p.breakersMutex.Unlock()
+
+	if cb, exists := p.circuitBreakers[model.Name]; exists {
+		return cb
+	}
+
+	cbConfig := CircuitBreakerConfig{
+		Name:             model.Name,
+		FailureThreshold: model.CircuitBreaker.FailureThreshold,
+		SuccessThreshold: model.CircuitBreaker.SuccessThreshold,
+		Timeout:          model.CircuitBreaker.Timeout,
+		MaxTimeout:       model.CircuitBreaker.MaxTimeout,
+
+		HalfOpenMaxRequests: model.CircuitBreaker.HalfOpenMaxRequests,
+		OnStateChange:       p.onCircuitStateChange,
+		WindowSize:          model.CircuitBreaker.WindowSize,
+		WindowFailureRatio:  model.CircuitBreaker.WindowFailureRatio,
+	}
+
+	cb := NewCircuitBreaker(cbConfig)
+	cb.SetMetricsCollector(p.metricsCollector)
+	if p.breakerStore != nil {
+		cb.SetStateStore(p.breakerStore)
+	}
+	p.circuitBreakers[model.Name] = cb
+	p.logger.WithFields(logrus.Fields{
+		"model":             model.Name,
+		"provider":          model.Provider,
+		"failure_threshold": model.CircuitBreaker.FailureThreshold,
+		"timeout":           model.CircuitBreaker.Timeout,
+	}).Info("Circuit breaker initialized for model")
+	return cb
+}
+
+// onCircuitStateChange logs and counts a circuit breaker transition
+func (p *FallbackPipeline) onCircuitStateChange(name string, from, to CircuitState) {
+	circuitStateChanges.WithLabelValues(name, from.String(), to.String()).Inc()
+
+	entry := p.logger.WithFields(logrus.Fields{
+		"model": name,
+		"from":  from.String(),
+		"to":    to.String(),
+	})
+	if to == CircuitOpen {
+		entry.Warn("Circuit breaker opened")
+		return
+	}
+	entry.Info("Circuit breaker state changed")
+}
+
+// circuitBreakerSnapshot returns a copy of the circuit breaker map safe for iteration
+func (p *FallbackPipeline) circuitBreakerSnapshot() map[string]*CircuitBreaker {
+	p.breakersMutex.RLock()
+	defer p.breakersMutex.RUnlock()
+
+	snapshot := make(map[string]*CircuitBreaker, len(p.circuitBreakers))
+	for name, cb := range p.circuitBreakers {
+		snapshot[name] = cb
+	}
+	return snapshot
+}
+
 // logModelStatus logs the status of all models
 func (p *FallbackPipeline) logModelStatus() {
 	enabledModels := p.modelRegistry.GetEnabledModels()
@@ -92,51 +235,107 @@ func (p *FallbackPipeline) logModelStatus() {
 	}
 }
 
-// Analyze processes a detection request with intelligent fallback
+// Analyze processes a detection request and applies the requested action to the result
 func (p *FallbackPipeline) Analyze(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error) {
+	ctx, span := tracer.Start(ctx, "pipeline.analyze")
+	explain := req.Config != nil && req.Config.Explain
+	if explain {
+		req = explainRequest(req)
+	}
+	response, err := analyzeCached(ctx, p.resultCache, req, p.analyze)
+	if err == nil {
+		inferThreatTypes(response, req, p.llmDetector)
+		if explain {
+			response.Explanation = explainDetection(req, response, p.confidenceThreshold, p.llmDetector)
+		}
+		annotateMatches(response, req)
+		response.Severity = p.severity.withDefaults().classify(response)
+		applyAction(response, req, req.Config, p.llmDetector.heuristic)
+		p.recent.Record(requestText(req), response)
+		record := newDetectionRecord(ctx, requestText(req), response, p.recordText, p.redactor)
+		if err := p.resultSink.Record(ctx, record); err != nil {
+			logging.FromContext(ctx, p.logger).WithError(err).Warn("Failed to record detection result")
+		}
+		// Fail-closed verdicts reflect a model outage, not a detected attack
+		if p.notifier != nil && !response.FailedClosed {
+			p.notifier.NotifyDetection(ctx, requestText(req), response)
+		}
+	}
+	endPipelineSpan(span, response, err)
+	return response, err
+}
+
+// analyze processes a detection request with intelligent fallback
+func (p *FallbackPipeline) analyze(ctx context.Context, req *DetectionRequest) (*DetectionResponse, error) {
+	logger := logging.FromContext(ctx, p.logger)
+
 	startTime := time.Now()
 
 	// Validate input
-	if len(req.Text) == 0 {
+	if len(req.Text) == 0 && len(req.Messages) == 0 {
 		return p.handleEmptyInput(startTime), nil
 	}
 
 	// Apply request-specific configuration
 	config := p.applyConfig(req.Config)
 
+	// Role-tagged messages are scanned one at a time
+	if len(req.Messages) > 0 {
+		return analyzeMessages(ctx, req, config, p.analyze)
+	}
+
+	// Local mode never touches the network
+	if p.useLocalDetection(config) {
+		return p.analyzeLocally(req, config, startTime), nil
+	}
+
+	ctx = withVariantScope(ctx, modeVariantScope(config.Mode))
+	if config.Mode == ModeFast {
+		return p.analyzeFast(ctx, req, config, startTime)
+	}
+
+	switch config.Strategy {
+	case StrategyRace:
+		return p.analyzeRace(ctx, req, config, startTime)
+	case StrategyConsensus:
+		return p.analyzeConsensus(ctx, req, config, startTime)
+	}
+
 	// Try models in priority order with circuit breaker protection
 	enabledModels := p.modelRegistry.GetEnabledModels()
 	
 	var lastError error
 	var attemptedModels []string
+	var modelResults []ModelResult
+
+	// Each attempt gets a fair share of the remaining budget so one slow model can't consume it all,
+	// and later attempts shrink so the cumulative time stays within the caller's deadline
+	deadline := requestDeadline(config, startTime)
+
+	for i, model := range enabledModels {
+		model.Timeout = attemptTimeout(ctx, model, deadline, len(enabledModels)-i)
+		if model.Timeout <= 0 {
+			lastError = ErrBudgetExhausted
+			break
+		}
 
-	for _, model := range enabledModels {
-		circuitBreaker := p.circuitBreakers[model.Name]
 		attemptedModels = append(attemptedModels, model.Name)
-		
-		p.logger.WithFields(logrus.Fields{
-			"model": model.Name,
-			"state": circuitBreaker.GetStateName(),
-		}).Debug("Attempting model detection")
 
 		// Try this model through circuit breaker
-		var result *DetectionResult
-		err := circuitBreaker.Call(func() error {
-			var detectionErr error
-			result, detectionErr = p.detectWithModel(model, req.Text)
-			return detectionErr
-		})
+		result, modelResult, err := p.callModel(ctx, model, req.Text)
+		modelResults = append(modelResults, modelResult)
 
 		if err == ErrCircuitOpen {
-			p.logger.WithField("model", model.Name).Warn("Model circuit breaker is open, trying next model")
+			logger.WithField("model", model.Name).Warn("Model circuit breaker is open, trying next model")
 			lastError = err
 			continue
 		}
 
 		if err != nil {
-			p.logger.WithFields(logrus.Fields{
-				"model": model.Name,
-				"error": err.Error(),
+			logger.WithFields(logrus.Fields{
+				"model":          model.Name,
+				"error":          err.Error(),
+				"error_category": ErrorCategoryOf(err),
 			}).Warn("Model detection failed, trying next model")
 			lastError = err
 			continue
@@ -144,21 +343,13 @@ func (p *FallbackPipeline) Analyze(ctx context.Context, req *DetectionRequest) (
 
 		// Success! Build and return response
 		response := p.buildResponse(result, config, time.Since(startTime), model.Name)
-		p.metrics.RecordSuccess(time.Since(startTime), response)
-		
-		// Record Prometheus metrics
-		resultType := "benign"
-		if response.IsMalicious {
-			resultType = "malicious"
+		if config.DetailedResponse {
+			response.ModelResults = modelResults
+			response.Strategy = StrategyFirst
 		}
-		p.metricsCollector.RecordDetectionRequest(
-			model.Name, 
-			resultType, 
-			response.ThreatTypes, 
-			time.Since(startTime),
-		)
-		
-		p.logger.WithFields(logrus.Fields{
+		p.recordDetection(model.Name, response, time.Since(startTime))
+
+		logger.WithFields(logrus.Fields{
 			"model":       model.Name,
 			"confidence":  result.Score,
 			"is_malicious": response.IsMalicious,
@@ -168,37 +359,420 @@ func (p *FallbackPipeline) Analyze(ctx context.Context, req *DetectionRequest) (
 		return response, nil
 	}
 
+	return p.handleFailedAttempts(config, StrategyFirst, startTime, attemptedModels, modelResults, lastError)
+}
+
+// analyzeLocally serves a request with heuristic detection only
+func (p *FallbackPipeline) analyzeLocally(req *DetectionRequest, config *DetectionConfig, startTime time.Time) *DetectionResponse {
+	result := p.llmDetector.DetectLocal(req.Text)
+	response := p.buildResponse(result, config, time.Since(startTime), localEndpointName)
+	p.recordDetection(localEndpointName, response, time.Since(startTime))
+	return response
+}
+
+// analyzeFast serves fast mode: a decisive heuristic hit answers without a model call,
+// otherwise a single classifier checks the original text and is merged with the heuristics
+func (p *FallbackPipeline) analyzeFast(ctx context.Context, req *DetectionRequest, config *DetectionConfig, startTime time.Time) (*DetectionResponse, error) {
+	heuristic := p.llmDetector.DetectLocal(req.Text)
+	if heuristic.Score >= config.ConfidenceThreshold {
+		response := p.buildResponse(heuristic, config, time.Since(startTime), localEndpointName)
+		p.recordDetection(localEndpointName, response, time.Since(startTime))
+		return response, nil
+	}
+
+	model, ok := fastModeModel(p.modelRegistry.GetEnabledModels())
+	if !ok {
+		return p.handleFailedAttempts(config, StrategyFirst, startTime, nil, nil, nil)
+	}
+
+	model.Timeout = attemptTimeout(ctx, model, requestDeadline(config, startTime), 1)
+	if model.Timeout <= 0 {
+		return p.handleFailedAttempts(config, StrategyFirst, startTime, nil, nil, ErrBudgetExhausted)
+	}
+
+	result, modelResult, err := p.callModel(ctx, model, req.Text)
+	modelResults := []ModelResult{modelResult}
+	if err != nil {
+		return p.handleFailedAttempts(config, StrategyFirst, startTime, []string{model.Name}, modelResults, err)
+	}
+
+	response := p.buildResponse(mergeFastModeResult(result, heuristic), config, time.Since(startTime), model.Name)
+	if config.DetailedResponse {
+		response.ModelResults = modelResults
+		response.Strategy = StrategyFirst
+	}
+	p.recordDetection(model.Name, response, time.Since(startTime))
+	return response, nil
+}
+
+// SetDisagreementThreshold sets the consensus score spread above which high_disagreement is reported
+func (p *FallbackPipeline) SetDisagreementThreshold(threshold float64) {
+	p.disagreementThreshold = threshold
+}
+
+// SetTieBreaker designates the model that decides consensus votes that split evenly
+// or disagree beyond the disagreement threshold. It does not vote itself and may be
+// disabled so that no other strategy calls it.
+func (p *FallbackPipeline) SetTieBreaker(name string) {
+	p.tieBreaker = name
+}
+
+// SetMinBenignVotes requires at least n models to answer and vote benign before an
+// input is reported safe; requests run in consensus and fail closed otherwise
+func (p *FallbackPipeline) SetMinBenignVotes(n int) {
+	p.minBenignVotes = n
+}
+
+// SetSeverityThresholds sets the minimum score for each response severity
+func (p *FallbackPipeline) SetSeverityThresholds(thresholds SeverityThresholds) {
+	p.severity = thresholds
+}
+
+// SetHealthProber makes the prober check every enabled model and report its
+// reachability in health and diagnostics; unreachable models count as unavailable
+func (p *FallbackPipeline) SetHealthProber(prober *HealthProber) {
+	p.prober = prober
+	if prober == nil {
+		return
+	}
+	prober.setTargets(func() []probeTarget {
+		enabledModels := p.modelRegistry.GetEnabledModels()
+		targets := make([]probeTarget, 0, len(enabledModels))
+		for _, model := range enabledModels {
+			model := model
+			targets = append(targets, probeTarget{
+				name: model.Name,
+				probe: func(ctx context.Context) error {
+					_, err := p.detectWithModel(ctx, model, probeText)
+					return err
+				},
+			})
+		}
+		return targets
+	})
+}
+
+// SetFailureMode chooses whether all-models-failed classifies the input as safe (open) or malicious (closed)
+func (p *FallbackPipeline) SetFailureMode(mode FailureMode) {
+	p.failureMode = mode
+}
+
+// SetLocalOnly switches the pipeline between model fallback and local heuristic detection
+func (p *FallbackPipeline) SetLocalOnly(localOnly bool) {
+	p.localOnly = localOnly
+	if localOnly {
+		p.logger.Info("Local-only detection mode enabled - models will not be called")
+	}
+}
+
+// SetBreakerStore shares circuit breaker state with other instances through store
+func (p *FallbackPipeline) SetBreakerStore(store BreakerStateStore) {
+	p.breakersMutex.Lock()
+	defer p.breakersMutex.Unlock()
+
+	p.breakerStore = store
+	for _, cb := range p.circuitBreakers {
+		cb.SetStateStore(store)
+	}
+}
+
+// SetRecentDetections enables capture of recent detections for GET /v1/detections/recent
+func (p *FallbackPipeline) SetRecentDetections(recent *RecentDetections) {
+	p.recent = recent
+}
+
+// RecentDetections returns the recent detection log, nil when capture is disabled
+func (p *FallbackPipeline) RecentDetections() *RecentDetections {
+	return p.recent
+}
+
+// SetRedactor sets the patterns masked in text the pipeline stores, such as sink records
+func (p *FallbackPipeline) SetRedactor(redactor *redact.Redactor) {
+	p.redactor = redactor
+}
+
+// SetResultSink persists a record of every completed detection to sink, with the
+// analyzed text only when includeText is set. Wrap slow sinks in an AsyncSink so
+// recording never delays the response.
+func (p *FallbackPipeline) SetResultSink(sink ResultSink, includeText bool) {
+	p.resultSink = sink
+	p.recordText = includeText
+}
+
+// SetNotifier registers a receiver for every completed detection
+func (p *FallbackPipeline) SetNotifier(notifier DetectionNotifier) {
+	p.notifier = notifier
+}
+
+// RegisterProvider routes models of the given provider to detector, so new providers
+// can be added without changing the pipeline
+func (p *FallbackPipeline) RegisterProvider(provider ModelProvider, detector Detector) {
+	p.providers.Register(provider, detector)
+}
+
+// SetCostBudget caps daily spend on paid models; once reached, only free models are called
+func (p *FallbackPipeline) SetCostBudget(budget *CostBudget) {
+	p.costBudget = budget
+}
+
+// SetResultCache enables caching of detection responses for repeated text
+func (p *FallbackPipeline) SetResultCache(cache ResultCache) {
+	p.resultCache = cache
+}
+
+// SetSessionTTL replaces the session store with one whose idle sessions expire after ttl
+func (p *FallbackPipeline) SetSessionTTL(ttl time.Duration) {
+	p.sessions = NewSessionStore(ttl)
+}
+
+// AnalyzeOutput scans model-generated output for leaked system prompts, canaries and secrets
+func (p *FallbackPipeline) AnalyzeOutput(ctx context.Context, req *OutputDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.outputScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime), localEndpointName)
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.recordDetection(localEndpointName, response, time.Since(startTime))
+
+	return response, nil
+}
+
+// AnalyzeDocument scans untrusted document content, such as retrieved RAG context, for embedded instructions
+func (p *FallbackPipeline) AnalyzeDocument(ctx context.Context, req *DocumentDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.documentScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime), localEndpointName)
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.recordDetection(localEndpointName, response, time.Since(startTime))
+
+	return response, nil
+}
+
+// AnalyzeToolCall scans the arguments of a model-chosen tool call for injection and path traversal
+func (p *FallbackPipeline) AnalyzeToolCall(ctx context.Context, req *ToolCallDetectionRequest) (*DetectionResponse, error) {
+	startTime := time.Now()
+
+	config := p.applyConfig(req.Config)
+	result := p.toolCallScanner.Scan(req)
+
+	response := p.buildResponse(result, config, time.Since(startTime), localEndpointName)
+	response.Severity = p.severity.withDefaults().classify(response)
+	p.recordDetection(localEndpointName, response, time.Since(startTime))
+
+	return response, nil
+}
+
+// Readiness reports whether the pipeline can currently serve detections, with a short reason
+func (p *FallbackPipeline) Readiness() (bool, string) {
+	if p.localOnly {
+		return true, "local detection active"
+	}
+	health := p.GetHealth()
+	if !health.APIKeyConfigured {
+		return false, "no API key configured"
+	}
+	if health.ModelsAvailable == 0 {
+		return false, "no model circuit breaker is closed or half-open"
+	}
+	return true, fmt.Sprintf("%d of %d models available", health.ModelsAvailable, health.TotalModels)
+}
+
+// DispatchSlots returns the outbound model call slots in use and the total available (0 means unlimited)
+func (p *FallbackPipeline) DispatchSlots() (int, int) {
+	return p.llmDetector.DispatchSlots()
+}
+
+// useLocalDetection reports whether a request should be served by local detection
+func (p *FallbackPipeline) useLocalDetection(config *DetectionConfig) bool {
+	if config.LocalOnly != nil {
+		return *config.LocalOnly
+	}
+	return p.localOnly
+}
+
+// IsAvailable reports whether the pipeline can produce real detections
+func (p *FallbackPipeline) IsAvailable() bool {
+	return p.localOnly || p.llmDetector.IsAvailable()
+}
+
+// callModel runs a single model through its circuit breaker and captures the attempt
+func (p *FallbackPipeline) callModel(ctx context.Context, model ModelConfig, text string) (*DetectionResult, ModelResult, error) {
+	// Provider calls run under the span's context so their HTTP requests join the trace
+	ctx, span := tracer.Start(ctx, "model.call", trace.WithAttributes(
+		attribute.String("model.name", model.Name),
+		attribute.String("model.provider", string(model.Provider)),
+	))
+
+	if reason, misconfigured := p.getMisconfiguration(model.Name); misconfigured {
+		err := &ProviderError{Category: ErrorCategoryAuth, Message: "model skipped as misconfigured: " + reason}
+		endModelSpan(span, err)
+		return nil, newModelResult(model.Name, nil, err, 0), err
+	}
+	if until, exhausted := p.quotaDisabledUntil(model.Name); exhausted {
+		err := &ProviderError{Category: ErrorCategoryQuota, Message: "model skipped until " + until.Format(time.RFC3339) + " after quota exhaustion"}
+		endModelSpan(span, err)
+		return nil, newModelResult(model.Name, nil, err, 0), err
+	}
+
+	if model.CostPerRequest > 0 && p.costBudget.Exhausted() {
+		err := ErrCostBudgetExceeded
+		endModelSpan(span, err)
+		return nil, newModelResult(model.Name, nil, err, 0), err
+	}
+
+	circuitBreaker := p.ensureCircuitBreaker(model)
+	span.SetAttributes(attribute.String("circuit.state", circuitBreaker.GetStateName()))
+
+	p.logger.WithFields(logrus.Fields{
+		"model": model.Name,
+		"state": circuitBreaker.GetStateName(),
+	}).Debug("Attempting model detection")
+
+	var result *DetectionResult
+	modelStart := time.Now()
+	err := circuitBreaker.Call(func() error {
+		var detectionErr error
+		result, detectionErr = p.detectWithModel(ctx, model, text)
+		return detectionErr
+	})
+
+	if err != nil {
+		category := ErrorCategoryOf(err)
+		p.logger.WithFields(logrus.Fields{
+			"model":          model.Name,
+			"error_category": category,
+		}).Debug("Model call failed")
+
+		switch category {
+		case ErrorCategoryAuth:
+			p.markMisconfigured(model.Name, err)
+		case ErrorCategoryQuota:
+			p.markQuotaExhausted(model, err)
+		}
+	} else {
+		p.costBudget.Record(model.CostPerRequest)
+	}
+
+	endModelSpan(span, err)
+	return result, newModelResult(model.Name, result, err, time.Since(modelStart)), err
+}
+
+// markMisconfigured records that a model failed authentication so it is skipped
+func (p *FallbackPipeline) markMisconfigured(modelName string, err error) {
+	p.misconfiguredMutex.Lock()
+	defer p.misconfiguredMutex.Unlock()
+
+	p.misconfiguredModels[modelName] = err.Error()
+	p.logger.WithFields(logrus.Fields{
+		"model": modelName,
+		"error": err.Error(),
+	}).Error("Model authentication failed - marking as misconfigured")
+}
+
+// getMisconfiguration returns the recorded auth failure for a model, if any
+func (p *FallbackPipeline) getMisconfiguration(modelName string) (string, bool) {
+	p.misconfiguredMutex.RLock()
+	defer p.misconfiguredMutex.RUnlock()
+
+	reason, exists := p.misconfiguredModels[modelName]
+	return reason, exists
+}
+
+// getMisconfiguredModels returns a snapshot of all misconfigured models
+func (p *FallbackPipeline) getMisconfiguredModels() map[string]string {
+	p.misconfiguredMutex.RLock()
+	defer p.misconfiguredMutex.RUnlock()
+
+	snapshot := make(map[string]string, len(p.misconfiguredModels))
+	for name, reason := range p.misconfiguredModels {
+		snapshot[name] = reason
+	}
+	return snapshot
+}
+
+// recordDetection records internal and Prometheus metrics for a completed detection
+func (p *FallbackPipeline) recordDetection(modelName string, response *DetectionResponse, duration time.Duration) {
+	p.metrics.RecordSuccess(duration, response)
+
+	resultType := "benign"
+	if response.IsMalicious {
+		resultType = "malicious"
+	}
+	p.metricsCollector.RecordDetectionRequest(
+		modelName,
+		resultType,
+		response.ThreatTypes,
+		duration,
+	)
+}
+
+// handleFailedAttempts records the failure and builds the response when no model answered
+func (p *FallbackPipeline) handleFailedAttempts(config *DetectionConfig, strategy AggregationStrategy, startTime time.Time, attemptedModels []string, modelResults []ModelResult, lastError error) (*DetectionResponse, error) {
 	// All models failed - record failure and return service unavailable error
 	p.metrics.RecordFailure(time.Since(startTime))
-	
+
+	lastErrorMessage := "no models attempted"
+	if lastError != nil {
+		lastErrorMessage = lastError.Error()
+	}
+
 	p.logger.WithFields(logrus.Fields{
 		"attempted_models": attemptedModels,
-		"last_error":       lastError.Error(),
+		"strategy":         strategy,
+		"last_error":       lastErrorMessage,
 		"duration_ms":      time.Since(startTime).Milliseconds(),
 	}).Error("All detection models failed")
 
-	return p.handleAllModelsFailed(startTime, attemptedModels), ErrAllModelsFailed
+	response := p.handleAllModelsFailed(startTime, attemptedModels)
+	err := ErrAllModelsFailed
+	if errors.Is(lastError, ErrCostBudgetExceeded) {
+		// Paid models were skipped for cost and no free model could answer
+		_, _, classification := p.failureMode.verdict()
+		response.Reason = fmt.Sprintf("Daily cost budget exhausted and no free model available (tried: %v) - returning %s", attemptedModels, classification)
+		response.Endpoint = "budget_exhausted"
+		err = ErrCostBudgetExceeded
+	}
+	if config.DetailedResponse {
+		response.ModelResults = modelResults
+		response.Strategy = strategy
+	}
+
+	return p.failureMode.settle(response, err)
 }
 
-// detectWithModel performs detection using a specific model
-func (p *FallbackPipeline) detectWithModel(model ModelConfig, text string) (*DetectionResult, error) {
-	// For now, we'll use the existing LLMDetector but we can extend this
-	// to support different model types (OpenAI, Anthropic, etc.) later
-	
-	// Create a temporary detector for this specific model
-	// This is a simplified approach - in a full implementation, we'd have
-	// specific handlers for each provider type
-	
-	switch model.Provider {
-	case ProviderHuggingFace:
-		return p.llmDetector.detectWithSpecificEndpoint(text, model)
-	case ProviderGoogle:
-		return p.llmDetector.detectWithSpecificEndpoint(text, model)
-	case ProviderOpenRouter:
-		return p.llmDetector.detectWithSpecificEndpoint(text, model)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", model.Provider)
+// newModelResult converts a single model attempt into its detailed-response form
+func newModelResult(modelName string, result *DetectionResult, err error, latency time.Duration) ModelResult {
+	modelResult := ModelResult{
+		Model:       modelName,
+		ThreatTypes: []string{},
+		LatencyMs:   latency.Milliseconds(),
 	}
+
+	if err != nil {
+		modelResult.Error = err.Error()
+		modelResult.ErrorCategory = string(ErrorCategoryOf(err))
+		return modelResult
+	}
+
+	if result != nil {
+		modelResult.Score = result.Score
+		modelResult.Reason = result.Reason
+		for _, threat := range result.ThreatTypes {
+			modelResult.ThreatTypes = append(modelResult.ThreatTypes, string(threat))
+		}
+	}
+
+	return modelResult
+}
+
+// detectWithModel performs detection using the detector registered for the model's provider
+func (p *FallbackPipeline) detectWithModel(ctx context.Context, model ModelConfig, text string) (*DetectionResult, error) {
+	return p.providers.Detect(ctx, text, model)
 }
 
 // handleEmptyInput returns appropriate response for empty input
@@ -213,14 +787,15 @@ func (p *FallbackPipeline) handleEmptyInput(startTime time.Time) *DetectionRespo
 	}
 }
 
-// handleAllModelsFailed returns response when all models are unavailable
+// handleAllModelsFailed returns the failure-mode verdict when all models are unavailable
 func (p *FallbackPipeline) handleAllModelsFailed(startTime time.Time, attemptedModels []string) *DetectionResponse {
+	isMalicious, confidence, classification := p.failureMode.verdict()
 	return &DetectionResponse{
-		IsMalicious:      false, // Conservative: assume safe when unsure
-		Confidence:       0.5,   // Uncertain confidence
+		IsMalicious:      isMalicious,
+		Confidence:       confidence,
 		ThreatTypes:      []string{},
 		ProcessingTimeMs: time.Since(startTime).Milliseconds(),
-		Reason:           fmt.Sprintf("All detection models unavailable (tried: %v) - returning safe classification", attemptedModels),
+		Reason:           fmt.Sprintf("All detection models unavailable (tried: %v) - returning %s", attemptedModels, classification),
 		Endpoint:         "fallback_failed",
 	}
 }
@@ -233,13 +808,8 @@ func (p *FallbackPipeline) buildResponse(result *DetectionResult, config *Detect
 		threatTypes[i] = string(threat)
 	}
 
-	// Determine if malicious based on threshold
-	threshold := config.ConfidenceThreshold
-	if threshold == 0 {
-		threshold = p.confidenceThreshold
-	}
-
-	isMalicious := result.Score >= threshold
+	// Determine if malicious based on per-threat and global thresholds
+	isMalicious := exceedsThreshold(result.Score, threatTypes, config, p.confidenceThreshold)
 
 	return &DetectionResponse{
 		IsMalicious:      isMalicious,
@@ -248,19 +818,34 @@ func (p *FallbackPipeline) buildResponse(result *DetectionResult, config *Detect
 		ProcessingTimeMs: duration.Milliseconds(),
 		Reason:           result.Reason,
 		Endpoint:         modelUsed,
+		Matches:          result.Matches,
 	}
 }
 
 // applyConfig applies request-specific configuration with defaults
-func (p *FallbackPipeline) applyConfig(config *DetectionConfig) *DetectionConfig {
-	if config == nil {
-		config = &DetectionConfig{}
+func (p *FallbackPipeline) applyConfig(requested *DetectionConfig) *DetectionConfig {
+	// Work on a copy: the caller's config may be shared by concurrent requests
+	config := &DetectionConfig{}
+	if requested != nil {
+		*config = *requested
 	}
 
 	// Set defaults if not specified
 	if config.ConfidenceThreshold == 0 {
 		config.ConfidenceThreshold = p.confidenceThreshold
 	}
+	if config.MinBenignVotes == 0 {
+		config.MinBenignVotes = p.minBenignVotes
+	}
+	resolveMode(config)
+
+	// Benign votes are only counted in consensus, so a requirement overrides other strategies
+	if config.MinBenignVotes > 0 {
+		config.Strategy = StrategyConsensus
+		if config.Mode == ModeFast {
+			config.Mode = ModeBalanced
+		}
+	}
 
 	return config
 }
@@ -275,28 +860,43 @@ func (p *FallbackPipeline) GetHealth() *HealthStatus {
 	enabledModels := p.modelRegistry.GetEnabledModels()
 	modelStatuses := make(map[string]CircuitBreakerStats)
 	
+	misconfiguredModels := p.getMisconfiguredModels()
+	quotaExhausted := p.getQuotaExhaustedModels()
+	circuitBreakers := p.circuitBreakerSnapshot()
+
 	healthyModels := 0
 	for _, model := range enabledModels {
-		if cb, exists := p.circuitBreakers[model.Name]; exists {
+		if cb, exists := circuitBreakers[model.Name]; exists {
 			stats := cb.GetStats()
 			modelStatuses[model.Name] = stats
-			if !stats.IsOpen {
+			_, misconfigured := misconfiguredModels[model.Name]
+			_, outOfQuota := quotaExhausted[model.Name]
+			if !stats.IsOpen && !misconfigured && !outOfQuota && !p.prober.unreachable(model.Name) {
 				healthyModels++
 			}
 		}
 	}
 
-	// Determine overall status
+	var costBudget *CostBudgetStatus
+	if p.costBudget != nil {
+		status := p.costBudget.Status()
+		costBudget = &status
+	}
+
+	// Determine overall status; model availability is irrelevant in local-only mode
 	status := "healthy"
-	if healthyModels == 0 {
+	switch {
+	case p.localOnly:
+		// Local detection serves every request
+	case healthyModels == 0:
 		status = "critical - all models unavailable"
-	} else if healthyModels < len(enabledModels) {
+	case healthyModels < len(enabledModels):
 		status = "degraded - some models unavailable"
 	}
 
 	return &HealthStatus{
 		Status:           status,
-		Version:          "3.0.0-circuit-breaker-fallback",
+		Version:          version.Version,
 		Uptime:           time.Since(p.startTime),
 		RequestsServed:   p.metrics.GetRequestsTotal(),
 		AverageLatency:   p.metrics.GetAverageLatency(),
@@ -304,6 +904,13 @@ func (p *FallbackPipeline) GetHealth() *HealthStatus {
 		TotalModels:      len(enabledModels),
 		CircuitBreakers:  modelStatuses,
 		APIKeyConfigured: p.llmDetector.IsAvailable(),
+		LocalDetection:   p.localOnly,
+
+		MisconfiguredModels: misconfiguredModels,
+		QuotaExhaustedUntil: quotaExhausted,
+		CostBudget:          costBudget,
+
+		Probes: p.prober.Results(),
 	}
 }
 
@@ -311,7 +918,7 @@ func (p *FallbackPipeline) GetHealth() *HealthStatus {
 func (p *FallbackPipeline) GetCircuitBreakerStats() map[string]CircuitBreakerStats {
 	stats := make(map[string]CircuitBreakerStats)
 	
-	for name, cb := range p.circuitBreakers {
+	for name, cb := range p.circuitBreakerSnapshot() {
 		stats[name] = cb.GetStats()
 	}
 	
@@ -324,7 +931,7 @@ func (p *FallbackPipeline) updateCircuitBreakerMetrics() {
 	defer ticker.Stop()
 	
 	for range ticker.C {
-		for modelName, cb := range p.circuitBreakers {
+		for modelName, cb := range p.circuitBreakerSnapshot() {
 			// Record current circuit breaker state
 			stateInt := metrics.CircuitBreakerStateToInt(cb.GetStateName())
 			p.metricsCollector.RecordCircuitBreakerState(modelName, stateInt)
@@ -344,10 +951,137 @@ func (p *FallbackPipeline) updateCircuitBreakerMetrics() {
 
 // ResetCircuitBreaker manually resets a specific circuit breaker
 func (p *FallbackPipeline) ResetCircuitBreaker(modelName string) error {
-	if cb, exists := p.circuitBreakers[modelName]; exists {
+	p.breakersMutex.RLock()
+	cb, exists := p.circuitBreakers[modelName]
+	p.breakersMutex.RUnlock()
+
+	if exists {
 		cb.Reset()
+
+		// Resetting also gives a misconfigured or out-of-quota model another chance
+		p.misconfiguredMutex.Lock()
+		delete(p.misconfiguredModels, modelName)
+		p.misconfiguredMutex.Unlock()
+
+		p.quotaMutex.Lock()
+		delete(p.quotaExhausted, modelName)
+		p.quotaMutex.Unlock()
+
 		p.logger.WithField("model", modelName).Info("Circuit breaker manually reset")
 		return nil
 	}
 	return fmt.Errorf("circuit breaker for model %s not found", modelName)
-}
\ No newline at end of file
+}
+// ModelStatus describes a registered model together with its live runtime state
+type ModelStatus struct {
+	ModelConfig
+	CircuitState  string `json:"circuit_state,omitempty"`
+	Misconfigured string `json:"misconfigured,omitempty"`
+
+	// Set while the model is skipped after exhausting its quota
+	DisabledUntil *time.Time `json:"disabled_until,omitempty"`
+}
+
+// ListModels returns every registered model with its circuit breaker state
+func (p *FallbackPipeline) ListModels() []ModelStatus {
+	models := p.modelRegistry.GetAllModels()
+	circuitBreakers := p.circuitBreakerSnapshot()
+	misconfiguredModels := p.getMisconfiguredModels()
+	quotaExhausted := p.getQuotaExhaustedModels()
+
+	statuses := make([]ModelStatus, 0, len(models))
+	for _, model := range models {
+		status := ModelStatus{
+			ModelConfig:   model,
+			Misconfigured: misconfiguredModels[model.Name],
+		}
+		if until, exhausted := quotaExhausted[model.Name]; exhausted {
+			status.DisabledUntil = &until
+		}
+		if cb, exists := circuitBreakers[model.Name]; exists {
+			status.CircuitState = cb.GetStateName()
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// ModelMetrics is the per-model load and cost breakdown reported by /v1/metrics
+type ModelMetrics struct {
+	Name               string  `json:"name"`
+	Provider           string  `json:"provider"`
+	TotalRequests      int64   `json:"total_requests"`
+	SuccessfulRequests int64   `json:"successful_requests"`
+	FailedRequests     int64   `json:"failed_requests"`
+	SuccessRate        float64 `json:"success_rate"`
+	EstimatedCost      float64 `json:"estimated_cost_usd"` // TotalRequests x CostPerRequest
+}
+
+// GetModelMetrics returns request counts, success rate and estimated cost for
+// every model that has a circuit breaker, in registry order
+func (p *FallbackPipeline) GetModelMetrics() []ModelMetrics {
+	circuitBreakers := p.circuitBreakerSnapshot()
+
+	modelMetrics := make([]ModelMetrics, 0, len(circuitBreakers))
+	for _, model := range p.modelRegistry.GetAllModels() {
+		cb, exists := circuitBreakers[model.Name]
+		if !exists {
+			continue
+		}
+
+		stats := cb.GetStats()
+		modelMetrics = append(modelMetrics, ModelMetrics{
+			Name:               model.Name,
+			Provider:           string(model.Provider),
+			TotalRequests:      stats.TotalRequests,
+			SuccessfulRequests: stats.SuccessfulRequests,
+			FailedRequests:     stats.FailedRequests,
+			SuccessRate:        stats.SuccessRate,
+			EstimatedCost:      float64(stats.TotalRequests) * model.CostPerRequest,
+		})
+	}
+
+	return modelMetrics
+}
+
+// UpdateModel toggles a model and/or changes its priority at runtime.
+// Enabling a model creates its circuit breaker if it does not exist yet.
+func (p *FallbackPipeline) UpdateModel(name string, enabled *bool, priority *int) (ModelConfig, error) {
+	model, err := p.modelRegistry.GetModelByName(name)
+	if err != nil {
+		return ModelConfig{}, err
+	}
+
+	if priority != nil {
+		if err := p.modelRegistry.UpdateModelPriority(name, *priority); err != nil {
+			return ModelConfig{}, err
+		}
+	}
+
+	if enabled != nil {
+		if *enabled {
+			// Create the breaker before the model becomes visible to Analyze
+			p.ensureCircuitBreaker(model)
+			err = p.modelRegistry.EnableModel(name)
+		} else {
+			err = p.modelRegistry.DisableModel(name)
+		}
+		if err != nil {
+			return ModelConfig{}, err
+		}
+	}
+
+	updated, err := p.modelRegistry.GetModelByName(name)
+	if err != nil {
+		return ModelConfig{}, err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"model":    name,
+		"enabled":  updated.Enabled,
+		"priority": updated.Priority,
+	}).Info("Model configuration updated at runtime")
+
+	return updated, nil
+}
@@ -0,0 +1,127 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// fakeModel is a model served by the fake provider: it answers with score and
+// threats, or fails with err when set
+type fakeModel struct {
+	name    string
+	score   float64
+	threats []ThreatType
+	err     error
+	cost    float64 // CostPerRequest of the model, 0 for a free one
+}
+
+// newFakeProviderPipeline builds a fallback pipeline whose models, tried in the
+// given order, are answered by the fake provider
+func newFakeProviderPipeline(t *testing.T, fakes ...fakeModel) *FallbackPipeline {
+	t.Helper()
+
+	byName := make(map[string]fakeModel, len(fakes))
+	models := make([]ModelConfig, len(fakes))
+	for i, fake := range fakes {
+		byName[fake.name] = fake
+		models[i] = testModel(fake.name, providerFake, "")
+		models[i].Priority = i + 1
+		models[i].CostPerRequest = fake.cost
+	}
+
+	pipeline := newTestFallbackPipeline(t, models...)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		fake := byName[model.Name]
+		if fake.err != nil {
+			return nil, fake.err
+		}
+		return &DetectionResult{
+			Method:      MethodLLM,
+			Score:       fake.score,
+			ThreatTypes: fake.threats,
+			Reason:      "fake verdict from " + fake.name,
+			Endpoint:    fake.name,
+		}, nil
+	}))
+	return pipeline
+}
+
+func TestDetailedResponseModelResults(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "broken", err: &ProviderError{Category: ErrorCategoryServer, Message: "upstream failure"}},
+		fakeModel{name: "answering", score: 0.9, threats: []ThreatType{ThreatTypeJailbreak}},
+	)
+
+	// The detailed request goes first: the failure opens the broken model's breaker
+	detailed, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{DetailedResponse: true},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if detailed.Strategy != StrategyFirst {
+		t.Errorf("Strategy = %q, want %q", detailed.Strategy, StrategyFirst)
+	}
+	if len(detailed.ModelResults) != 2 {
+		t.Fatalf("got %d model results, want one per attempted model: %+v", len(detailed.ModelResults), detailed.ModelResults)
+	}
+	if failed := detailed.ModelResults[0]; failed.Model != "broken" || failed.Error == "" || failed.ErrorCategory != string(ErrorCategoryServer) {
+		t.Errorf("first model result = %+v, want the broken model's server error", failed)
+	}
+	answered := detailed.ModelResults[1]
+	if answered.Model != "answering" || answered.Score != 0.9 || answered.Error != "" {
+		t.Errorf("second model result = %+v, want the answering model's 0.9 vote", answered)
+	}
+	if len(answered.ThreatTypes) != 1 || answered.ThreatTypes[0] != string(ThreatTypeJailbreak) {
+		t.Errorf("second model threat types = %v, want [jailbreak]", answered.ThreatTypes)
+	}
+
+	plain, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "what is the capital of France"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if plain.ModelResults != nil || plain.Strategy != "" {
+		t.Errorf("plain response has model results %v and strategy %q, want neither", plain.ModelResults, plain.Strategy)
+	}
+	body, err := json.Marshal(plain)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(body), "model_results") {
+		t.Errorf("plain response JSON contains model_results: %s", body)
+	}
+}
+
+func TestFallbackPipelineSkipsMisconfiguredModel(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "bad-key", err: &ProviderError{Category: ErrorCategoryAuth, StatusCode: 401, Message: "invalid api key"}},
+		fakeModel{name: "answering", score: 0.1},
+	)
+
+	for i := 0; i < 2; i++ {
+		response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+			Text:   "what is the capital of France",
+			Config: &DetectionConfig{DetailedResponse: true},
+		})
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		if response.Endpoint != "answering" {
+			t.Errorf("Endpoint = %q, want the fallback model", response.Endpoint)
+		}
+		if got := response.ModelResults[0].ErrorCategory; got != string(ErrorCategoryAuth) {
+			t.Errorf("request %d: bad-key error category = %q, want auth", i, got)
+		}
+	}
+
+	// An auth failure marks the model misconfigured instead of tripping its breaker
+	if state := pipeline.circuitBreakerSnapshot()["bad-key"].GetState(); state != CircuitClosed {
+		t.Errorf("bad-key breaker state = %v, want closed", state)
+	}
+	if _, misconfigured := pipeline.GetHealth().MisconfiguredModels["bad-key"]; !misconfigured {
+		t.Error("health does not report bad-key as misconfigured")
+	}
+}
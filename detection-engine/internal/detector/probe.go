@@ -0,0 +1,196 @@
+package detector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// probeText is the trivial classification health probes send to each endpoint
+const probeText = "Hello, how are you today?"
+
+// ProbeResult is the outcome of the latest health probe of one endpoint
+type ProbeResult struct {
+	Reachable bool      `json:"reachable"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// probeTarget is an endpoint the prober checks, named as health reports name it
+type probeTarget struct {
+	name  string
+	probe func(ctx context.Context) error
+}
+
+// HealthProber periodically sends a trivial classification to every endpoint
+// and caches whether it answered, so health reports reflect real reachability
+// instead of whether a key is configured
+type HealthProber struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	targets func() []probeTarget
+	results map[string]ProbeResult
+	mutex   sync.RWMutex
+
+	started  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewHealthProber creates a prober checking endpoints every interval, failing
+// probes that take longer than timeout. Probing starts once a pipeline has set
+// its targets and Start is called.
+func NewHealthProber(interval, timeout time.Duration) *HealthProber {
+	return &HealthProber{
+		interval: interval,
+		timeout:  timeout,
+		results:  make(map[string]ProbeResult),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// setTargets sets the function listing the endpoints to probe
+func (h *HealthProber) setTargets(targets func() []probeTarget) {
+	h.targets = targets
+}
+
+// Start probes every endpoint immediately and then every interval until Stop is called
+func (h *HealthProber) Start() {
+	if h == nil || h.interval <= 0 || h.targets == nil || !h.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			h.probeAll()
+			select {
+			case <-ticker.C:
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background probing and waits for the probe loop to exit
+func (h *HealthProber) Stop() {
+	if h == nil {
+		return
+	}
+	h.stopOnce.Do(func() {
+		close(h.stop)
+		if h.started.Load() {
+			<-h.done
+		}
+	})
+}
+
+// Results returns the latest probe result per endpoint, nil before the first round
+func (h *HealthProber) Results() map[string]ProbeResult {
+	if h == nil {
+		return nil
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if len(h.results) == 0 {
+		return nil
+	}
+	results := make(map[string]ProbeResult, len(h.results))
+	for name, result := range h.results {
+		results[name] = result
+	}
+	return results
+}
+
+// unreachable reports whether the latest probe of the named endpoint failed;
+// endpoints not yet probed are assumed reachable
+func (h *HealthProber) unreachable(name string) bool {
+	if h == nil {
+		return false
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	result, probed := h.results[name]
+	return probed && !result.Reachable
+}
+
+// probeAll probes every target concurrently and replaces the cached results
+func (h *HealthProber) probeAll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	targets := h.targets()
+	results := make(map[string]ProbeResult, len(targets))
+	var resultsMutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target probeTarget) {
+			defer wg.Done()
+			result := h.probeOne(ctx, target)
+			resultsMutex.Lock()
+			results[target.name] = result
+			resultsMutex.Unlock()
+		}(target)
+	}
+	wg.Wait()
+
+	h.mutex.Lock()
+	h.results = results
+	h.mutex.Unlock()
+}
+
+// probeOne sends the probe classification to one target and times it
+func (h *HealthProber) probeOne(ctx context.Context, target probeTarget) ProbeResult {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	// Some providers apply only their own timeout, so stop waiting once ctx is done
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() { errs <- target.probe(ctx) }()
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := ProbeResult{
+		Reachable: err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// probeEndpoint sends the probe classification to one endpoint, bypassing the
+// dispatch limit and endpoint stats so probes neither queue behind nor skew traffic
+func (l *LLMDetector) probeEndpoint(ctx context.Context, endpoint LLMEndpoint) error {
+	_, err := l.callWithKeys(ctx, endpoint, probeText)
+	return err
+}
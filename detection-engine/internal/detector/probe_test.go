@@ -0,0 +1,173 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchableHandler answers SAFE until down is set, and 500 while it is
+func switchableHandler(down *atomic.Bool) func(http.ResponseWriter, *http.Request, int32) {
+	return func(w http.ResponseWriter, r *http.Request, call int32) {
+		io.Copy(io.Discard, r.Body)
+		if down.Load() {
+			http.Error(w, `{"error":"endpoint down"}`, http.StatusInternalServerError)
+			return
+		}
+		writeHuggingFaceLabel(w, "SAFE", 0.99)
+	}
+}
+
+// newStartedProber returns a prober checking every 10ms that is stopped when the test ends
+func newStartedProber(t *testing.T, configure func(*HealthProber)) *HealthProber {
+	t.Helper()
+
+	prober := NewHealthProber(10*time.Millisecond, time.Second)
+	configure(prober)
+	prober.Start()
+	t.Cleanup(prober.Stop)
+	return prober
+}
+
+// waitForHealth polls health until it reports want or the test times out
+func waitForHealth(t *testing.T, health func() *HealthStatus, want string) *HealthStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := health()
+		if status.Status == want && len(status.Probes) > 0 {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health stuck at %q with probes %+v, want %q", status.Status, status.Probes, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHealthProbeFlipsPipelineToDegraded(t *testing.T) {
+	var down atomic.Bool
+	stable, _ := newFakeHuggingFaceServer(t, switchableHandler(&atomic.Bool{}))
+	flaky, _ := newFakeHuggingFaceServer(t, switchableHandler(&down))
+	pipeline := NewPipelineWithDetector(newTestLogger(), newTestLLMDetector(t,
+		testModel("stable-classifier", ProviderHuggingFace, stable.URL),
+		testModel("flaky-classifier", ProviderHuggingFace, flaky.URL),
+	))
+	newStartedProber(t, pipeline.SetHealthProber)
+
+	healthy := waitForHealth(t, pipeline.GetHealth, "healthy")
+	if probe := healthy.Probes["flaky-classifier"]; !probe.Reachable || probe.CheckedAt.IsZero() {
+		t.Errorf("flaky-classifier probe = %+v, want reachable", probe)
+	}
+
+	down.Store(true)
+	degraded := waitForHealth(t, pipeline.GetHealth, "degraded - some LLM endpoints unreachable")
+	if probe := degraded.Probes["flaky-classifier"]; probe.Reachable || probe.Error == "" {
+		t.Errorf("flaky-classifier probe = %+v, want unreachable with its error", probe)
+	}
+	if probe := degraded.Probes["stable-classifier"]; !probe.Reachable {
+		t.Errorf("stable-classifier probe = %+v, want reachable", probe)
+	}
+
+	diagnostic := pipeline.DiagnoseLLMEndpoints()
+	statuses := make(map[string]any)
+	for key, value := range diagnostic {
+		if endpoint, ok := value.(map[string]interface{}); ok && strings.HasPrefix(key, "endpoint_") {
+			statuses[endpoint["model"].(string)] = endpoint["status"]
+			if _, probed := endpoint["probe"]; !probed {
+				t.Errorf("%s diagnostic has no probe result", key)
+			}
+		}
+	}
+	if statuses["flaky-classifier"] != "unreachable" || statuses["stable-classifier"] != "available" {
+		t.Errorf("diagnosed statuses = %v, want flaky-classifier unreachable and stable-classifier available", statuses)
+	}
+
+	down.Store(false)
+	waitForHealth(t, pipeline.GetHealth, "healthy")
+}
+
+func TestHealthProbeFlipsFallbackPipelineToDegraded(t *testing.T) {
+	var down atomic.Bool
+	models := []ModelConfig{testModel("stable", providerFake, ""), testModel("flaky", providerFake, "")}
+	models[1].Priority = 2
+	pipeline := newTestFallbackPipeline(t, models...)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		if model.Name == "flaky" && down.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return &DetectionResult{Method: MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+	}))
+	newStartedProber(t, pipeline.SetHealthProber)
+
+	healthy := waitForHealth(t, pipeline.GetHealth, "healthy")
+	if healthy.ModelsAvailable != 2 {
+		t.Errorf("ModelsAvailable = %d while both models answer, want 2", healthy.ModelsAvailable)
+	}
+
+	down.Store(true)
+	degraded := waitForHealth(t, pipeline.GetHealth, "degraded - some models unavailable")
+	if degraded.ModelsAvailable != 1 {
+		t.Errorf("ModelsAvailable = %d with flaky down, want 1", degraded.ModelsAvailable)
+	}
+	if probe := degraded.Probes["flaky"]; probe.Reachable || !strings.Contains(probe.Error, "connection refused") {
+		t.Errorf("flaky probe = %+v, want unreachable with its error", probe)
+	}
+	// The circuit breaker never saw the probe failures
+	if stats := degraded.CircuitBreakers["flaky"]; stats.IsOpen {
+		t.Errorf("flaky breaker = %+v, want probes kept out of it", stats)
+	}
+}
+
+func TestHealthProbeDisabled(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	server, _ := newFakeHuggingFaceServer(t, switchableHandler(&down))
+	pipeline := NewPipelineWithDetector(newTestLogger(), newTestLLMDetector(t, testModel("classifier", ProviderHuggingFace, server.URL)))
+
+	prober := NewHealthProber(0, time.Second)
+	pipeline.SetHealthProber(prober)
+	prober.Start()
+	defer prober.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	if results := prober.Results(); results != nil {
+		t.Errorf("Results = %+v with a zero interval, want no probes", results)
+	}
+	if health := pipeline.GetHealth(); health.Status != "healthy" || health.Probes != nil {
+		t.Errorf("health = %q with probes %+v, want healthy and unprobed", health.Status, health.Probes)
+	}
+
+	// A pipeline without a prober reports no probes either
+	var unset *HealthProber
+	unset.Start()
+	unset.Stop()
+	if unset.Results() != nil || unset.unreachable("classifier") {
+		t.Error("nil prober reported probe results")
+	}
+}
+
+func TestHealthProbeTimeout(t *testing.T) {
+	prober := NewHealthProber(time.Hour, 20*time.Millisecond)
+	prober.setTargets(func() []probeTarget {
+		return []probeTarget{{name: "hanging", probe: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}}}
+	})
+
+	start := time.Now()
+	prober.probeAll()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("probe round took %s, want it cut off at the 20ms timeout", elapsed)
+	}
+	if result := prober.Results()["hanging"]; result.Reachable || !strings.Contains(result.Error, context.DeadlineExceeded.Error()) {
+		t.Errorf("hanging probe = %+v, want unreachable after the timeout", result)
+	}
+}
@@ -0,0 +1,68 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Detector runs detection for one text against a single configured model
+type Detector interface {
+	Detect(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error)
+}
+
+// DetectorFunc adapts an ordinary function to the Detector interface
+type DetectorFunc func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error)
+
+// Detect calls f(ctx, text, model)
+func (f DetectorFunc) Detect(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+	return f(ctx, text, model)
+}
+
+// ProviderRegistry maps model providers to the detectors that call them
+type ProviderRegistry struct {
+	detectors map[ModelProvider]Detector
+	mutex     sync.RWMutex
+}
+
+// NewProviderRegistry creates an empty provider registry
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{detectors: make(map[ModelProvider]Detector)}
+}
+
+// Register routes models of the given provider to detector, replacing any previous registration
+func (r *ProviderRegistry) Register(provider ModelProvider, detector Detector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.detectors[provider] = detector
+}
+
+// Get returns the detector registered for a provider
+func (r *ProviderRegistry) Get(provider ModelProvider) (Detector, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	detector, exists := r.detectors[provider]
+	return detector, exists
+}
+
+// Detect dispatches to the detector registered for the model's provider
+func (r *ProviderRegistry) Detect(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+	detector, exists := r.Get(model.Provider)
+	if !exists {
+		return nil, fmt.Errorf("unsupported provider: %s", model.Provider)
+	}
+	return detector.Detect(ctx, text, model)
+}
+
+// registerEndpointProviders routes the providers served by the LLM detector's HTTP endpoints to it
+func registerEndpointProviders(registry *ProviderRegistry, llmDetector *LLMDetector) {
+	// Calls end with the request: cancellation leaves the breaker untouched (see
+	// isBreakerNeutral), so race losers abandoned by the pipeline are not judged as failures
+	endpoint := DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		return llmDetector.detectWithSpecificEndpoint(ctx, text, model, variantScopeFrom(ctx))
+	})
+
+	for _, provider := range []ModelProvider{ProviderHuggingFace, ProviderGoogle, ProviderOpenRouter, ProviderOpenAI, ProviderOpenAICompatible} {
+		registry.Register(provider, endpoint)
+	}
+}
@@ -0,0 +1,61 @@
+package detector
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// quotaCooldownEnd returns when a model whose quota ran out at now may be tried again:
+// after the configured cooldown, or at the start of the next UTC day when none is set
+func quotaCooldownEnd(now time.Time, cooldown time.Duration) time.Time {
+	if cooldown > 0 {
+		return now.Add(cooldown)
+	}
+	year, month, day := now.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
+
+// markQuotaExhausted skips a model until its quota cooldown ends
+func (p *FallbackPipeline) markQuotaExhausted(model ModelConfig, err error) {
+	until := quotaCooldownEnd(time.Now(), model.QuotaCooldown)
+
+	p.quotaMutex.Lock()
+	p.quotaExhausted[model.Name] = until
+	p.quotaMutex.Unlock()
+
+	p.logger.WithFields(logrus.Fields{
+		"model":          model.Name,
+		"error":          err.Error(),
+		"disabled_until": until,
+	}).Warn("Model quota exhausted - skipping until cooldown ends")
+}
+
+// quotaDisabledUntil returns when an out-of-quota model resumes, if it is still cooling down
+func (p *FallbackPipeline) quotaDisabledUntil(modelName string) (time.Time, bool) {
+	p.quotaMutex.RLock()
+	defer p.quotaMutex.RUnlock()
+
+	until, exists := p.quotaExhausted[modelName]
+	if !exists || !time.Now().Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// getQuotaExhaustedModels returns a snapshot of models still cooling down, dropping expired entries
+func (p *FallbackPipeline) getQuotaExhaustedModels() map[string]time.Time {
+	p.quotaMutex.Lock()
+	defer p.quotaMutex.Unlock()
+
+	now := time.Now()
+	snapshot := make(map[string]time.Time, len(p.quotaExhausted))
+	for name, until := range p.quotaExhausted {
+		if !now.Before(until) {
+			delete(p.quotaExhausted, name)
+			continue
+		}
+		snapshot[name] = until
+	}
+	return snapshot
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuotaCooldownEnd(t *testing.T) {
+	now := time.Date(2026, 3, 14, 22, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+
+	if got, want := quotaCooldownEnd(now, 0), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("default cooldown ends %v, want the next UTC midnight %v", got, want)
+	}
+	if got, want := quotaCooldownEnd(now, time.Hour), now.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("configured cooldown ends %v, want %v", got, want)
+	}
+}
+
+func TestQuotaExhaustedModelSkippedDuringCooldown(t *testing.T) {
+	free := testModel("free-tier", providerFake, "")
+	free.QuotaCooldown = time.Hour
+	paid := testModel("paid", providerFake, "")
+	paid.Priority = 2
+
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	pipeline := newTestFallbackPipeline(t, free, paid)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		mutex.Lock()
+		calls[model.Name]++
+		mutex.Unlock()
+
+		if model.Name == "free-tier" {
+			return nil, &ProviderError{Category: ErrorCategoryQuota, StatusCode: 429, Message: "You exceeded your current quota"}
+		}
+		return &DetectionResult{Method: MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+	}))
+
+	before := time.Now()
+	for i := 0; i < 3; i++ {
+		response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "hello", Config: &DetectionConfig{DetailedResponse: true}})
+		if err != nil {
+			t.Fatalf("Analyze %d: %v", i+1, err)
+		}
+		if response.Endpoint != "paid" {
+			t.Errorf("Analyze %d answered by %q, want the fallback", i+1, response.Endpoint)
+		}
+		if i > 0 && !strings.Contains(response.ModelResults[0].Error, "skipped until") {
+			t.Errorf("Analyze %d free-tier result = %+v, want it skipped for quota", i+1, response.ModelResults[0])
+		}
+	}
+
+	if calls["free-tier"] != 1 || calls["paid"] != 3 {
+		t.Errorf("calls = %v, want free-tier called once and skipped while cooling down", calls)
+	}
+
+	until, disabled := pipeline.quotaDisabledUntil("free-tier")
+	if !disabled || until.Before(before.Add(time.Hour)) || until.After(time.Now().Add(time.Hour)) {
+		t.Errorf("free-tier disabled until %v (%v), want an hour from the quota error", until, disabled)
+	}
+	if got, exists := pipeline.GetHealth().QuotaExhaustedUntil["free-tier"]; !exists || !got.Equal(until) {
+		t.Errorf("health quota_exhausted_until = %v, want %v", got, until)
+	}
+	for _, status := range pipeline.ListModels() {
+		if status.Name == "free-tier" && (status.DisabledUntil == nil || !status.DisabledUntil.Equal(until)) {
+			t.Errorf("listed free-tier disabled_until = %v, want %v", status.DisabledUntil, until)
+		}
+	}
+}
+
+func TestQuotaCooldownExpires(t *testing.T) {
+	pipeline := newTestFallbackPipeline(t)
+	model := testModel("free-tier", providerFake, "")
+	model.QuotaCooldown = 20 * time.Millisecond
+
+	pipeline.markQuotaExhausted(model, &ProviderError{Category: ErrorCategoryQuota, Message: "insufficient credits"})
+	if _, disabled := pipeline.quotaDisabledUntil("free-tier"); !disabled {
+		t.Fatal("model not disabled right after its quota ran out")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, disabled := pipeline.quotaDisabledUntil("free-tier"); disabled {
+		t.Error("model still disabled after its cooldown")
+	}
+	if exhausted := pipeline.getQuotaExhaustedModels(); len(exhausted) != 0 {
+		t.Errorf("quota exhausted models = %v, want the expired entry dropped", exhausted)
+	}
+}
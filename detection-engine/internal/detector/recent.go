@@ -0,0 +1,135 @@
+package detector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"prompt-injection-detection/internal/redact"
+)
+
+// DetectionNotifier receives completed detections, e.g. to alert on malicious prompts.
+// Implementations must not block the request.
+type DetectionNotifier interface {
+	NotifyDetection(ctx context.Context, text string, response *DetectionResponse)
+}
+
+// RecentDetection is a privacy-preserving record of a completed detection
+type RecentDetection struct {
+	Timestamp   time.Time `json:"timestamp"`
+	TextHash    string    `json:"text_hash"` // SHA-256 of the analyzed text
+	IsMalicious bool      `json:"is_malicious"`
+	Confidence  float64   `json:"confidence"`
+	ThreatTypes []string  `json:"threat_types"`
+	Model       string    `json:"model,omitempty"`
+	Text        string    `json:"text,omitempty"` // Truncated, PII- and secret-redacted text when capture is enabled
+}
+
+// RecentDetections keeps the last N detections in a fixed-size ring buffer
+type RecentDetections struct {
+	entries    []RecentDetection
+	next       int
+	full       bool
+	textLength int // Runes of text to keep, 0 stores only the hash
+	redactor   *redact.Redactor
+	mutex      sync.RWMutex
+}
+
+// NewRecentDetections creates a ring buffer holding size detections.
+// When textLength is positive the first textLength runes of each text are kept,
+// masked by redactor (the built-in patterns when nil).
+func NewRecentDetections(size, textLength int, redactor *redact.Redactor) *RecentDetections {
+	if size <= 0 {
+		size = 1
+	}
+	if redactor == nil {
+		redactor = redact.Default()
+	}
+	return &RecentDetections{
+		entries:    make([]RecentDetection, size),
+		textLength: textLength,
+		redactor:   redactor,
+	}
+}
+
+// Record stores a detection, overwriting the oldest entry once the buffer is full
+func (r *RecentDetections) Record(text string, response *DetectionResponse) {
+	if r == nil || response == nil {
+		return
+	}
+
+	hash := sha256.Sum256([]byte(text))
+	entry := RecentDetection{
+		Timestamp:   time.Now(),
+		TextHash:    hex.EncodeToString(hash[:]),
+		IsMalicious: response.IsMalicious,
+		Confidence:  response.Confidence,
+		ThreatTypes: response.ThreatTypes,
+		Model:       response.Endpoint,
+	}
+	if r.textLength > 0 {
+		entry.Text = r.preview(text)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns up to limit detections, newest first
+func (r *RecentDetections) List(limit int) []RecentDetection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	detections := make([]RecentDetection, 0, count)
+	for i := 1; i <= count; i++ {
+		index := (r.next - i + len(r.entries)) % len(r.entries)
+		detections = append(detections, r.entries[index])
+	}
+	return detections
+}
+
+// Capacity returns the maximum number of detections retained
+func (r *RecentDetections) Capacity() int {
+	return len(r.entries)
+}
+
+// preview redacts PII and secrets from text and truncates it to the configured length
+func (r *RecentDetections) preview(text string) string {
+	text = r.redactor.Redact(text)
+
+	runes := []rune(text)
+	if len(runes) > r.textLength {
+		return string(runes[:r.textLength]) + "..."
+	}
+	return text
+}
+
+// requestText returns the text a request analyzed, joining role-tagged messages
+func requestText(req *DetectionRequest) string {
+	if len(req.Messages) == 0 {
+		return req.Text
+	}
+
+	parts := make([]string, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		parts = append(parts, "["+message.Role+"] "+message.Content)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,202 @@
+package detector
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// ResultCache stores detection responses for previously analyzed text
+type ResultCache interface {
+	Get(ctx context.Context, key string) (*DetectionResponse, bool)
+	Set(ctx context.Context, key string, response *DetectionResponse)
+}
+
+// resultCacheKey hashes the normalized text together with any per-request config,
+// since thresholds and strategy change the response for the same text
+func resultCacheKey(req *DetectionRequest) string {
+	hash := sha256.New()
+	hash.Write([]byte(strings.Join(strings.Fields(strings.ToLower(req.Text)), " ")))
+	if req.Config != nil {
+		if config, err := json.Marshal(req.Config); err == nil {
+			hash.Write([]byte{0})
+			hash.Write(config)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// analyzeCached serves single-text requests from the cache, storing fresh model results
+func analyzeCached(ctx context.Context, cache ResultCache, req *DetectionRequest, analyze func(context.Context, *DetectionRequest) (*DetectionResponse, error)) (*DetectionResponse, error) {
+	if cache == nil || req.Text == "" || len(req.Messages) > 0 {
+		return analyze(ctx, req)
+	}
+
+	startTime := time.Now()
+	key := resultCacheKey(req)
+	if cached, ok := cache.Get(ctx, key); ok {
+		cached.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+		return cached, nil
+	}
+
+	response, err := analyze(ctx, req)
+	// Local heuristic results are cheap to recompute and shouldn't outlive a model outage,
+	// and neither should fail-closed verdicts
+	if err == nil && response.Endpoint != localEndpointName && !response.FailedClosed {
+		cache.Set(ctx, key, response)
+	}
+	return response, err
+}
+
+// MemoryResultCache is a size-bounded LRU cache with per-entry expiry.
+// Responses are stored serialized so callers can't mutate cached entries.
+type MemoryResultCache struct {
+	size    int
+	ttl     time.Duration
+	order   *list.List // Most recently used at the front
+	entries map[string]*list.Element
+	mutex   sync.Mutex
+}
+
+// memoryCacheEntry is the value stored in the LRU list
+type memoryCacheEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewMemoryResultCache creates an in-process cache holding at most size responses for ttl
+func NewMemoryResultCache(size int, ttl time.Duration) *MemoryResultCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryResultCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns a copy of the cached response if present and not expired
+func (c *MemoryResultCache) Get(ctx context.Context, key string) (*DetectionResponse, bool) {
+	c.mutex.Lock()
+	element, exists := c.entries[key]
+	if !exists {
+		c.mutex.Unlock()
+		return nil, false
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		c.mutex.Unlock()
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	data := entry.data
+	c.mutex.Unlock()
+
+	return decodeCachedResponse(data)
+}
+
+// Set stores the response, evicting the least recently used entry when full
+func (c *MemoryResultCache) Set(ctx context.Context, key string, response *DetectionResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &memoryCacheEntry{key: key, data: data, expiresAt: time.Now().Add(c.ttl)}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// RedisResultCache shares cached responses between replicas, using a local
+// cache whenever Redis calls fail
+type RedisResultCache struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	local     *MemoryResultCache
+	logger    *logrus.Logger
+}
+
+// NewRedisResultCache connects to Redis and verifies it is reachable
+func NewRedisResultCache(ctx context.Context, url, keyPrefix string, ttl time.Duration, local *MemoryResultCache, logger *logrus.Logger) (*RedisResultCache, error) {
+	client, err := connectRedis(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisResultCache{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+		local:     local,
+		logger:    logger,
+	}, nil
+}
+
+// Get reads the response from Redis, falling back to the local cache on errors
+func (c *RedisResultCache) Get(ctx context.Context, key string) (*DetectionResponse, bool) {
+	data, err := c.client.Get(ctx, c.keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+	if err != nil {
+		c.logger.WithError(err).Debug("Redis result cache read failed, using local cache")
+		return c.local.Get(ctx, key)
+	}
+
+	return decodeCachedResponse(data)
+}
+
+// Set writes the response to Redis with the cache TTL, falling back to the local cache on errors
+func (c *RedisResultCache) Set(ctx context.Context, key string, response *DetectionResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	if err := c.client.Set(ctx, c.keyPrefix+key, data, c.ttl).Err(); err != nil {
+		c.logger.WithError(err).Debug("Redis result cache write failed, using local cache")
+		c.local.Set(ctx, key, response)
+	}
+}
+
+// Close releases the Redis connection pool
+func (c *RedisResultCache) Close() error {
+	return c.client.Close()
+}
+
+// decodeCachedResponse deserializes a stored response, treating corrupt entries as misses
+func decodeCachedResponse(data []byte) (*DetectionResponse, bool) {
+	var response DetectionResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
@@ -0,0 +1,124 @@
+package detector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisResultCache connects a result cache with the given TTL to server
+func newTestRedisResultCache(t *testing.T, server *miniredis.Miniredis, ttl time.Duration) *RedisResultCache {
+	t.Helper()
+
+	cache, err := NewRedisResultCache(context.Background(), "redis://"+server.Addr(), "results:", ttl, NewMemoryResultCache(10, ttl), newTestLogger())
+	if err != nil {
+		t.Fatalf("NewRedisResultCache: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+// cachedResponse is a response exercising every kind of field that must survive a round trip
+func cachedResponse() *DetectionResponse {
+	index := 1
+	return &DetectionResponse{
+		IsMalicious:      true,
+		Confidence:       0.92,
+		ThreatTypes:      []string{string(ThreatTypeInjection), string(ThreatTypeDataExtraction)},
+		ProcessingTimeMs: 42,
+		Reason:           "consensus: 2 of 3 models flagged",
+		Endpoint:         "primary",
+		Disagreement:     true,
+		Matches:          []Match{{ThreatType: string(ThreatTypeInjection), Start: 3, End: 9, Snippet: "ignore"}},
+		Severity:         SeverityHigh,
+		Action:           ActionSanitize,
+		SanitizedMessages: []Message{
+			{Role: "user", Content: "hello"},
+		},
+		MessageIndex: &index,
+		MessageRole:  "user",
+		ModelResults: []ModelResult{
+			{Model: "primary", Score: 0.92, ThreatTypes: []string{string(ThreatTypeInjection)}, LatencyMs: 30},
+			{Model: "secondary", ThreatTypes: []string{}, Error: "timeout", ErrorCategory: string(ErrorCategoryTimeout)},
+		},
+		Strategy:          StrategyConsensus,
+		ModelDisagreement: 0.31,
+		TieBreaker:        "primary",
+	}
+}
+
+func TestRedisResultCacheSetGet(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := newTestRedisResultCache(t, server, time.Minute)
+	ctx := context.Background()
+
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Error("Get on an empty cache reported a hit")
+	}
+
+	want := cachedResponse()
+	cache.Set(ctx, "key", want)
+	if ttl := server.TTL("results:key"); ttl != time.Minute {
+		t.Errorf("stored TTL = %v, want 1m", ttl)
+	}
+
+	got, ok := cache.Get(ctx, "key")
+	if !ok {
+		t.Fatal("Get after Set missed")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v\nwant %+v", got, want)
+	}
+
+	// Another replica sharing the server sees the entry
+	replica := newTestRedisResultCache(t, server, time.Minute)
+	if got, ok := replica.Get(ctx, "key"); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("replica Get = %+v, %v, want the shared entry", got, ok)
+	}
+}
+
+func TestRedisResultCacheExpiry(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := newTestRedisResultCache(t, server, time.Minute)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key", cachedResponse())
+	server.FastForward(59 * time.Second)
+	if _, ok := cache.Get(ctx, "key"); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+	server.FastForward(time.Second)
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Error("entry still served after its TTL")
+	}
+}
+
+func TestRedisResultCacheCorruptEntryIsMiss(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := newTestRedisResultCache(t, server, time.Minute)
+
+	server.Set("results:key", "{not json")
+	if _, ok := cache.Get(context.Background(), "key"); ok {
+		t.Error("corrupt entry reported as a hit")
+	}
+}
+
+func TestRedisResultCacheFallsBackToLocal(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := newTestRedisResultCache(t, server, time.Minute)
+	ctx := context.Background()
+	server.Close()
+
+	want := cachedResponse()
+	cache.Set(ctx, "key", want)
+	got, ok := cache.Get(ctx, "key")
+	if !ok {
+		t.Fatal("Get with Redis down missed the locally cached entry")
+	}
+	if got.Confidence != want.Confidence || !reflect.DeepEqual(got.ThreatTypes, want.ThreatTypes) {
+		t.Errorf("local fallback = %+v, want %+v", got, want)
+	}
+}
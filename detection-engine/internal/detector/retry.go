@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy repeats provider calls that failed for transient reasons before
+// the failure is reported (and counted by the model's circuit breaker)
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per call including the first, 1 disables retries
+	BaseDelay   time.Duration // Backoff before the first retry, doubled for each further retry
+	MaxDelay    time.Duration // Cap on a single backoff
+}
+
+// DefaultRetryPolicy retries a transient failure once after about 200ms
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// backoff returns the delay before the given retry (1 for the first): a random
+// duration between half and all of BaseDelay*2^(retry-1), capped at MaxDelay
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// isRetriable reports whether a failed call may succeed if repeated: connection
+// failures and 502/503/504 responses. Timeouts, cancellation, auth, quota, rate
+// limit and validation errors are returned as they are.
+func isRetriable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+// callWithRetry calls the provider, retrying transient failures with jittered
+// exponential backoff for as long as the policy and ctx allow
+func (l *LLMDetector) callWithRetry(ctx context.Context, endpoint LLMEndpoint, prompt string) (string, error) {
+	attempts := max(l.retry.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		result, err := l.callProvider(ctx, endpoint, prompt)
+		if err == nil || attempt >= attempts || !isRetriable(err) {
+			return result, err
+		}
+		if sleepWithContext(ctx, l.retry.backoff(attempt)) != nil {
+			return result, err
+		}
+	}
+}
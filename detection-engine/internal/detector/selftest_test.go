@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// selfTestScores answers the self-test corpus correctly
+func selfTestScores(text string) float64 {
+	for _, testCase := range selfTestCorpus {
+		if testCase.text == text && testCase.malicious {
+			return 0.95
+		}
+	}
+	return 0.05
+}
+
+func TestRunSelfTestGradesModels(t *testing.T) {
+	broken, _ := newHuggingFaceServer(t, http.StatusInternalServerError, "", 0)
+
+	models := []ModelConfig{
+		testModel("broken-endpoint", ProviderHuggingFace, broken.URL),
+		testModel("accurate", providerFake, ""),
+		testModel("paranoid", providerFake, ""),
+	}
+	for i := range models {
+		models[i].Priority = i + 1
+	}
+	pipeline := newTestFallbackPipeline(t, models...)
+	pipeline.RegisterProvider(providerFake, DetectorFunc(func(ctx context.Context, text string, model ModelConfig) (*DetectionResult, error) {
+		score := selfTestScores(text)
+		if model.Name == "paranoid" {
+			score = 0.9 // Flags the benign prompts too
+		}
+		return &DetectionResult{Method: MethodLLM, Score: score, Endpoint: model.Name}, nil
+	}))
+
+	if pipeline.LastSelfTest() != nil {
+		t.Fatal("LastSelfTest() returned a report before any self-test ran")
+	}
+
+	report := pipeline.RunSelfTest(context.Background())
+	if !report.Healthy {
+		t.Error("Healthy = false, want true while one model classifies")
+	}
+	if len(report.Models) != 3 {
+		t.Fatalf("Models = %+v, want one outcome per model", report.Models)
+	}
+
+	outcomes := make(map[string]ModelSelfTest)
+	for _, outcome := range report.Models {
+		outcomes[outcome.Model] = outcome
+	}
+	if got := outcomes["broken-endpoint"]; got.Status != SelfTestFail || got.Errors != len(selfTestCorpus) || got.LastError == "" {
+		t.Errorf("broken endpoint = %+v, want fail with an error per case", got)
+	}
+	if got := outcomes["accurate"]; got.Status != SelfTestPass || got.Correct != len(selfTestCorpus) {
+		t.Errorf("accurate model = %+v, want pass", got)
+	}
+	if got := outcomes["paranoid"]; got.Status != SelfTestDegraded || got.Incorrect != 2 {
+		t.Errorf("paranoid model = %+v, want degraded with the two benign prompts wrong", got)
+	}
+
+	if pipeline.LastSelfTest() != report {
+		t.Error("LastSelfTest() does not return the latest report")
+	}
+}
+
+func TestRunSelfTestUnhealthyWhenEveryModelFails(t *testing.T) {
+	broken, calls := newHuggingFaceServer(t, http.StatusServiceUnavailable, "", 0)
+	pipeline := newTestFallbackPipeline(t, testModel("broken-endpoint", ProviderHuggingFace, broken.URL))
+
+	report := pipeline.RunSelfTest(context.Background())
+	if report.Healthy {
+		t.Error("Healthy = true, want false when no model can classify")
+	}
+	if len(report.Models) != 1 || report.Models[0].Status != SelfTestFail {
+		t.Errorf("Models = %+v, want the broken endpoint failing", report.Models)
+	}
+	if calls.Load() == 0 {
+		t.Error("the self-test never called the endpoint")
+	}
+
+	// The self-test bypasses breakers: running it does not take the model out of rotation
+	if health := pipeline.GetHealth(); health.ModelsAvailable != 1 {
+		t.Errorf("ModelsAvailable = %d after the self-test, want 1", health.ModelsAvailable)
+	}
+}
+
+func TestRunSelfTestLocalOnly(t *testing.T) {
+	pipeline := newTestFallbackPipeline(t)
+	pipeline.SetLocalOnly(true)
+
+	report := pipeline.RunSelfTest(context.Background())
+	if !report.Healthy || len(report.Models) != 1 || report.Models[0].Model != localEndpointName {
+		t.Fatalf("report = %+v, want a healthy local outcome", report)
+	}
+	if got := report.Models[0]; got.Status != SelfTestPass {
+		t.Errorf("local outcome = %+v, want the heuristics to pass the corpus", got)
+	}
+}
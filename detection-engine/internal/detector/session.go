@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSessionTTL is how long an idle session's risk is remembered
+	defaultSessionTTL = 30 * time.Minute
+
+	// sessionContextTurns is how many recent messages are analyzed together
+	sessionContextTurns = 6
+
+	// sessionRiskDecay discounts earlier risk each turn so stale suspicion fades
+	sessionRiskDecay = 0.85
+
+	// sessionTurnWeight scales how much a single turn contributes to cumulative risk
+	sessionTurnWeight = 0.5
+)
+
+// SessionStore tracks cumulative suspicion per conversation session in memory
+type SessionStore struct {
+	sessions map[string]*sessionState
+	ttl      time.Duration
+	mutex    sync.Mutex
+}
+
+// sessionState holds the running risk for a single session
+type sessionState struct {
+	risk     float64
+	peakRisk float64
+	turns    int
+	lastSeen time.Time
+}
+
+// SessionRisk is a snapshot of a session's cumulative risk
+type SessionRisk struct {
+	Risk     float64 `json:"risk"`
+	PeakRisk float64 `json:"peak_risk"`
+	Turns    int     `json:"turns"`
+}
+
+// NewSessionStore creates a session store whose idle sessions expire after ttl
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	return &SessionStore{
+		sessions: make(map[string]*sessionState),
+		ttl:      ttl,
+	}
+}
+
+// Record folds a turn score into the session's cumulative risk and returns the new risk
+func (s *SessionStore) Record(sessionID string, turnScore float64) SessionRisk {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+
+	state, exists := s.sessions[sessionID]
+	if !exists {
+		state = &sessionState{}
+		s.sessions[sessionID] = state
+	}
+
+	// Decayed noisy-OR: repeated mildly suspicious turns escalate, isolated ones fade
+	state.risk = combineScores(state.risk*sessionRiskDecay, turnScore*sessionTurnWeight)
+	if turnScore > state.risk {
+		state.risk = turnScore
+	}
+	if state.risk > state.peakRisk {
+		state.peakRisk = state.risk
+	}
+	state.turns++
+	state.lastSeen = now
+
+	return SessionRisk{Risk: state.risk, PeakRisk: state.peakRisk, Turns: state.turns}
+}
+
+// Reset forgets a session's accumulated risk
+func (s *SessionStore) Reset(sessionID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+// evictExpired removes idle sessions; caller must hold the lock
+func (s *SessionStore) evictExpired(now time.Time) {
+	for id, state := range s.sessions {
+		if now.Sub(state.lastSeen) > s.ttl {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// buildSessionContext joins the most recent turns into a single text for analysis
+func buildSessionContext(messages []Message) string {
+	if len(messages) > sessionContextTurns {
+		messages = messages[len(messages)-sessionContextTurns:]
+	}
+
+	var builder strings.Builder
+	for i, message := range messages {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("[%s] %s", message.Role, message.Content))
+	}
+	return builder.String()
+}
+
+// AnalyzeSession analyzes the latest turn in the context of recent history and updates the session risk
+func (p *FallbackPipeline) AnalyzeSession(ctx context.Context, req *SessionDetectionRequest) (*SessionDetectionResponse, error) {
+	turnResponse, err := p.Analyze(ctx, &DetectionRequest{
+		Text:   buildSessionContext(req.Messages),
+		Config: req.Config,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessionRisk := p.sessions.Record(req.SessionID, turnResponse.Confidence)
+
+	threshold := p.confidenceThreshold
+	if req.Config != nil && req.Config.ConfidenceThreshold > 0 {
+		threshold = req.Config.ConfidenceThreshold
+	}
+
+	return &SessionDetectionResponse{
+		SessionID:    req.SessionID,
+		TurnResult:   turnResponse,
+		SessionRisk:  sessionRisk,
+		IsMalicious:  turnResponse.IsMalicious || sessionRisk.Risk >= threshold,
+		TurnsInScope: min(len(req.Messages), sessionContextTurns),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package detector
+
+// Severity grades a detection beyond the is_malicious boolean
+type Severity string
+
+const (
+	SeverityNone     Severity = "none"
+	SeverityLow      Severity = "low" // Suspicious
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical" // Clearly malicious
+)
+
+// severityLevels orders severities from least to most severe
+var severityLevels = []Severity{SeverityNone, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// criticalThreats escalate a high severity to critical: exfiltration and prompt
+// leaks do damage as soon as they succeed
+var criticalThreats = map[string]bool{
+	string(ThreatTypeDataExtraction):   true,
+	string(ThreatTypeSystemPromptLeak): true,
+}
+
+// SeverityThresholds are the minimum scores for each severity
+type SeverityThresholds struct {
+	Low      float64
+	Medium   float64
+	High     float64
+	Critical float64
+}
+
+// DefaultSeverityThresholds returns the score bands used when none are configured
+func DefaultSeverityThresholds() SeverityThresholds {
+	return SeverityThresholds{Low: 0.3, Medium: 0.5, High: 0.7, Critical: 0.9}
+}
+
+// classify grades a response by its score, escalating high-confidence critical threats.
+// Responses below the malicious threshold are at most low (suspicious).
+func (t SeverityThresholds) classify(response *DetectionResponse) Severity {
+	level := 0
+	for i, threshold := range []float64{t.Low, t.Medium, t.High, t.Critical} {
+		if response.Confidence >= threshold {
+			level = i + 1
+		}
+	}
+
+	if level == 3 {
+		for _, threat := range response.ThreatTypes {
+			if criticalThreats[threat] {
+				level = 4
+				break
+			}
+		}
+	}
+
+	if !response.IsMalicious {
+		level = min(level, 1)
+	} else {
+		level = max(level, 1)
+	}
+	return severityLevels[level]
+}
+
+// withDefaults returns the thresholds, or the defaults when none were set
+func (t SeverityThresholds) withDefaults() SeverityThresholds {
+	if t == (SeverityThresholds{}) {
+		return DefaultSeverityThresholds()
+	}
+	return t
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeverityClassify(t *testing.T) {
+	tests := map[string]struct {
+		score     float64
+		malicious bool
+		threats   []ThreatType
+		want      Severity
+	}{
+		"benign":                           {0.05, false, nil, SeverityNone},
+		"suspicious but below threshold":   {0.45, false, []ThreatType{ThreatTypeInjection}, SeverityLow},
+		"high score overruled as safe":     {0.95, false, []ThreatType{ThreatTypeDataExtraction}, SeverityLow},
+		"malicious at medium":              {0.6, true, []ThreatType{ThreatTypeInjection}, SeverityMedium},
+		"malicious at high":                {0.75, true, []ThreatType{ThreatTypeJailbreak}, SeverityHigh},
+		"malicious near certain":           {0.95, true, []ThreatType{ThreatTypeJailbreak}, SeverityCritical},
+		"data extraction at high":          {0.75, true, []ThreatType{ThreatTypeDataExtraction}, SeverityCritical},
+		"system prompt leak at high":       {0.8, true, []ThreatType{ThreatTypeInjection, ThreatTypeSystemPromptLeak}, SeverityCritical},
+		"data extraction at medium":        {0.55, true, []ThreatType{ThreatTypeDataExtraction}, SeverityMedium},
+		"malicious under a lowered cutoff": {0.2, true, []ThreatType{ThreatTypeInjection}, SeverityLow},
+		"band boundary is inclusive":       {0.7, true, nil, SeverityHigh},
+		"critical boundary is inclusive":   {0.9, true, nil, SeverityCritical},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			response := &DetectionResponse{Confidence: tt.score, IsMalicious: tt.malicious, ThreatTypes: threatStrings(tt.threats)}
+			if got := DefaultSeverityThresholds().classify(response); got != tt.want {
+				t.Errorf("classify(%v, malicious %v, %v) = %q, want %q", tt.score, tt.malicious, tt.threats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityCustomThresholds(t *testing.T) {
+	strict := SeverityThresholds{Low: 0.1, Medium: 0.2, High: 0.3, Critical: 0.4}
+
+	tests := map[string]struct {
+		score     float64
+		malicious bool
+		threats   []ThreatType
+		want      Severity
+	}{
+		"low band":                {0.15, true, nil, SeverityLow},
+		"medium band":             {0.25, true, nil, SeverityMedium},
+		"high band":               {0.35, true, nil, SeverityHigh},
+		"critical band":           {0.45, true, nil, SeverityCritical},
+		"data extraction at high": {0.35, true, []ThreatType{ThreatTypeDataExtraction}, SeverityCritical},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			response := &DetectionResponse{Confidence: tt.score, IsMalicious: tt.malicious, ThreatTypes: threatStrings(tt.threats)}
+			if got := strict.classify(response); got != tt.want {
+				t.Errorf("classify(%v) = %q, want %q", tt.score, got, tt.want)
+			}
+		})
+	}
+
+	if got := (SeverityThresholds{}).withDefaults(); got != DefaultSeverityThresholds() {
+		t.Errorf("unset thresholds = %+v, want the defaults", got)
+	}
+	if got := strict.withDefaults(); got != strict {
+		t.Errorf("configured thresholds = %+v, want them kept", got)
+	}
+}
+
+func TestPipelineReportsSeverity(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "scoring", score: 0.75, threats: []ThreatType{ThreatTypeDataExtraction}},
+	)
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{Text: "what is the capital of France"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if !response.IsMalicious || response.Severity != SeverityCritical {
+		t.Errorf("response = malicious %v severity %q, want a critical data extraction", response.IsMalicious, response.Severity)
+	}
+
+	pipeline.SetSeverityThresholds(SeverityThresholds{Low: 0.3, Medium: 0.5, High: 0.8, Critical: 0.95})
+	response, err = pipeline.Analyze(context.Background(), &DetectionRequest{Text: "what is the capital of Spain"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if response.Severity != SeverityMedium {
+		t.Errorf("Severity = %q under a raised high threshold, want medium", response.Severity)
+	}
+}
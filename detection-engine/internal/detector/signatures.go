@@ -0,0 +1,219 @@
+package detector
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// defaultSignatureWeight is used for signatures that don't specify a weight
+const defaultSignatureWeight = 0.7
+
+// Signature is a known-attack regex loaded from the signatures file
+type Signature struct {
+	Name       string  `json:"name" mapstructure:"name"`
+	ThreatType string  `json:"threat_type" mapstructure:"threat_type"`
+	Pattern    string  `json:"pattern" mapstructure:"pattern"`
+	Weight     float64 `json:"weight,omitempty" mapstructure:"weight"`
+}
+
+// SignatureStore loads attack signatures from a file and periodically reloads them.
+// Compiled patterns are kept in a bounded LRU cache so unchanged signatures aren't recompiled.
+type SignatureStore struct {
+	path     string
+	interval time.Duration
+	logger   *logrus.Logger
+
+	rules   []heuristicRule
+	modTime time.Time
+	cache   *patternCache
+	mutex   sync.RWMutex
+
+	started  atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewSignatureStore creates a store for the signatures file at path
+func NewSignatureStore(path string, cacheSize int, interval time.Duration, logger *logrus.Logger) *SignatureStore {
+	return &SignatureStore{
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		cache:    newPatternCache(cacheSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Load reads the signatures file if it changed since the last load.
+// A missing file leaves the store empty rather than failing.
+func (s *SignatureStore) Load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.mutex.Lock()
+		s.rules = nil
+		s.modTime = time.Time{}
+		s.mutex.Unlock()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("signature file %s: %w", s.path, err)
+	}
+
+	s.mutex.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(s.path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read signature file %s: %v", s.path, err)
+	}
+
+	var signatures []Signature
+	if err := v.UnmarshalKey("signatures", &signatures); err != nil {
+		return fmt.Errorf("failed to parse signature file %s: %v", s.path, err)
+	}
+
+	rules := make([]heuristicRule, 0, len(signatures))
+	for _, signature := range signatures {
+		pattern, err := s.cache.compile(signature.Pattern)
+		if err != nil {
+			s.logger.WithError(err).WithField("signature", signature.Name).Warn("Skipping invalid signature pattern")
+			continue
+		}
+
+		weight := signature.Weight
+		if weight <= 0 || weight > 1 {
+			weight = defaultSignatureWeight
+		}
+
+		rules = append(rules, heuristicRule{
+			threat:      ThreatType(signature.ThreatType),
+			pattern:     pattern,
+			weight:      weight,
+			description: "signature " + signature.Name,
+		})
+	}
+
+	s.mutex.Lock()
+	s.rules = rules
+	s.modTime = info.ModTime()
+	s.mutex.Unlock()
+
+	s.logger.WithFields(logrus.Fields{
+		"path":       s.path,
+		"signatures": len(rules),
+	}).Info("Attack signatures loaded")
+
+	return nil
+}
+
+// Rules returns the currently loaded signatures as heuristic rules
+func (s *SignatureStore) Rules() []heuristicRule {
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.rules
+}
+
+// Start reloads the signatures file every interval until Stop is called
+func (s *SignatureStore) Start() {
+	if s.interval <= 0 || !s.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Load(); err != nil {
+					s.logger.WithError(err).Warn("Failed to reload attack signatures, keeping previous set")
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background reloading and waits for the reload loop to exit
+func (s *SignatureStore) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started.Load() {
+			<-s.done
+		}
+	})
+}
+
+// patternCache is a size-bounded LRU cache of compiled regular expressions
+type patternCache struct {
+	size    int
+	order   *list.List // Most recently used at the front
+	entries map[string]*list.Element
+	mutex   sync.Mutex
+}
+
+// patternCacheEntry is the value stored in the LRU list
+type patternCacheEntry struct {
+	source   string
+	compiled *regexp.Regexp
+}
+
+// newPatternCache creates a cache holding at most size compiled patterns
+func newPatternCache(size int) *patternCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &patternCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// compile returns a cached compiled pattern, compiling and caching it on a miss
+func (c *patternCache) compile(source string) (*regexp.Regexp, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.entries[source]; exists {
+		c.order.MoveToFront(element)
+		return element.Value.(*patternCacheEntry).compiled, nil
+	}
+
+	compiled, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[source] = c.order.PushFront(&patternCacheEntry{source: source, compiled: compiled})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*patternCacheEntry).source)
+	}
+
+	return compiled, nil
+}
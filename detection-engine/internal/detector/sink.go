@@ -0,0 +1,166 @@
+package detector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/logging"
+	"prompt-injection-detection/internal/redact"
+)
+
+// ErrSinkQueueFull is returned when an async sink drops a record because its queue is full
+var ErrSinkQueueFull = errors.New("result sink queue full")
+
+// DetectionRecord is the persisted outcome of a detection for audit and analytics.
+// The analyzed text is identified by its hash unless text capture is enabled, and
+// captured text is redacted.
+type DetectionRecord struct {
+	RequestID   string    `json:"request_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	TextHash    string    `json:"text_hash"` // SHA-256 of the analyzed text
+	IsMalicious bool      `json:"is_malicious"`
+	Confidence  float64   `json:"confidence"`
+	ThreatTypes []string  `json:"threat_types"`
+	Severity    Severity  `json:"severity,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	LatencyMs   int64     `json:"latency_ms"`
+	Text        string    `json:"text,omitempty"`
+}
+
+// ResultSink persists detection records
+type ResultSink interface {
+	Record(ctx context.Context, record DetectionRecord) error
+}
+
+// NopSink discards every record; pipelines use it when no sink is configured
+type NopSink struct{}
+
+// Record discards the record
+func (NopSink) Record(context.Context, DetectionRecord) error {
+	return nil
+}
+
+// newDetectionRecord builds the record of a completed detection, keeping the
+// redacted text only when asked to; the hash is of the original text
+func newDetectionRecord(ctx context.Context, text string, response *DetectionResponse, includeText bool, redactor *redact.Redactor) DetectionRecord {
+	hash := sha256.Sum256([]byte(text))
+	record := DetectionRecord{
+		RequestID:   logging.RequestID(ctx),
+		Timestamp:   time.Now().UTC(),
+		TextHash:    hex.EncodeToString(hash[:]),
+		IsMalicious: response.IsMalicious,
+		Confidence:  response.Confidence,
+		ThreatTypes: response.ThreatTypes,
+		Severity:    response.Severity,
+		Model:       response.Endpoint,
+		LatencyMs:   response.ProcessingTimeMs,
+	}
+	if includeText {
+		record.Text = redactor.Redact(text)
+	}
+	return record
+}
+
+// JSONLSink appends records to a file, one JSON object per line
+type JSONLSink struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewJSONLSink opens path for appending, creating it if needed
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open result sink file %s: %w", path, err)
+	}
+	return &JSONLSink{file: file}, nil
+}
+
+// Record writes the record as a single line; concurrent records never interleave
+func (s *JSONLSink) Record(_ context.Context, record DetectionRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode detection record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write detection record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file
+func (s *JSONLSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+// AsyncSink queues records for a background worker so a slow sink never delays
+// a detection response; records arriving while the queue is full are dropped
+type AsyncSink struct {
+	sink   ResultSink
+	logger *logrus.Logger
+	queue  chan DetectionRecord
+	done   chan struct{}
+}
+
+// NewAsyncSink wraps sink with a queue of queueSize records and starts its worker
+func NewAsyncSink(sink ResultSink, queueSize int, logger *logrus.Logger) *AsyncSink {
+	s := &AsyncSink{
+		sink:   sink,
+		logger: logger,
+		queue:  make(chan DetectionRecord, max(queueSize, 1)),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+	return s
+}
+
+// Record queues the record without blocking, returning ErrSinkQueueFull when it is dropped
+func (s *AsyncSink) Record(_ context.Context, record DetectionRecord) error {
+	select {
+	case s.queue <- record:
+		return nil
+	default:
+		return ErrSinkQueueFull
+	}
+}
+
+// Stop writes queued records, waits for the worker to exit and closes the
+// wrapped sink if it holds a resource
+func (s *AsyncSink) Stop() {
+	close(s.queue)
+	<-s.done
+
+	if closer, ok := s.sink.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			s.logger.WithError(err).Warn("Failed to close result sink")
+		}
+	}
+}
+
+// run writes queued records until the queue is closed
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	for record := range s.queue {
+		if err := s.sink.Record(context.Background(), record); err != nil {
+			s.logger.WithError(err).WithField("request_id", record.RequestID).Error("Failed to persist detection record")
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package detector
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxSpacedChunk is the longest letter group a punctuation-laced word is split into ("ig.no.re")
+	maxSpacedChunk = 3
+
+	// maxSpacingSeparator is the longest run of spacing or decorative punctuation between two letter groups
+	maxSpacingSeparator = 6
+
+	// minSpacedLetters is the fewest letters a spaced-out run needs before it is rejoined,
+	// so abbreviations such as "U.S.A" or "e.g." stay intact
+	minSpacedLetters = 4
+)
+
+// spacedChunk is a group of letters inside a spaced-out run and the separator preceding it
+type spacedChunk struct {
+	letters   string
+	separator []rune // Empty for the first chunk
+}
+
+// normalizeSpacing rejoins words spaced out with whitespace ("i g n o r e   a l l") or
+// laced with decorative punctuation ("i.g.n.o.r.e", "ig*no*re") and collapses runs of
+// whitespace. A separator wider than the run's narrowest one, or whitespace in a run
+// otherwise split by punctuation, is kept as a word break. Text without such runs is
+// returned unchanged, so whitespace alone never produces a variant.
+func normalizeSpacing(text string) string {
+	runes := []rune(text)
+
+	var normalized strings.Builder
+	normalized.Grow(len(text))
+
+	rejoined := false
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) || (i > 0 && unicode.IsLetter(runes[i-1])) {
+			normalized.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		chunks, end := scanSpacedRun(runes, i)
+		if !isSpacedRun(chunks) {
+			normalized.WriteString(chunks[0].letters)
+			i += len([]rune(chunks[0].letters))
+			continue
+		}
+
+		writeSpacedRun(&normalized, chunks)
+		rejoined = true
+		i = end
+	}
+
+	if !rejoined {
+		return text
+	}
+	return collapseWhitespace(normalized.String())
+}
+
+// scanSpacedRun collects the letter groups starting at runes[start] that are joined
+// by spacing or decorative punctuation, returning them and the index after the last one
+func scanSpacedRun(runes []rune, start int) ([]spacedChunk, int) {
+	var chunks []spacedChunk
+	var separator []rune
+
+	runEnd := start
+	for i := start; ; {
+		end := i
+		for end < len(runes) && unicode.IsLetter(runes[end]) {
+			end++
+		}
+		chunk := spacedChunk{letters: string(runes[i:end]), separator: separator}
+		if len(chunks) > 0 && !spacedJoinAllowed(chunks[len(chunks)-1], chunk) {
+			break
+		}
+		chunks = append(chunks, chunk)
+		runEnd = end
+
+		sepEnd := end
+		for sepEnd < len(runes) && sepEnd-end < maxSpacingSeparator && isSpacingSeparatorRune(runes[sepEnd]) {
+			sepEnd++
+		}
+		if sepEnd == end || sepEnd >= len(runes) || !unicode.IsLetter(runes[sepEnd]) {
+			break
+		}
+		separator = runes[end:sepEnd]
+		i = sepEnd
+	}
+
+	return chunks, runEnd
+}
+
+// spacedJoinAllowed reports whether next can continue a spaced-out run after prev:
+// whitespace may only separate single letters, punctuation may separate short groups
+func spacedJoinAllowed(prev, next spacedChunk) bool {
+	prevLen, nextLen := len([]rune(prev.letters)), len([]rune(next.letters))
+	if hasWhitespace(next.separator) {
+		return prevLen == 1 && nextLen == 1
+	}
+	return prevLen <= maxSpacedChunk && nextLen <= maxSpacedChunk
+}
+
+// isSpacedRun reports whether the chunks are an obfuscated word rather than ordinary text
+func isSpacedRun(chunks []spacedChunk) bool {
+	if len(chunks) < 3 {
+		return false
+	}
+	letters := 0
+	for _, chunk := range chunks {
+		letters += len([]rune(chunk.letters))
+	}
+	return letters >= minSpacedLetters
+}
+
+// writeSpacedRun writes the chunks as words, keeping a space only at separators
+// that stand out as word breaks
+func writeSpacedRun(normalized *strings.Builder, chunks []spacedChunk) {
+	punctuationOnly := false
+	narrowest := maxSpacingSeparator + 1
+	for _, chunk := range chunks[1:] {
+		if !hasWhitespace(chunk.separator) {
+			punctuationOnly = true
+			continue
+		}
+		narrowest = min(narrowest, len(chunk.separator))
+	}
+
+	for i, chunk := range chunks {
+		if i > 0 && hasWhitespace(chunk.separator) && (punctuationOnly || len(chunk.separator) > narrowest) {
+			normalized.WriteRune(' ')
+		}
+		normalized.WriteString(chunk.letters)
+	}
+}
+
+// collapseWhitespace replaces every run of whitespace with a single space, or a
+// single newline when the run spans lines
+func collapseWhitespace(text string) string {
+	var collapsed strings.Builder
+	collapsed.Grow(len(text))
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !unicode.IsSpace(runes[i]) {
+			collapsed.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		newline := false
+		for end < len(runes) && unicode.IsSpace(runes[end]) {
+			newline = newline || runes[end] == '\n'
+			end++
+		}
+		if newline {
+			collapsed.WriteRune('\n')
+		} else {
+			collapsed.WriteRune(' ')
+		}
+		i = end
+	}
+
+	return collapsed.String()
+}
+
+// isSpacingSeparatorRune reports whether r can be inserted between letters to obfuscate a word
+func isSpacingSeparatorRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// hasWhitespace reports whether the separator contains any whitespace
+func hasWhitespace(separator []rune) bool {
+	for _, r := range separator {
+		if unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package detector
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fragmentMarkerRegex finds enumerated fragment labels such as "part1:", "Part 2 -" or "fragment #3)"
+var fragmentMarkerRegex = regexp.MustCompile(`(?i)\b(?:part|fragment|piece|segment|chunk|section)\s*#?\s*(\d{1,2})\s*[:=\-)]`)
+
+// textFragment is a labelled piece of a split payload
+type textFragment struct {
+	index   int
+	content string
+}
+
+// reassembleFragments joins enumerated fragments in label order, returning ""
+// when the text holds fewer than two of them
+func reassembleFragments(text string) string {
+	markers := fragmentMarkerRegex.FindAllStringSubmatchIndex(text, -1)
+	if len(markers) < 2 {
+		return ""
+	}
+
+	fragments := make([]textFragment, 0, len(markers))
+	for i, marker := range markers {
+		index, err := strconv.Atoi(text[marker[2]:marker[3]])
+		if err != nil {
+			continue
+		}
+
+		// A fragment runs until the next marker or the end of its line
+		end := len(text)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		content := text[marker[1]:end]
+		if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+			content = content[:newline]
+		}
+
+		content = strings.Trim(content, " \t\r\"'`,;")
+		if content != "" {
+			fragments = append(fragments, textFragment{index: index, content: content})
+		}
+	}
+	if len(fragments) < 2 {
+		return ""
+	}
+
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].index < fragments[j].index
+	})
+
+	parts := make([]string, len(fragments))
+	for i, fragment := range fragments {
+		parts[i] = fragment.content
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,88 @@
+package detector
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// structuredAnalysis is the JSON verdict requested from models running in JSON mode
+type structuredAnalysis struct {
+	Score   *float64 `json:"score"`
+	Threats []string `json:"threats"`
+	Reason  string   `json:"reason"`
+}
+
+// jsonModeInstruction replaces the SCORE/THREATS/REASON line format when JSON output is requested
+const jsonModeInstruction = "\n\nRespond with a JSON object instead of the SCORE/THREATS/REASON line: " +
+	`{"score": <0.0-1.0>, "threats": [<threat types>], "reason": "<explanation>"}`
+
+// geminiAnalysisSchema constrains Gemini JSON output (OpenAPI schema subset)
+var geminiAnalysisSchema = map[string]interface{}{
+	"type": "OBJECT",
+	"properties": map[string]interface{}{
+		"score":   map[string]interface{}{"type": "NUMBER"},
+		"threats": map[string]interface{}{"type": "ARRAY", "items": map[string]interface{}{"type": "STRING"}},
+		"reason":  map[string]interface{}{"type": "STRING"},
+	},
+	"required": []string{"score", "threats", "reason"},
+}
+
+// OpenAIResponseFormat requests schema-constrained output from OpenAI-compatible APIs
+type OpenAIResponseFormat struct {
+	Type       string           `json:"type"` // "json_schema"
+	JSONSchema OpenAIJSONSchema `json:"json_schema"`
+}
+
+// OpenAIJSONSchema names the schema the response must follow
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// openAIAnalysisFormat is the response_format sent to OpenAI-compatible APIs in JSON mode
+var openAIAnalysisFormat = &OpenAIResponseFormat{
+	Type: "json_schema",
+	JSONSchema: OpenAIJSONSchema{
+		Name:   "injection_analysis",
+		Strict: true,
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"score":   map[string]interface{}{"type": "number"},
+				"threats": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"reason":  map[string]interface{}{"type": "string"},
+			},
+			"required":             []string{"score", "threats", "reason"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+// parseStructuredAnalysis reads a JSON verdict, tolerating a markdown code fence around it.
+// Returns false when the analysis is not a JSON object with a score.
+func parseStructuredAnalysis(analysis string) (float64, []ThreatType, string, bool) {
+	body := strings.TrimSpace(analysis)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSuffix(body, "```")
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		return 0, nil, "", false
+	}
+
+	var parsed structuredAnalysis
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil || parsed.Score == nil {
+		return 0, nil, "", false
+	}
+
+	threatTypes := make([]ThreatType, 0, len(parsed.Threats))
+	for _, threat := range parsed.Threats {
+		if threatType, ok := threatTypeFromLabel(threat); ok {
+			threatTypes = appendThreat(threatTypes, threatType)
+		}
+	}
+
+	score := min(max(*parsed.Score, 0), 1)
+	return score, threatTypes, strings.TrimSpace(parsed.Reason), true
+}
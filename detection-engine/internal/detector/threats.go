@@ -0,0 +1,34 @@
+package detector
+
+// ThreatInfo describes a threat type for clients building UIs and mappings
+type ThreatInfo struct {
+	ID              ThreatType `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	DefaultSeverity Severity   `json:"default_severity"` // Severity of a confident detection
+}
+
+// threatCatalog documents every ThreatType constant; each new constant needs an entry
+var threatCatalog = []ThreatInfo{
+	{ID: ThreatTypeJailbreak, Name: "Jailbreak", Description: "Attempts to make the model drop its rules, e.g. DAN or developer mode role-play"},
+	{ID: ThreatTypeSystemPromptLeak, Name: "System prompt leak", Description: "Requests to reveal the system prompt or other hidden instructions"},
+	{ID: ThreatTypeInjection, Name: "Prompt injection", Description: "Instructions that override or replace the application's instructions"},
+	{ID: ThreatTypeDataExtraction, Name: "Data extraction", Description: "Attempts to exfiltrate secrets, credentials, files or user data"},
+	{ID: ThreatTypeEncodingAttack, Name: "Encoding attack", Description: "Instructions hidden in base64, hex, ROT13, leetspeak or similar encodings"},
+	{ID: ThreatTypeDelimiterAttack, Name: "Delimiter attack", Description: "Fake system, role or end-of-prompt markers that spoof message boundaries"},
+	{ID: ThreatTypePayloadSplitting, Name: "Payload splitting", Description: "Instructions split across enumerated fragments to be reassembled by the model"},
+	{ID: ThreatTypeRefusalSuppression, Name: "Refusal suppression", Description: "Instructions forbidding the model to refuse, apologize or add disclaimers"},
+}
+
+// ThreatCatalog returns every threat type with its name, description and default severity
+func ThreatCatalog() []ThreatInfo {
+	catalog := make([]ThreatInfo, len(threatCatalog))
+	for i, info := range threatCatalog {
+		info.DefaultSeverity = SeverityHigh
+		if criticalThreats[string(info.ID)] {
+			info.DefaultSeverity = SeverityCritical
+		}
+		catalog[i] = info
+	}
+	return catalog
+}
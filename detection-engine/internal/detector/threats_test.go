@@ -0,0 +1,90 @@
+package detector
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// declaredThreatTypes parses the package sources for every constant declared as a ThreatType
+func declaredThreatTypes(t *testing.T) []ThreatType {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("list sources: %v", err)
+	}
+	fset := token.NewFileSet()
+	var threats []ThreatType
+	for _, file := range files {
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		for _, decl := range parsed.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value := spec.(*ast.ValueSpec)
+				if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "ThreatType" {
+					continue
+				}
+				for _, expr := range value.Values {
+					if literal, ok := expr.(*ast.BasicLit); ok && literal.Kind == token.STRING {
+						unquoted, err := strconv.Unquote(literal.Value)
+						if err != nil {
+							t.Fatalf("unquote %s: %v", literal.Value, err)
+						}
+						threats = append(threats, ThreatType(unquoted))
+					}
+				}
+			}
+		}
+	}
+	return threats
+}
+
+func TestThreatCatalogListsEveryThreatType(t *testing.T) {
+	declared := declaredThreatTypes(t)
+	if len(declared) == 0 {
+		t.Fatal("found no ThreatType constants")
+	}
+
+	catalog := ThreatCatalog()
+	ids := make([]ThreatType, len(catalog))
+	for i, info := range catalog {
+		ids[i] = info.ID
+		if info.Name == "" || info.Description == "" {
+			t.Errorf("catalog entry %q has no name or description", info.ID)
+		}
+		if !slices.Contains(declared, info.ID) {
+			t.Errorf("catalog entry %q is not a declared ThreatType", info.ID)
+		}
+	}
+	for _, threat := range declared {
+		if !slices.Contains(ids, threat) {
+			t.Errorf("ThreatType %q is missing from the catalog", threat)
+		}
+	}
+	if len(ids) != len(declared) {
+		t.Errorf("catalog has %d entries for %d threat types", len(ids), len(declared))
+	}
+}
+
+func TestThreatCatalogDefaultSeverity(t *testing.T) {
+	for _, info := range ThreatCatalog() {
+		want := SeverityHigh
+		if info.ID == ThreatTypeDataExtraction || info.ID == ThreatTypeSystemPromptLeak {
+			want = SeverityCritical
+		}
+		if info.DefaultSeverity != want {
+			t.Errorf("%s default severity = %q, want %q", info.ID, info.DefaultSeverity, want)
+		}
+	}
+}
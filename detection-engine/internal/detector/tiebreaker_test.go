@@ -0,0 +1,129 @@
+package detector
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// breakerRequests returns how many calls have gone through a model's circuit breaker
+func breakerRequests(t *testing.T, pipeline *FallbackPipeline, name string) int64 {
+	t.Helper()
+	cb, ok := pipeline.circuitBreakerSnapshot()[name]
+	if !ok {
+		t.Fatalf("no circuit breaker for %s", name)
+	}
+	return cb.GetStats().TotalRequests
+}
+
+func TestConsensusTieBreaker(t *testing.T) {
+	malicious := []ThreatType{ThreatTypeJailbreak}
+
+	tests := map[string]struct {
+		voters        []fakeModel
+		judge         fakeModel
+		wantCalled    bool
+		wantMalicious bool
+	}{
+		"even split decided malicious": {
+			voters:        []fakeModel{{name: "a", score: 0.9, threats: malicious}, {name: "b", score: 0.2}},
+			judge:         fakeModel{name: "judge", score: 0.95, threats: []ThreatType{ThreatTypeDataExtraction}},
+			wantCalled:    true,
+			wantMalicious: true,
+		},
+		"even split decided benign": {
+			voters:     []fakeModel{{name: "a", score: 0.9, threats: malicious}, {name: "b", score: 0.8, threats: malicious}, {name: "c", score: 0.5}, {name: "d", score: 0.55}},
+			judge:      fakeModel{name: "judge", score: 0.05},
+			wantCalled: true,
+		},
+		"majority with high disagreement": {
+			voters:        []fakeModel{{name: "a", score: 0.1}, {name: "b", score: 0.15}, {name: "c", score: 0.9, threats: malicious}},
+			judge:         fakeModel{name: "judge", score: 0.85, threats: malicious},
+			wantCalled:    true,
+			wantMalicious: true,
+		},
+		"agreement": {
+			voters: []fakeModel{{name: "a", score: 0.1}, {name: "b", score: 0.2}},
+			judge:  fakeModel{name: "judge", score: 0.95, threats: malicious},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pipeline := newFakeProviderPipeline(t, append(tt.voters, tt.judge)...)
+			pipeline.SetTieBreaker(tt.judge.name)
+
+			response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+				Text:   "what is the capital of France",
+				Config: &DetectionConfig{Strategy: StrategyConsensus, DetailedResponse: true},
+			})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+
+			calls := breakerRequests(t, pipeline, tt.judge.name)
+			if !tt.wantCalled {
+				if calls != 0 || response.TieBreaker != "" {
+					t.Errorf("tie-breaker called %d times (TieBreaker %q), want it held back", calls, response.TieBreaker)
+				}
+				if len(response.ModelResults) != len(tt.voters) {
+					t.Errorf("got %d model results, want only the %d voters", len(response.ModelResults), len(tt.voters))
+				}
+				return
+			}
+
+			if calls != 1 || response.TieBreaker != tt.judge.name {
+				t.Fatalf("tie-breaker called %d times (TieBreaker %q), want it to decide once", calls, response.TieBreaker)
+			}
+			if response.IsMalicious != tt.wantMalicious || response.Confidence != tt.judge.score {
+				t.Errorf("verdict = %v at %v, want the tie-breaker's %v at %v", response.IsMalicious, response.Confidence, tt.wantMalicious, tt.judge.score)
+			}
+			wantThreats := []string{}
+			if tt.wantMalicious {
+				wantThreats = threatStrings(tt.judge.threats)
+			}
+			if !slices.Equal(response.ThreatTypes, wantThreats) {
+				t.Errorf("ThreatTypes = %v, want the tie-breaker's %v", response.ThreatTypes, wantThreats)
+			}
+			if !strings.Contains(response.Reason, "tie broken by judge") {
+				t.Errorf("Reason = %q, want it to name the tie-breaker", response.Reason)
+			}
+			if len(response.ModelResults) != len(tt.voters)+1 {
+				t.Errorf("got %d model results, want the %d voters and the tie-breaker", len(response.ModelResults), len(tt.voters))
+			}
+		})
+	}
+}
+
+func TestConsensusTieBreakerFailureKeepsQuorumVerdict(t *testing.T) {
+	pipeline := newFakeProviderPipeline(t,
+		fakeModel{name: "a", score: 0.9, threats: []ThreatType{ThreatTypeJailbreak}},
+		fakeModel{name: "b", score: 0.2},
+		fakeModel{name: "judge", err: &ProviderError{Category: ErrorCategoryServer, Message: "upstream failure"}},
+	)
+	pipeline.SetTieBreaker("judge")
+
+	response, err := pipeline.Analyze(context.Background(), &DetectionRequest{
+		Text:   "what is the capital of France",
+		Config: &DetectionConfig{Strategy: StrategyConsensus},
+	})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	// One of two votes misses the default majority quorum of two
+	if response.IsMalicious || response.TieBreaker != "" {
+		t.Errorf("verdict = %v decided by %q, want the benign quorum verdict to stand", response.IsMalicious, response.TieBreaker)
+	}
+
+	// The failure is charged to the tie-breaker's own breaker, not the voters'
+	judge := pipeline.circuitBreakerSnapshot()["judge"].GetStats()
+	if judge.FailedRequests != 1 {
+		t.Errorf("judge breaker recorded %d failures, want 1", judge.FailedRequests)
+	}
+	for _, voter := range []string{"a", "b"} {
+		if stats := pipeline.circuitBreakerSnapshot()[voter].GetStats(); stats.FailedRequests != 0 {
+			t.Errorf("%s breaker recorded %d failures, want none", voter, stats.FailedRequests)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package detector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ToolCallDetectionRequest represents a model-chosen tool invocation whose arguments should be checked before execution
+type ToolCallDetectionRequest struct {
+	ToolName  string                 `json:"tool_name" binding:"required"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Config    *DetectionConfig       `json:"config,omitempty"`
+}
+
+// ToolCallScanner finds command injection, SQL injection and path traversal in tool arguments
+type ToolCallScanner struct {
+	patterns []documentPattern
+}
+
+// toolArgument is a string argument value with its key path ("query", "options.path", "files[0]")
+type toolArgument struct {
+	key   string
+	value string
+}
+
+// NewToolCallScanner creates a tool call scanner with the built-in argument patterns
+func NewToolCallScanner() *ToolCallScanner {
+	return &ToolCallScanner{
+		patterns: []documentPattern{
+			// Command injection
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)\brm\s+-[a-z]*(rf|fr)[a-z]*\b`), 0.9, "command injection: recursive delete"},
+			{ThreatTypeInjection, regexp.MustCompile(`\$\([^)]*\)`), 0.8, "command injection: command substitution"},
+			{ThreatTypeInjection, regexp.MustCompile("`[^`]+`"), 0.5, "command injection: backtick substitution"},
+			{ThreatTypeInjection, regexp.MustCompile(`(?i)(;|&&|\|\||\|)\s*(rm|curl|wget|bash|sh|zsh|nc|ncat|netcat|cat|chmod|chown|python3?|perl|ruby|php|base64|eval|exec|sudo|mkfifo|dd)\b`), 0.85, "command injection: chained shell command"},
+
+			// SQL injection
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i)'\s*(or|and)\s+'?\w+'?\s*=\s*'?\w+`), 0.85, "SQL injection: tautology"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i)\bunion\s+(all\s+)?select\b`), 0.85, "SQL injection: UNION SELECT"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i);\s*(drop|delete|truncate|alter|insert|update|create)\s+\w`), 0.85, "SQL injection: stacked query"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`'\s*(--|#|/\*)`), 0.6, "SQL injection: comment terminator"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i)\b(sleep|pg_sleep|benchmark)\s*\(|\bwaitfor\s+delay\b`), 0.6, "SQL injection: time delay"},
+
+			// Path traversal
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(\.\.[/\\]){1,}`), 0.7, "path traversal: parent directory"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i)(%2e|\.)(%2e|\.)(%2f|%5c)|%2e%2e[/\\]`), 0.8, "path traversal: encoded parent directory"},
+			{ThreatTypeDataExtraction, regexp.MustCompile(`(?i)(^|[\s'"=])(/etc/(passwd|shadow|sudoers)|/proc/self/|~/\.ssh/|~/\.aws/)`), 0.8, "path traversal: sensitive system path"},
+		},
+	}
+}
+
+// Scan checks every string argument separately; the call scores as its most
+// suspicious argument and each offending argument is reported as a match
+func (s *ToolCallScanner) Scan(req *ToolCallDetectionRequest) *DetectionResult {
+	startTime := time.Now()
+
+	result := &DetectionResult{
+		Method:      MethodHeuristic,
+		Score:       0.0,
+		ThreatTypes: make([]ThreatType, 0),
+		Reason:      fmt.Sprintf("tool call %q: no unsafe arguments found", req.ToolName),
+		Endpoint:    localEndpointName,
+	}
+
+	findings := make([]string, 0)
+	for _, arg := range flattenToolArguments("", req.Arguments) {
+		score := 0.0
+		descriptions := make([]string, 0)
+		for _, p := range s.patterns {
+			loc := p.pattern.FindStringIndex(arg.value)
+			if loc == nil {
+				continue
+			}
+			score = combineScores(score, p.weight)
+			result.ThreatTypes = appendThreat(result.ThreatTypes, p.threat)
+			descriptions = append(descriptions, p.description)
+
+			match := newMatch(arg.value, p.threat, loc[0], loc[1])
+			match.Argument = arg.key
+			result.Matches = append(result.Matches, match)
+		}
+
+		if len(descriptions) > 0 {
+			result.Score = max(result.Score, score)
+			findings = append(findings, fmt.Sprintf("%s (%s)", arg.key, strings.Join(descriptions, ", ")))
+		}
+	}
+
+	if len(findings) > 0 {
+		result.Reason = fmt.Sprintf("tool call %q: unsafe argument(s): %s", req.ToolName, strings.Join(findings, "; "))
+	}
+
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// flattenToolArguments collects the string values of nested JSON arguments in key order.
+// Numbers and booleans are skipped since they can't carry an injection.
+func flattenToolArguments(prefix string, value interface{}) []toolArgument {
+	switch v := value.(type) {
+	case string:
+		return []toolArgument{{key: prefix, value: v}}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		args := make([]toolArgument, 0, len(v))
+		for _, key := range keys {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			args = append(args, flattenToolArguments(path, v[key])...)
+		}
+		return args
+	case []interface{}:
+		args := make([]toolArgument, 0, len(v))
+		for i, item := range v {
+			args = append(args, flattenToolArguments(fmt.Sprintf("%s[%d]", prefix, i), item)...)
+		}
+		return args
+	default:
+		return nil
+	}
+}
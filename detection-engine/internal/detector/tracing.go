@@ -0,0 +1,58 @@
+package detector
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates pipeline and model call spans; it is a no-op until a tracer provider is installed
+var tracer = otel.Tracer("prompt-injection-detection/detector")
+
+// endPipelineSpan annotates the pipeline span with the detection outcome
+func endPipelineSpan(span trace.Span, response *DetectionResponse, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if response != nil {
+		span.SetAttributes(
+			attribute.Bool("detection.is_malicious", response.IsMalicious),
+			attribute.Float64("detection.confidence", response.Confidence),
+			attribute.String("detection.endpoint", response.Endpoint),
+		)
+	}
+	span.End()
+}
+
+// endModelSpan annotates a model call span with its outcome
+func endModelSpan(span trace.Span, err error) {
+	outcome := "success"
+	switch {
+	case err == ErrCircuitOpen:
+		outcome = "circuit_open"
+	case err != nil:
+		outcome = string(ErrorCategoryOf(err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("model.outcome", outcome))
+	span.End()
+}
+
+// tracePropagatingTransport adds the caller's trace context to outbound provider
+// requests so providers that honor W3C trace context join the request's trace
+type tracePropagatingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip injects trace headers into a copy of the request and sends it
+func (t tracePropagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,40 @@
+package detector
+
+// TruncationStrategy chooses which part of an over-long input is kept for a model
+type TruncationStrategy string
+
+const (
+	TruncateHead   TruncationStrategy = "head"   // Keep the beginning
+	TruncateTail   TruncationStrategy = "tail"   // Keep the end
+	TruncateMiddle TruncationStrategy = "middle" // Keep the centre
+	TruncateSmart  TruncationStrategy = "smart"  // Keep the beginning and the end (default)
+)
+
+// truncationMarker joins the kept beginning and end under smart truncation
+const truncationMarker = " ... "
+
+// truncateText shortens text to at most limit characters using the strategy,
+// reporting whether anything was cut
+func truncateText(text string, limit int, strategy TruncationStrategy) (string, bool) {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text, false
+	}
+
+	switch strategy {
+	case TruncateHead:
+		return string(runes[:limit]), true
+	case TruncateTail:
+		return string(runes[len(runes)-limit:]), true
+	case TruncateMiddle:
+		start := (len(runes) - limit) / 2
+		return string(runes[start : start+limit]), true
+	default:
+		kept := limit - len(truncationMarker)
+		if kept < 2 {
+			return string(runes[:limit]), true
+		}
+		head := (kept + 1) / 2
+		return string(runes[:head]) + truncationMarker + string(runes[len(runes)-(kept-head):]), true
+	}
+}
@@ -0,0 +1,107 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTruncateText(t *testing.T) {
+	const text = "0123456789abcdefghij" // 20 characters
+
+	tests := map[string]struct {
+		strategy      TruncationStrategy
+		limit         int
+		want          string
+		wantTruncated bool
+	}{
+		"head":             {TruncateHead, 8, "01234567", true},
+		"tail":             {TruncateTail, 8, "cdefghij", true},
+		"middle":           {TruncateMiddle, 8, "6789abcd", true},
+		"smart":            {TruncateSmart, 11, "012" + truncationMarker + "hij", true},
+		"smart odd split":  {TruncateSmart, 12, "0123" + truncationMarker + "hij", true},
+		"default is smart": {"", 11, "012" + truncationMarker + "hij", true},
+		"smart tiny limit": {TruncateSmart, 5, "01234", true},
+		"fits":             {TruncateHead, 20, text, false},
+		"no limit":         {TruncateTail, 0, text, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, truncated := truncateText(text, tt.limit, tt.strategy)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateText(%q, %d, %q) = %q %v, want %q %v", text, tt.limit, tt.strategy, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestTruncateTextKeepsWholeCharacters(t *testing.T) {
+	text := strings.Repeat("日本", 10)
+	for _, strategy := range []TruncationStrategy{TruncateHead, TruncateTail, TruncateMiddle, TruncateSmart} {
+		got, _ := truncateText(text, 9, strategy)
+		if len([]rune(got)) != 9 || strings.ContainsRune(got, '�') {
+			t.Errorf("%s: truncateText = %q, want 9 whole characters", strategy, got)
+		}
+	}
+}
+
+func TestClassifierReceivesTruncatedInput(t *testing.T) {
+	head := strings.Repeat("h", 400)
+	middle := strings.Repeat("m", 400)
+	tail := strings.Repeat("t", 400)
+	prompt := head + middle + tail
+
+	tests := map[TruncationStrategy]struct {
+		wantPrefix string
+		wantSuffix string
+	}{
+		TruncateHead:   {wantPrefix: head, wantSuffix: "m"},
+		TruncateTail:   {wantPrefix: "m", wantSuffix: tail},
+		TruncateMiddle: {wantPrefix: "hhh", wantSuffix: "ttt"},
+		TruncateSmart:  {wantPrefix: "h", wantSuffix: "t"},
+	}
+	for strategy, tt := range tests {
+		t.Run(string(strategy), func(t *testing.T) {
+			inputs := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Inputs string `json:"inputs"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				inputs <- body.Inputs
+				json.NewEncoder(w).Encode([][]HuggingFaceLabel{{{Label: "SAFE", Score: 0.99}}})
+			}))
+			t.Cleanup(server.Close)
+
+			config := DefaultLLMDetectorConfig()
+			config.EndpointDelay = 0
+			config.Retry = RetryPolicy{MaxAttempts: 1}
+			config.Chunking = TextChunking{}
+			config.Truncation = strategy
+			detector := NewLLMDetectorWithConfig(config)
+			t.Setenv(testAPIKeyEnv, "test-key")
+
+			result, err := detector.detectWithSpecificEndpoint(context.Background(), prompt, testModel("truncating", ProviderHuggingFace, server.URL), variantScopeOriginal)
+			if err != nil {
+				t.Fatalf("detectWithSpecificEndpoint: %v", err)
+			}
+
+			input := <-inputs
+			if len([]rune(input)) != huggingFaceMaxInput {
+				t.Errorf("classifier input has %d characters, want %d", len([]rune(input)), huggingFaceMaxInput)
+			}
+			if !strings.HasPrefix(input, tt.wantPrefix) || !strings.HasSuffix(input, tt.wantSuffix) {
+				t.Errorf("classifier input = %q...%q, want it to start with %q and end with %q", input[:10], input[len(input)-10:], tt.wantPrefix[:min(len(tt.wantPrefix), 10)], tt.wantSuffix)
+			}
+			if strategy == TruncateSmart && !strings.Contains(input, truncationMarker) {
+				t.Errorf("smart truncation input lacks the %q marker", truncationMarker)
+			}
+			if want := "input truncated to 500 of 1200 characters (" + string(strategy) + ")"; !strings.Contains(result.Reason, want) {
+				t.Errorf("Reason = %q, want it to contain %q", result.Reason, want)
+			}
+		})
+	}
+}
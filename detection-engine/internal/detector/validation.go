@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"fmt"
+)
+
+// FieldError is a request validation failure attributed to one field, named by its
+// JSON path (for example "config.confidence_threshold")
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error returns the underlying validation message
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error so sentinels such as ErrTextAndMessages still match
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError attributes err to the named field, nil when err is nil
+func fieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+// Validate checks every per-request detection option, returning a *FieldError naming
+// the first invalid one under "config.". A nil config is valid.
+func (c *DetectionConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ConfidenceThreshold < 0 || c.ConfidenceThreshold > 1 {
+		return fieldError("config.confidence_threshold", fmt.Errorf("invalid confidence_threshold %v: must be between 0 and 1", c.ConfidenceThreshold))
+	}
+	if err := validateStrategy(c.Strategy); err != nil {
+		return fieldError("config.strategy", err)
+	}
+	if c.Quorum < 0 {
+		return fieldError("config.quorum", fmt.Errorf("invalid quorum %d: must not be negative", c.Quorum))
+	}
+	for threat, threshold := range c.ThreatThresholds {
+		if threshold < 0 || threshold > 1 {
+			return fieldError("config.threat_thresholds."+threat, fmt.Errorf("invalid threat_thresholds[%s] %v: must be between 0 and 1", threat, threshold))
+		}
+	}
+	if c.TimeoutMs < 0 {
+		return fieldError("config.timeout_ms", fmt.Errorf("invalid timeout_ms %d: must not be negative", c.TimeoutMs))
+	}
+	if err := validateMode(c.Mode, c.Strategy); err != nil {
+		return fieldError("config.mode", err)
+	}
+	if err := validateMinBenignVotes(c); err != nil {
+		return fieldError("config.min_benign_votes", err)
+	}
+	return fieldError("config.action", validateAction(c.Action))
+}
+
+// validateStrategy checks that the aggregation strategy is supported
+func validateStrategy(strategy AggregationStrategy) error {
+	switch strategy {
+	case "", StrategyFirst, StrategyRace, StrategyConsensus:
+		return nil
+	}
+	return fmt.Errorf("invalid strategy %q: must be %q, %q or %q", strategy, StrategyFirst, StrategyRace, StrategyConsensus)
+}
+
+// Validate checks the session request's config, returning a *FieldError naming the offending field
+func (r *SessionDetectionRequest) Validate() error {
+	return r.Config.Validate()
+}
+
+// Validate checks the output request's config, returning a *FieldError naming the offending field
+func (r *OutputDetectionRequest) Validate() error {
+	return r.Config.Validate()
+}
+
+// Validate checks the document request's config, returning a *FieldError naming the offending field
+func (r *DocumentDetectionRequest) Validate() error {
+	return r.Config.Validate()
+}
+
+// Validate checks the tool call request's config, returning a *FieldError naming the offending field
+func (r *ToolCallDetectionRequest) Validate() error {
+	return r.Config.Validate()
+}
@@ -0,0 +1,65 @@
+package detector
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectionConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config    *DetectionConfig
+		wantField string // Empty when the config is valid
+	}{
+		"nil config":            {nil, ""},
+		"empty config":          {&DetectionConfig{}, ""},
+		"valid config":          {&DetectionConfig{ConfidenceThreshold: 0.7, Strategy: StrategyConsensus, Quorum: 2, ThreatThresholds: map[string]float64{"jailbreak": 0.5}, TimeoutMs: 500, Mode: ModeThorough, Action: ActionBlock}, ""},
+		"threshold bounds":      {&DetectionConfig{ConfidenceThreshold: 1}, ""},
+		"threshold above one":   {&DetectionConfig{ConfidenceThreshold: 1.5}, "config.confidence_threshold"},
+		"threshold negative":    {&DetectionConfig{ConfidenceThreshold: -0.1}, "config.confidence_threshold"},
+		"unknown strategy":      {&DetectionConfig{Strategy: "majority"}, "config.strategy"},
+		"negative quorum":       {&DetectionConfig{Quorum: -1}, "config.quorum"},
+		"threat threshold":      {&DetectionConfig{ThreatThresholds: map[string]float64{"jailbreak": 2}}, "config.threat_thresholds.jailbreak"},
+		"negative timeout":      {&DetectionConfig{TimeoutMs: -5}, "config.timeout_ms"},
+		"unknown mode":          {&DetectionConfig{Mode: "turbo"}, "config.mode"},
+		"fast with consensus":   {&DetectionConfig{Mode: ModeFast, Strategy: StrategyConsensus}, "config.mode"},
+		"negative benign votes": {&DetectionConfig{MinBenignVotes: -1}, "config.min_benign_votes"},
+		"unknown action":        {&DetectionConfig{Action: "quarantine"}, "config.action"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Validate = %v, want a *FieldError", err)
+			}
+			if fieldErr.Field != tt.wantField || fieldErr.Error() == "" {
+				t.Errorf("field error = %q (%v), want one naming %s", fieldErr.Field, fieldErr, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestRequestValidateChecksConfig(t *testing.T) {
+	invalid := &DetectionConfig{ConfidenceThreshold: 2}
+	requests := map[string]interface{ Validate() error }{
+		"detection": &DetectionRequest{Text: "hello", Config: invalid},
+		"session":   &SessionDetectionRequest{SessionID: "s", Messages: []Message{{Role: "user", Content: "hello"}}, Config: invalid},
+		"output":    &OutputDetectionRequest{Output: "hello", Config: invalid},
+		"document":  &DocumentDetectionRequest{Document: "hello", Config: invalid},
+		"tool call": &ToolCallDetectionRequest{ToolName: "search", Config: invalid},
+	}
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			var fieldErr *FieldError
+			if err := req.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != "config.confidence_threshold" {
+				t.Errorf("Validate = %v, want the invalid confidence_threshold named", err)
+			}
+		})
+	}
+}
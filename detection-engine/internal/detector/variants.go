@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+)
+
+// VariantAggregation controls how scores for the original text and its decoded variants are combined
+type VariantAggregation string
+
+const (
+	VariantAggregationMax      VariantAggregation = "max"      // Highest variant score wins (default)
+	VariantAggregationMean     VariantAggregation = "mean"     // Plain average over all variants
+	VariantAggregationWeighted VariantAggregation = "weighted" // Average with decoded variants counting half as much as the original
+)
+
+// decodedVariantWeight is the weight of each decoded variant under weighted aggregation
+const decodedVariantWeight = 0.5
+
+// originalVariant labels the undecoded input text
+const originalVariant = "original"
+
+// textVariant is the input text or one of its decodings
+type textVariant struct {
+	text     string
+	decoding string // "original", "base64", "hex", "rot13", ...
+}
+
+// variantVerdict is an endpoint's aggregated verdict over all variants of a text
+type variantVerdict struct {
+	score       float64
+	threatTypes []ThreatType
+	reason      string
+}
+
+// analyzeVariants sends every variant to the endpoint and combines the scores using
+// the configured aggregation. Threat types come from the highest-scoring variant,
+// which is named in the reason. Exhaustive analysis scores every variant even after
+// a confident hit. Returns the last error when no variant was analyzed.
+func (l *LLMDetector) analyzeVariants(ctx context.Context, endpoint LLMEndpoint, variants []textVariant, original string, exhaustive bool) (*variantVerdict, error) {
+	var lastError error
+	var top *variantVerdict
+	topDecoding := ""
+	weightedSum, totalWeight := 0.0, 0.0
+
+	for _, variant := range variants {
+		if err := ctx.Err(); err != nil {
+			// Keep a typed provider error (such as the call's own timeout) over the bare context error
+			if lastError == nil {
+				lastError = err
+			}
+			break
+		}
+
+		analysis, err := l.callEndpoint(ctx, endpoint, variant.text)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		score, threatTypes, reason := l.parseAnalysis(analysis, original)
+
+		weight := 1.0
+		if l.aggregation == VariantAggregationWeighted && variant.decoding != originalVariant {
+			weight = decodedVariantWeight
+		}
+		weightedSum += score * weight
+		totalWeight += weight
+
+		if top == nil || score > top.score {
+			top = &variantVerdict{score: score, threatTypes: threatTypes, reason: reason}
+			topDecoding = variant.decoding
+		}
+
+		// A confident hit decides max aggregation without waiting for the other variants
+		if l.aggregation == VariantAggregationMax && score >= 0.8 && !exhaustive {
+			break
+		}
+	}
+
+	if top == nil {
+		return nil, lastError
+	}
+
+	verdict := &variantVerdict{score: top.score, threatTypes: top.threatTypes, reason: top.reason}
+	if l.aggregation != VariantAggregationMax {
+		verdict.score = weightedSum / totalWeight
+	}
+
+	// Only name the triggering variant when there was more than one to choose from
+	if len(variants) > 1 {
+		label := "original text"
+		if topDecoding != originalVariant {
+			label = topDecoding + "-decoded variant"
+		}
+		verdict.reason = fmt.Sprintf("%s scored %.2f (%s of %d variants): %s", label, top.score, l.aggregation, len(variants), top.reason)
+	}
+
+	return verdict, nil
+}
@@ -0,0 +1,106 @@
+package detector
+
+import "time"
+
+// windowBuckets is the number of buckets in every rolling window
+const windowBuckets = 60
+
+// WindowCounts holds the event counts observed within a rolling window
+type WindowCounts struct {
+	Requests  int64 `json:"requests"`
+	Failures  int64 `json:"failures"`
+	Malicious int64 `json:"malicious"`
+}
+
+// windowBucket holds counts for one bucket-width slice of time
+type windowBucket struct {
+	epoch  int64 // Index of the time slice this bucket currently represents
+	counts WindowCounts
+}
+
+// rollingWindow is a ring buffer of fixed-width buckets covering windowBuckets x width.
+// Stale buckets are reset lazily when reused, so recording and reading are O(windowBuckets).
+type rollingWindow struct {
+	width   time.Duration
+	buckets [windowBuckets]windowBucket
+}
+
+// add records counts in the bucket for now, recycling it if it belongs to an older slice
+func (w *rollingWindow) add(now time.Time, counts WindowCounts) {
+	epoch := now.UnixNano() / int64(w.width)
+	bucket := &w.buckets[epoch%windowBuckets]
+	if bucket.epoch != epoch {
+		bucket.epoch = epoch
+		bucket.counts = WindowCounts{}
+	}
+	bucket.counts.Requests += counts.Requests
+	bucket.counts.Failures += counts.Failures
+	bucket.counts.Malicious += counts.Malicious
+}
+
+// sum totals every bucket that still falls within the window ending at now
+func (w *rollingWindow) sum(now time.Time) WindowCounts {
+	current := now.UnixNano() / int64(w.width)
+
+	var total WindowCounts
+	for _, bucket := range w.buckets {
+		if bucket.epoch <= current-windowBuckets || bucket.epoch > current {
+			continue
+		}
+		total.Requests += bucket.counts.Requests
+		total.Failures += bucket.counts.Failures
+		total.Malicious += bucket.counts.Malicious
+	}
+	return total
+}
+
+// rollingCounters tracks recent activity over 1 minute, 5 minute and 1 hour windows.
+// It has no lock of its own; Metrics guards it with its mutex.
+type rollingCounters struct {
+	now     func() time.Time // Injectable clock
+	windows []namedWindow
+}
+
+// namedWindow pairs a rolling window with the label it is reported under
+type namedWindow struct {
+	name   string
+	window *rollingWindow
+}
+
+// newRollingCounters creates the standard reporting windows using clock for timestamps
+func newRollingCounters(clock func() time.Time) *rollingCounters {
+	return &rollingCounters{
+		now: clock,
+		windows: []namedWindow{
+			{name: "1m", window: &rollingWindow{width: time.Minute / windowBuckets}},
+			{name: "5m", window: &rollingWindow{width: 5 * time.Minute / windowBuckets}},
+			{name: "1h", window: &rollingWindow{width: time.Hour / windowBuckets}},
+		},
+	}
+}
+
+// record adds one request outcome to every window
+func (r *rollingCounters) record(failed, malicious bool) {
+	counts := WindowCounts{Requests: 1}
+	if failed {
+		counts.Failures = 1
+	}
+	if malicious {
+		counts.Malicious = 1
+	}
+
+	now := r.now()
+	for _, named := range r.windows {
+		named.window.add(now, counts)
+	}
+}
+
+// snapshot returns the current counts for every window keyed by its label
+func (r *rollingCounters) snapshot() map[string]WindowCounts {
+	now := r.now()
+	counts := make(map[string]WindowCounts, len(r.windows))
+	for _, named := range r.windows {
+		counts[named.name] = named.window.sum(now)
+	}
+	return counts
+}
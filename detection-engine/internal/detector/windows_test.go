@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is an injectable clock advanced by hand
+type fakeClock struct {
+	now time.Time
+}
+
+// Now returns the current fake time
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// Advance moves the fake time forward by d
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestRollingCountersAgeOut(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	counters := newRollingCounters(clock.Now)
+
+	counters.record(false, true)
+	counters.record(true, false)
+	counters.record(false, false)
+	recorded := WindowCounts{Requests: 3, Failures: 1, Malicious: 1}
+
+	steps := []struct {
+		advance time.Duration
+		want    map[string]WindowCounts
+	}{
+		{0, map[string]WindowCounts{"1m": recorded, "5m": recorded, "1h": recorded}},
+		{59 * time.Second, map[string]WindowCounts{"1m": recorded, "5m": recorded, "1h": recorded}},
+		{time.Second, map[string]WindowCounts{"1m": {}, "5m": recorded, "1h": recorded}},
+		{4 * time.Minute, map[string]WindowCounts{"1m": {}, "5m": {}, "1h": recorded}},
+		{55 * time.Minute, map[string]WindowCounts{"1m": {}, "5m": {}, "1h": {}}},
+	}
+	elapsed := time.Duration(0)
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		elapsed += step.advance
+
+		got := counters.snapshot()
+		for name, want := range step.want {
+			if got[name] != want {
+				t.Errorf("after %v: %s window = %+v, want %+v", elapsed, name, got[name], want)
+			}
+		}
+	}
+}
+
+func TestRollingCountersKeepRecentEvents(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	counters := newRollingCounters(clock.Now)
+
+	counters.record(false, false)
+	clock.Advance(30 * time.Second)
+	counters.record(false, true)
+	clock.Advance(45 * time.Second)
+
+	// The first event left the 1 minute window, the second is still in it
+	got := counters.snapshot()
+	if got["1m"] != (WindowCounts{Requests: 1, Malicious: 1}) {
+		t.Errorf("1m window = %+v, want only the recent event", got["1m"])
+	}
+	if got["5m"] != (WindowCounts{Requests: 2, Malicious: 1}) {
+		t.Errorf("5m window = %+v, want both events", got["5m"])
+	}
+}
+
+func TestRollingWindowRecyclesBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	counters := newRollingCounters(clock.Now)
+
+	counters.record(true, true)
+
+	// Exactly one lap later the same ring slot is reused and must not keep the old counts
+	clock.Advance(time.Minute)
+	counters.record(false, false)
+
+	if got := counters.snapshot()["1m"]; got != (WindowCounts{Requests: 1}) {
+		t.Errorf("1m window = %+v, want only the new event in the recycled bucket", got)
+	}
+	if got := counters.snapshot()["1h"]; got != (WindowCounts{Requests: 2, Failures: 1, Malicious: 1}) {
+		t.Errorf("1h window = %+v, want both events", got)
+	}
+}
+
+func TestMetricsWindowCounts(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.RecordSuccess(time.Millisecond, &DetectionResponse{IsMalicious: true})
+	metrics.RecordFailure(time.Millisecond)
+
+	for _, name := range []string{"1m", "5m", "1h"} {
+		if got := metrics.GetWindowCounts()[name]; got != (WindowCounts{Requests: 2, Failures: 1, Malicious: 1}) {
+			t.Errorf("%s window = %+v, want the two recorded requests", name, got)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: detection/v1/detection.proto
+
+package detectionpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	DetectionService_Detect_FullMethodName       = "/detection.v1.DetectionService/Detect"
+	DetectionService_DetectBatch_FullMethodName  = "/detection.v1.DetectionService/DetectBatch"
+	DetectionService_DetectStream_FullMethodName = "/detection.v1.DetectionService/DetectStream"
+)
+
+// DetectionServiceClient is the client API for DetectionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DetectionServiceClient interface {
+	// Detect analyzes a single prompt
+	Detect(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error)
+	// DetectBatch analyzes several texts and returns every result at once
+	DetectBatch(ctx context.Context, in *DetectBatchRequest, opts ...grpc.CallOption) (*DetectBatchResponse, error)
+	// DetectStream analyzes prompts as they arrive on a long-lived stream,
+	// answering each request in order
+	DetectStream(ctx context.Context, opts ...grpc.CallOption) (DetectionService_DetectStreamClient, error)
+}
+
+type detectionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDetectionServiceClient(cc grpc.ClientConnInterface) DetectionServiceClient {
+	return &detectionServiceClient{cc}
+}
+
+func (c *detectionServiceClient) Detect(ctx context.Context, in *DetectRequest, opts ...grpc.CallOption) (*DetectResponse, error) {
+	out := new(DetectResponse)
+	err := c.cc.Invoke(ctx, DetectionService_Detect_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *detectionServiceClient) DetectBatch(ctx context.Context, in *DetectBatchRequest, opts ...grpc.CallOption) (*DetectBatchResponse, error) {
+	out := new(DetectBatchResponse)
+	err := c.cc.Invoke(ctx, DetectionService_DetectBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *detectionServiceClient) DetectStream(ctx context.Context, opts ...grpc.CallOption) (DetectionService_DetectStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DetectionService_ServiceDesc.Streams[0], DetectionService_DetectStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &detectionServiceDetectStreamClient{stream}
+	return x, nil
+}
+
+type DetectionService_DetectStreamClient interface {
+	Send(*DetectRequest) error
+	Recv() (*DetectResponse, error)
+	grpc.ClientStream
+}
+
+type detectionServiceDetectStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *detectionServiceDetectStreamClient) Send(m *DetectRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *detectionServiceDetectStreamClient) Recv() (*DetectResponse, error) {
+	m := new(DetectResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DetectionServiceServer is the server API for DetectionService service.
+// All implementations must embed UnimplementedDetectionServiceServer
+// for forward compatibility
+type DetectionServiceServer interface {
+	// Detect analyzes a single prompt
+	Detect(context.Context, *DetectRequest) (*DetectResponse, error)
+	// DetectBatch analyzes several texts and returns every result at once
+	DetectBatch(context.Context, *DetectBatchRequest) (*DetectBatchResponse, error)
+	// DetectStream analyzes prompts as they arrive on a long-lived stream,
+	// answering each request in order
+	DetectStream(DetectionService_DetectStreamServer) error
+	mustEmbedUnimplementedDetectionServiceServer()
+}
+
+// UnimplementedDetectionServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDetectionServiceServer struct {
+}
+
+func (UnimplementedDetectionServiceServer) Detect(context.Context, *DetectRequest) (*DetectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Detect not implemented")
+}
+func (UnimplementedDetectionServiceServer) DetectBatch(context.Context, *DetectBatchRequest) (*DetectBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DetectBatch not implemented")
+}
+func (UnimplementedDetectionServiceServer) DetectStream(DetectionService_DetectStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method DetectStream not implemented")
+}
+func (UnimplementedDetectionServiceServer) mustEmbedUnimplementedDetectionServiceServer() {}
+
+// UnsafeDetectionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DetectionServiceServer will
+// result in compilation errors.
+type UnsafeDetectionServiceServer interface {
+	mustEmbedUnimplementedDetectionServiceServer()
+}
+
+func RegisterDetectionServiceServer(s grpc.ServiceRegistrar, srv DetectionServiceServer) {
+	s.RegisterService(&DetectionService_ServiceDesc, srv)
+}
+
+func _DetectionService_Detect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetectionServiceServer).Detect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DetectionService_Detect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetectionServiceServer).Detect(ctx, req.(*DetectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DetectionService_DetectBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DetectionServiceServer).DetectBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DetectionService_DetectBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DetectionServiceServer).DetectBatch(ctx, req.(*DetectBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DetectionService_DetectStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DetectionServiceServer).DetectStream(&detectionServiceDetectStreamServer{stream})
+}
+
+type DetectionService_DetectStreamServer interface {
+	Send(*DetectResponse) error
+	Recv() (*DetectRequest, error)
+	grpc.ServerStream
+}
+
+type detectionServiceDetectStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *detectionServiceDetectStreamServer) Send(m *DetectResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *detectionServiceDetectStreamServer) Recv() (*DetectRequest, error) {
+	m := new(DetectRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DetectionService_ServiceDesc is the grpc.ServiceDesc for DetectionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DetectionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "detection.v1.DetectionService",
+	HandlerType: (*DetectionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Detect",
+			Handler:    _DetectionService_Detect_Handler,
+		},
+		{
+			MethodName: "DetectBatch",
+			Handler:    _DetectionService_DetectBatch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DetectStream",
+			Handler:       _DetectionService_DetectStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "detection/v1/detection.proto",
+}
@@ -0,0 +1,9 @@
+// Package grpcapi serves the detection pipeline over gRPC.
+//
+// The detectionpb package is generated from api/proto/detection/v1/detection.proto;
+// regenerate it after changing the proto with:
+//
+//	go generate ./internal/grpcapi
+package grpcapi
+
+//go:generate protoc -I ../../api/proto --go_out=. --go_opt=module=prompt-injection-detection/internal/grpcapi --go-grpc_out=. --go-grpc_opt=module=prompt-injection-detection/internal/grpcapi detection/v1/detection.proto
@@ -0,0 +1,167 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/grpcapi/detectionpb"
+)
+
+// Server implements the DetectionService on top of a detection pipeline
+type Server struct {
+	detectionpb.UnimplementedDetectionServiceServer
+
+	analyzer     detector.Analyzer
+	workers      int
+	maxBatchSize int
+	logger       *logrus.Logger
+}
+
+// NewServer creates a gRPC server with the detection service registered
+func NewServer(analyzer detector.Analyzer, workers, maxBatchSize int, logger *logrus.Logger) *grpc.Server {
+	server := grpc.NewServer()
+	detectionpb.RegisterDetectionServiceServer(server, &Server{
+		analyzer:     analyzer,
+		workers:      workers,
+		maxBatchSize: maxBatchSize,
+		logger:       logger,
+	})
+	return server
+}
+
+// Detect analyzes a single prompt
+func (s *Server) Detect(ctx context.Context, req *detectionpb.DetectRequest) (*detectionpb.DetectResponse, error) {
+	return s.detect(ctx, req)
+}
+
+// DetectBatch analyzes several texts concurrently and returns the results in input order
+func (s *Server) DetectBatch(ctx context.Context, req *detectionpb.DetectBatchRequest) (*detectionpb.DetectBatchResponse, error) {
+	if len(req.GetTexts()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one text is required")
+	}
+	if len(req.GetTexts()) > s.maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "batch size cannot exceed %d texts", s.maxBatchSize)
+	}
+
+	results := make([]*detectionpb.BatchResult, len(req.GetTexts()))
+	for item := range detector.StreamBatch(ctx, s.analyzer, req.GetTexts(), toConfig(req.GetConfig()), s.workers) {
+		results[item.Index] = &detectionpb.BatchResult{
+			Index:  int32(item.Index),
+			Result: fromResponse(item.Response),
+			Error:  item.Error,
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	return &detectionpb.DetectBatchResponse{Results: results}, nil
+}
+
+// DetectStream answers each request received on the stream in order until the client closes it
+func (s *Server) DetectStream(stream detectionpb.DetectionService_DetectStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := s.detect(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// detect validates and analyzes one request, mapping pipeline errors to gRPC status codes
+func (s *Server) detect(ctx context.Context, req *detectionpb.DetectRequest) (*detectionpb.DetectResponse, error) {
+	detectionReq := toRequest(req)
+	if err := detectionReq.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	response, err := s.analyzer.Analyze(ctx, detectionReq)
+	if err != nil {
+		s.logger.WithError(err).Error("gRPC detection failed")
+		return nil, statusFromError(ctx, err)
+	}
+	return fromResponse(response), nil
+}
+
+// statusFromError maps a pipeline error to the closest gRPC status
+func statusFromError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.Is(err, detector.ErrAllModelsFailed), errors.Is(err, detector.ErrCostBudgetExceeded):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, detector.ErrDispatchSaturated):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toRequest converts a proto request into a pipeline request
+func toRequest(req *detectionpb.DetectRequest) *detector.DetectionRequest {
+	messages := make([]detector.Message, 0, len(req.GetMessages()))
+	for _, message := range req.GetMessages() {
+		messages = append(messages, detector.Message{Role: message.GetRole(), Content: message.GetContent()})
+	}
+
+	return &detector.DetectionRequest{
+		Text:     req.GetText(),
+		Messages: messages,
+		Config:   toConfig(req.GetConfig()),
+	}
+}
+
+// toConfig converts proto detection options, nil when none were sent
+func toConfig(config *detectionpb.DetectionConfig) *detector.DetectionConfig {
+	if config == nil {
+		return nil
+	}
+
+	return &detector.DetectionConfig{
+		ConfidenceThreshold: config.GetConfidenceThreshold(),
+		DetailedResponse:    config.GetDetailedResponse(),
+		Strategy:            detector.AggregationStrategy(config.GetStrategy()),
+		Quorum:              int(config.GetQuorum()),
+		LocalOnly:           config.LocalOnly,
+		Action:              detector.Action(config.GetAction()),
+		ThreatThresholds:    config.GetThreatThresholds(),
+		TimeoutMs:           int(config.GetTimeoutMs()),
+	}
+}
+
+// fromResponse converts a pipeline response into its proto form
+func fromResponse(response *detector.DetectionResponse) *detectionpb.DetectResponse {
+	if response == nil {
+		return nil
+	}
+
+	return &detectionpb.DetectResponse{
+		IsMalicious:      response.IsMalicious,
+		Confidence:       response.Confidence,
+		ThreatTypes:      response.ThreatTypes,
+		ProcessingTimeMs: response.ProcessingTimeMs,
+		Reason:           response.Reason,
+		Endpoint:         response.Endpoint,
+		Action:           string(response.Action),
+		Blocked:          response.Blocked,
+		SanitizedText:    response.SanitizedText,
+	}
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+// requestValidator is implemented by request types that check their own fields after decoding
+type requestValidator interface {
+	Validate() error
+}
+
+// bindJSON decodes the request body into req and validates it, translating malformed
+// JSON, wrong field types, binding tags and validation failures into invalid_payload
+// errors that name the offending field
+func bindJSON(c *gin.Context, req any) *apierror.APIError {
+	if err := c.ShouldBindJSON(req); err != nil {
+		return bindingError(req, err)
+	}
+	if v, ok := req.(requestValidator); ok {
+		if err := v.Validate(); err != nil {
+			return validationError(err)
+		}
+	}
+	return nil
+}
+
+// invalidPayload returns the invalid_payload error for a request problem
+func invalidPayload(details string) *apierror.APIError {
+	return apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid request payload").WithDetails(details)
+}
+
+// validationError maps a request validation failure to an invalid_payload error,
+// naming the field when the failure is a *detector.FieldError
+func validationError(err error) *apierror.APIError {
+	var fieldErr *detector.FieldError
+	if errors.As(err, &fieldErr) {
+		return invalidPayload(fieldErr.Error()).WithField(fieldErr.Field)
+	}
+	return invalidPayload(err.Error())
+}
+
+// bindingError maps a ShouldBindJSON failure to an invalid_payload error a client can act on
+func bindingError(req any, err error) *apierror.APIError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return invalidPayload("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidPayload("malformed JSON: unexpected end of input")
+	case errors.As(err, &syntaxErr):
+		return invalidPayload(fmt.Sprintf("malformed JSON at byte %d: %s", syntaxErr.Offset, syntaxErr))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return invalidPayload(fmt.Sprintf("request body must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value))
+		}
+		return invalidPayload(fmt.Sprintf("%s must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)).WithField(typeErr.Field)
+	case errors.As(err, &validationErrs) && len(validationErrs) > 0:
+		fieldErr := validationErrs[0]
+		field := jsonFieldPath(req, fieldErr.StructNamespace())
+		return invalidPayload(bindingTagMessage(field, fieldErr)).WithField(field)
+	}
+	return invalidPayload(err.Error())
+}
+
+// bindingTagMessage describes a failed binding tag on the named field
+func bindingTagMessage(field string, fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " is required"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at least %s characters", field, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must have at least %s entries", field, fieldErr.Param())
+	}
+	return fmt.Sprintf("%s failed the %q check", field, fieldErr.Tag())
+}
+
+// jsonFieldPath converts a validator struct namespace ("DetectionRequest.Messages[0].Role")
+// into the JSON path clients sent ("messages[0].role")
+func jsonFieldPath(req any, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:] // Drop the root type name
+	}
+
+	t := reflect.TypeOf(req)
+	path := make([]string, 0, len(parts))
+	for _, part := range parts {
+		name, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			path = append(path, part)
+			t = nil
+			continue
+		}
+		structField, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, part)
+			t = nil
+			continue
+		}
+
+		jsonName, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if jsonName == "" || jsonName == "-" {
+			jsonName = structField.Name
+		}
+		path = append(path, jsonName+index)
+
+		t = structField.Type
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if index != "" && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	if t == nil {
+		return "a valid value"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return "a valid value"
+}
+
+// validateBatch checks a batch request's texts against the size limit and its
+// shared config, naming the offending field
+func validateBatch(texts []string, config *detector.DetectionConfig, maxBatchSize int) *apierror.APIError {
+	if len(texts) == 0 {
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "At least one text is required").WithField("texts")
+	}
+	if len(texts) > maxBatchSize {
+		return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge,
+			fmt.Sprintf("Batch size cannot exceed %d texts", maxBatchSize)).WithField("texts")
+	}
+	if err := config.Validate(); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
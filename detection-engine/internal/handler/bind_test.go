@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+func TestInvalidDetectionRequestFields(t *testing.T) {
+	h := NewFallbackDetectionHandler(newTestFallbackPipeline(t, testModel("unreachable", 1, true)), newTestLogger())
+	router := gin.New()
+	router.POST("/v1/detect", h.DetectInjection)
+	router.POST("/v1/detect/session", h.DetectSession)
+
+	tests := map[string]struct {
+		path      string
+		body      string
+		wantField string // Empty when the error cannot be tied to a field
+		wantIn    string // Expected in the error details
+	}{
+		"empty body":             {"/v1/detect", ``, "", "empty"},
+		"malformed JSON":         {"/v1/detect", `{"text": "hello"`, "", "malformed JSON"},
+		"invalid JSON syntax":    {"/v1/detect", `{"text": hello}`, "", "malformed JSON at byte"},
+		"not an object":          {"/v1/detect", `"hello"`, "", "must be an object"},
+		"text not a string":      {"/v1/detect", `{"text": 42}`, "text", "text must be a string"},
+		"threshold not a number": {"/v1/detect", `{"text": "hi", "config": {"confidence_threshold": "high"}}`, "config.confidence_threshold", "must be a number"},
+		"threshold out of range": {"/v1/detect", `{"text": "hi", "config": {"confidence_threshold": 1.5}}`, "config.confidence_threshold", "between 0 and 1"},
+		"threat threshold":       {"/v1/detect", `{"text": "hi", "config": {"threat_thresholds": {"jailbreak": -1}}}`, "config.threat_thresholds.jailbreak", "between 0 and 1"},
+		"unknown strategy":       {"/v1/detect", `{"text": "hi", "config": {"strategy": "majority"}}`, "config.strategy", "majority"},
+		"negative quorum":        {"/v1/detect", `{"text": "hi", "config": {"quorum": -2}}`, "config.quorum", "negative"},
+		"negative timeout":       {"/v1/detect", `{"text": "hi", "config": {"timeout_ms": -1}}`, "config.timeout_ms", "negative"},
+		"unknown mode":           {"/v1/detect", `{"text": "hi", "config": {"mode": "turbo"}}`, "config.mode", "turbo"},
+		"unknown action":         {"/v1/detect", `{"text": "hi", "config": {"action": "quarantine"}}`, "config.action", "quarantine"},
+		"text and messages":      {"/v1/detect", `{"text": "hi", "messages": [{"role": "user", "content": "hi"}]}`, "messages", "mutually exclusive"},
+		"missing session id":     {"/v1/detect/session", `{"messages": [{"role": "user", "content": "hi"}]}`, "session_id", "session_id is required"},
+		"empty session messages": {"/v1/detect/session", `{"session_id": "s1", "messages": []}`, "messages", "at least 1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := postDetect(router, tt.path, tt.body, "")
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", recorder.Code, recorder.Body)
+			}
+			var apiErr apierror.APIError
+			decodeBody(t, recorder, &apiErr)
+			if apiErr.Code != apierror.CodeInvalidPayload {
+				t.Errorf("code = %q, want %q", apiErr.Code, apierror.CodeInvalidPayload)
+			}
+			if apiErr.Field != tt.wantField {
+				t.Errorf("field = %q, want %q", apiErr.Field, tt.wantField)
+			}
+			if !strings.Contains(apiErr.Details, tt.wantIn) {
+				t.Errorf("details = %q, want it to mention %q", apiErr.Details, tt.wantIn)
+			}
+		})
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	tests := map[string]struct {
+		texts      []string
+		config     *detector.DetectionConfig
+		wantStatus int // Zero when the batch is valid
+		wantCode   apierror.Code
+		wantField  string
+	}{
+		"valid":          {[]string{"a", "b"}, nil, 0, "", ""},
+		"at the limit":   {[]string{"a", "b", "c"}, nil, 0, "", ""},
+		"empty":          {nil, nil, http.StatusBadRequest, apierror.CodeInvalidPayload, "texts"},
+		"too many":       {[]string{"a", "b", "c", "d"}, nil, http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge, "texts"},
+		"invalid config": {[]string{"a"}, &detector.DetectionConfig{Quorum: -1}, http.StatusBadRequest, apierror.CodeInvalidPayload, "config.quorum"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			apiErr := validateBatch(tt.texts, tt.config, 3)
+			if tt.wantStatus == 0 {
+				if apiErr != nil {
+					t.Errorf("validateBatch = %+v, want nil", apiErr)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatal("validateBatch = nil, want an error")
+			}
+			if apiErr.Status != tt.wantStatus || apiErr.Code != tt.wantCode || apiErr.Field != tt.wantField {
+				t.Errorf("validateBatch = %d %q on %q, want %d %q on %q", apiErr.Status, apiErr.Code, apiErr.Field, tt.wantStatus, tt.wantCode, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestBatchJobRejectsOversizedBatch(t *testing.T) {
+	router := newJobsRouter(t)
+
+	texts := make([]string, 11)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	recorder := serveJSON(t, router, http.MethodPost, "/v1/detect/batch/async", gin.H{"texts": texts})
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413: %s", recorder.Code, recorder.Body)
+	}
+	var apiErr apierror.APIError
+	decodeBody(t, recorder, &apiErr)
+	if apiErr.Code != apierror.CodeRequestTooLarge || apiErr.Field != "texts" {
+		t.Errorf("body = %+v, want request_too_large on texts", apiErr)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"prompt-injection-detection/internal/apierror"
 	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/logging"
 )
 
 // DetectionHandler handles HTTP requests for prompt injection detection
@@ -28,56 +30,165 @@ func NewDetectionHandler(pipeline *detector.Pipeline, logger *logrus.Logger) *De
 
 // DetectInjection handles POST /v1/detect requests
 func (h *DetectionHandler) DetectInjection(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
 	var req detector.DetectionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Invalid request payload")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request payload",
-			"details": err.Error(),
-		})
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+	if err := applyThresholdQuery(c, &req); err != nil {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid threshold").WithDetails(err.Error()))
+		return
+	}
+	if err := applyExplainQuery(c, &req); err != nil {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid explain option").WithDetails(err.Error()))
 		return
 	}
 
 	// Remove validation - let pipeline handle empty text gracefully
 
 	// Set timeout for detection
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	// Log request (be careful not to log sensitive content)
-	h.logger.WithFields(logrus.Fields{
-		"text_length": len(req.Text),
-		"config":      req.Config,
-		"client_ip":   c.ClientIP(),
+	logger.WithFields(logrus.Fields{
+		"text_length":   len(req.Text),
+		"message_count": len(req.Messages),
+		"config":        req.Config,
+		"client_ip":     c.ClientIP(),
 	}).Info("Processing detection request")
 
 	// Process detection
 	response, err := h.pipeline.Analyze(ctx, &req)
 	if err != nil {
-		h.logger.WithError(err).Error("Detection analysis failed")
+		logger.WithError(err).Error("Detection analysis failed")
 
-		// Determine appropriate HTTP status code
-		statusCode := http.StatusInternalServerError
-		if ctx.Err() == context.DeadlineExceeded {
-			statusCode = http.StatusRequestTimeout
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error":   "Detection analysis failed",
-			"details": err.Error(),
-		})
+		apierror.Render(c, detectionError(ctx, err))
 		return
 	}
 
 	// Log response
-	h.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"is_malicious":       response.IsMalicious,
 		"confidence":         response.Confidence,
 		"threat_types":       response.ThreatTypes,
 		"processing_time_ms": response.ProcessingTimeMs,
 	}).Info("Detection completed")
 
-	// Return response
+	// Return response, or 304 if the client already has it
+	renderDetection(c, &req, response)
+}
+
+// DetectOutput handles POST /v1/detect/output requests for model-generated text
+func (h *DetectionHandler) DetectOutput(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.OutputDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid output request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"output_length":        len(req.Output),
+		"system_prompt_length": len(req.SystemPrompt),
+		"canaries":             len(req.Canaries),
+		"client_ip":            c.ClientIP(),
+	}).Info("Processing output detection request")
+
+	response, err := h.pipeline.AnalyzeOutput(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Output analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Output analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious": response.IsMalicious,
+		"confidence":   response.Confidence,
+		"threat_types": response.ThreatTypes,
+	}).Info("Output detection completed")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DetectDocument handles POST /v1/detect/document requests for untrusted document content
+func (h *DetectionHandler) DetectDocument(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.DocumentDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid document request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"document_length": len(req.Document),
+		"client_ip":       c.ClientIP(),
+	}).Info("Processing document detection request")
+
+	response, err := h.pipeline.AnalyzeDocument(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Document analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Document analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious":        response.IsMalicious,
+		"confidence":          response.Confidence,
+		"threat_types":        response.ThreatTypes,
+		"suspicious_passages": len(response.Matches),
+	}).Info("Document detection completed")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DetectToolCall handles POST /v1/detect/tool-call requests for model-chosen tool arguments
+func (h *DetectionHandler) DetectToolCall(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.ToolCallDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid tool call request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"tool_name":      req.ToolName,
+		"argument_count": len(req.Arguments),
+		"client_ip":      c.ClientIP(),
+	}).Info("Processing tool call detection request")
+
+	response, err := h.pipeline.AnalyzeToolCall(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Tool call analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Tool call analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious": response.IsMalicious,
+		"confidence":   response.Confidence,
+		"threat_types": response.ThreatTypes,
+		"tool_name":    req.ToolName,
+	}).Info("Tool call detection completed")
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -119,6 +230,38 @@ func (h *DetectionHandler) DiagnoseLLM(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// Liveness handles GET /live requests; it succeeds whenever the process is serving HTTP
+func (h *DetectionHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Readiness handles GET /ready requests; it fails while no detection backend is usable
+func (h *DetectionHandler) Readiness(c *gin.Context) {
+	ready, reason := h.pipeline.Readiness()
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": reason})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "reason": reason})
+}
+
+// GetSelfTest handles GET /v1/selftest requests with the most recent self-test report
+func (h *DetectionHandler) GetSelfTest(c *gin.Context) {
+	report := h.pipeline.LastSelfTest()
+	if report == nil {
+		apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "No self-test has run").WithDetails("Enable selftest.enabled to run the self-test at startup"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRecentDetections handles GET /v1/detections/recent requests
+func (h *DetectionHandler) GetRecentDetections(c *gin.Context) {
+	writeRecentDetections(c, h.pipeline.RecentDetections())
+}
+
 // HealthCheck handles GET /health requests
 func (h *DetectionHandler) HealthCheck(c *gin.Context) {
 	health := h.pipeline.GetHealth()
@@ -142,14 +285,23 @@ func (h *DetectionHandler) GetMetrics(c *gin.Context) {
 		successRate = float64(metrics.RequestsSuccessful) / float64(metrics.RequestsTotal)
 	}
 
+	slotsInUse, slotsTotal := h.pipeline.DispatchSlots()
+	latency := metrics.GetLatencyPercentiles()
+
 	response := gin.H{
 		"requests_total":       metrics.GetRequestsTotal(),
 		"requests_successful":  metrics.RequestsSuccessful,
 		"requests_failed":      metrics.RequestsFailed,
 		"success_rate":         successRate,
 		"average_latency_ms":   metrics.GetAverageLatency().Milliseconds(),
+		"p50_latency_ms":       latency.P50.Milliseconds(),
+		"p95_latency_ms":       latency.P95.Milliseconds(),
+		"p99_latency_ms":       latency.P99.Milliseconds(),
+		"windows":              metrics.GetWindowCounts(),
 		"detection_method":     "llm_only",
 		"detections_by_threat": metrics.DetectionsByThreat,
+		"dispatch_in_use":      slotsInUse,
+		"dispatch_capacity":    slotsTotal,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -162,37 +314,31 @@ func (h *DetectionHandler) DetectBatch(c *gin.Context) {
 		Config *detector.DetectionConfig `json:"config,omitempty"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request payload",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	// Validate batch size
-	if len(req.Texts) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "At least one text is required",
-		})
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		apierror.Render(c, apiErr)
 		return
 	}
 
-	if len(req.Texts) > 100 { // Limit batch size
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Batch size cannot exceed 100 texts",
-		})
+	// Validate batch size and the shared config
+	if apiErr := validateBatch(req.Texts, req.Config, 100); apiErr != nil {
+		apierror.Render(c, apiErr)
 		return
 	}
 
 	// Process each text
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
 	defer cancel()
 
 	responses := make([]*detector.DetectionResponse, len(req.Texts))
 	errors := make([]string, len(req.Texts))
 
 	for i, text := range req.Texts {
+		// Stop early on timeout or server shutdown rather than starting new detections
+		if ctx.Err() != nil {
+			errors[i] = ctx.Err().Error()
+			continue
+		}
+
 		detectionReq := detector.DetectionRequest{
 			Text:   text,
 			Config: req.Config,
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+// errAllModelsUnavailable is returned when every model in the fallback chain failed
+var errAllModelsUnavailable = apierror.New(http.StatusServiceUnavailable, apierror.CodeAllModelsUnavailable,
+	"All detection models are temporarily unavailable").WithDetails("Please try again in a few minutes")
+
+// detectionError maps a pipeline error to the API error returned to the client
+func detectionError(ctx context.Context, err error) *apierror.APIError {
+	switch {
+	case errors.Is(err, detector.ErrCostBudgetExceeded):
+		return apierror.New(http.StatusServiceUnavailable, apierror.CodeBudgetExhausted, "Daily cost budget exhausted").
+			WithDetails("Paid models resume at UTC midnight; no free model was available")
+	case errors.Is(err, detector.ErrAllModelsFailed):
+		return errAllModelsUnavailable.WithRetryAfter(60) // Suggest retry after 60 seconds
+	case ctx.Err() == context.DeadlineExceeded:
+		return apierror.New(http.StatusRequestTimeout, apierror.CodeTimeout, "Detection analysis timed out").WithDetails(err.Error())
+	case errors.Is(err, detector.ErrDispatchSaturated):
+		return apierror.New(http.StatusServiceUnavailable, apierror.CodeOverloaded, "Detection capacity exhausted").WithDetails(err.Error())
+	case detector.ErrorCategoryOf(err) == detector.ErrorCategoryRateLimit:
+		return apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Detection providers are rate limiting").WithDetails(err.Error())
+	default:
+		return apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Detection analysis failed").WithDetails(err.Error())
+	}
+}
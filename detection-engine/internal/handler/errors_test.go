@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+func TestDetectionErrorCodes(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := map[string]struct {
+		ctx        context.Context
+		err        error
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		"budget exhausted":  {context.Background(), fmt.Errorf("paid models skipped: %w", detector.ErrCostBudgetExceeded), http.StatusServiceUnavailable, apierror.CodeBudgetExhausted},
+		"all models failed": {context.Background(), fmt.Errorf("chain exhausted: %w", detector.ErrAllModelsFailed), http.StatusServiceUnavailable, apierror.CodeAllModelsUnavailable},
+		"deadline exceeded": {expired, context.DeadlineExceeded, http.StatusRequestTimeout, apierror.CodeTimeout},
+		"saturated":         {context.Background(), detector.ErrDispatchSaturated, http.StatusServiceUnavailable, apierror.CodeOverloaded},
+		"rate limited":      {context.Background(), &detector.ProviderError{Category: detector.ErrorCategoryRateLimit, StatusCode: http.StatusTooManyRequests}, http.StatusTooManyRequests, apierror.CodeRateLimited},
+		"unexpected":        {context.Background(), errors.New("boom"), http.StatusInternalServerError, apierror.CodeInternal},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			apiErr := detectionError(tt.ctx, tt.err)
+			if apiErr.Status != tt.wantStatus || apiErr.Code != tt.wantCode {
+				t.Errorf("detectionError = %d %q, want %d %q", apiErr.Status, apiErr.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+
+	if apiErr := detectionError(context.Background(), detector.ErrAllModelsFailed); apiErr.RetryAfter != 60 {
+		t.Errorf("retry_after = %d, want 60 when every model failed", apiErr.RetryAfter)
+	}
+}
+
+func TestDetectInjectionErrorResponses(t *testing.T) {
+	// The only model is unreachable, so any detection fails over the whole chain
+	router := newProbeRouter(NewFallbackDetectionHandler(newTestFallbackPipeline(t, testModel("unreachable", 1, true)), newTestLogger()))
+
+	tests := map[string]struct {
+		body       any
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		"malformed body":     {"not an object", http.StatusBadRequest, apierror.CodeInvalidPayload},
+		"text not a string":  {gin.H{"text": 42}, http.StatusBadRequest, apierror.CodeInvalidPayload},
+		"all models failing": {gin.H{"text": "hello"}, http.StatusServiceUnavailable, apierror.CodeAllModelsUnavailable},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveJSON(t, router, http.MethodPost, "/v1/detect", tt.body)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			var apiErr apierror.APIError
+			decodeBody(t, recorder, &apiErr)
+			if apiErr.Code != tt.wantCode || apiErr.Message == "" {
+				t.Errorf("body = %+v, want code %q with a message", apiErr, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+// renderDetection writes a detection response with its ETag, or 304 Not Modified
+// when the client's If-None-Match already names that ETag
+func renderDetection(c *gin.Context, req *detector.DetectionRequest, response *detector.DetectionResponse) {
+	etag := detector.ResponseETag(req, response)
+	c.Header("ETag", etag)
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or is "*".
+// If-None-Match uses weak comparison, so a W/ prefix is ignored.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// postDetect sends body to path with an optional If-None-Match header
+func postDetect(router http.Handler, path, body, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDetectETagThenNotModified(t *testing.T) {
+	router := newThresholdRouter(t)
+	const body = `{"text":"summarize the quarterly report"}`
+
+	first := postDetect(router, "/v1/detect", body, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.Len() == 0 {
+		t.Fatalf("first POST = %d %q, want 200 with a body", first.Code, first.Body)
+	}
+	if len(etag) < 3 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("ETag = %q, want a quoted strong validator", etag)
+	}
+
+	for name, header := range map[string]string{
+		"exact":    etag,
+		"weak":     "W/" + etag,
+		"listed":   `"stale", ` + etag,
+		"wildcard": "*",
+	} {
+		recorder := postDetect(router, "/v1/detect", body, header)
+		if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+			t.Errorf("%s If-None-Match = %d %q, want 304 without a body", name, recorder.Code, recorder.Body)
+		}
+		if recorder.Header().Get("ETag") != etag {
+			t.Errorf("%s ETag = %q, want %q", name, recorder.Header().Get("ETag"), etag)
+		}
+	}
+
+	if recorder := postDetect(router, "/v1/detect", body, `"stale"`); recorder.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match = %d, want 200", recorder.Code)
+	}
+}
+
+func TestDetectETagVariesWithInputAndConfig(t *testing.T) {
+	router := newThresholdRouter(t)
+	base := postDetect(router, "/v1/detect", `{"text":"summarize the quarterly report"}`, "").Header().Get("ETag")
+
+	tests := map[string]struct {
+		path string
+		body string
+	}{
+		"other text":      {"/v1/detect", `{"text":"translate the quarterly report"}`},
+		"query threshold": {"/v1/detect?threshold=0.4", `{"text":"summarize the quarterly report"}`},
+		"body threshold":  {"/v1/detect", `{"text":"summarize the quarterly report","config":{"confidence_threshold":0.4}}`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := postDetect(router, tt.path, tt.body, base)
+			if recorder.Code != http.StatusOK {
+				t.Errorf("POST with the original ETag = %d, want 200 since the result differs", recorder.Code)
+			}
+			if etag := recorder.Header().Get("ETag"); etag == "" || etag == base {
+				t.Errorf("ETag = %q, want one distinct from %q", etag, base)
+			}
+		})
+	}
+}
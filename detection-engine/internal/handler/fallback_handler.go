@@ -2,13 +2,16 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"prompt-injection-detection/internal/apierror"
 	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/logging"
 )
 
 // FallbackDetectionHandler handles HTTP requests for prompt injection detection with circuit breakers
@@ -27,57 +30,46 @@ func NewFallbackDetectionHandler(pipeline *detector.FallbackPipeline, logger *lo
 
 // DetectInjection handles POST /v1/detect requests with circuit breaker fallback
 func (h *FallbackDetectionHandler) DetectInjection(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
 	var req detector.DetectionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Invalid request payload")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request payload",
-			"details": err.Error(),
-		})
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+	if err := applyThresholdQuery(c, &req); err != nil {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid threshold").WithDetails(err.Error()))
+		return
+	}
+	if err := applyExplainQuery(c, &req); err != nil {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid explain option").WithDetails(err.Error()))
 		return
 	}
 
 	// Set timeout for detection
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	// Log request (be careful not to log sensitive content)
-	h.logger.WithFields(logrus.Fields{
-		"text_length": len(req.Text),
-		"config":      req.Config,
-		"client_ip":   c.ClientIP(),
+	logger.WithFields(logrus.Fields{
+		"text_length":   len(req.Text),
+		"message_count": len(req.Messages),
+		"config":        req.Config,
+		"client_ip":     c.ClientIP(),
 	}).Info("Processing detection request with circuit breaker fallback")
 
 	// Process detection
 	response, err := h.pipeline.Analyze(ctx, &req)
 	if err != nil {
-		h.logger.WithError(err).Error("Detection analysis failed")
-
-		// Check if all models failed (service unavailable)
-		if err == detector.ErrAllModelsFailed {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error":   "All detection models are temporarily unavailable",
-				"details": "Please try again in a few minutes",
-				"retry_after": 60, // Suggest retry after 60 seconds
-			})
-			return
-		}
+		logger.WithError(err).Error("Detection analysis failed")
 
-		// Determine appropriate HTTP status code
-		statusCode := http.StatusInternalServerError
-		if ctx.Err() == context.DeadlineExceeded {
-			statusCode = http.StatusRequestTimeout
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error":   "Detection analysis failed",
-			"details": err.Error(),
-		})
+		apierror.Render(c, detectionError(ctx, err))
 		return
 	}
 
 	// Log response with model used
-	h.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"is_malicious":       response.IsMalicious,
 		"confidence":         response.Confidence,
 		"threat_types":       response.ThreatTypes,
@@ -85,10 +77,195 @@ func (h *FallbackDetectionHandler) DetectInjection(c *gin.Context) {
 		"model_used":         response.Endpoint,
 	}).Info("Detection completed")
 
-	// Return response
+	// Return response, or 304 if the client already has it
+	renderDetection(c, &req, response)
+}
+
+// DetectOutput handles POST /v1/detect/output requests for model-generated text
+func (h *FallbackDetectionHandler) DetectOutput(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.OutputDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid output request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"output_length":        len(req.Output),
+		"system_prompt_length": len(req.SystemPrompt),
+		"canaries":             len(req.Canaries),
+		"client_ip":            c.ClientIP(),
+	}).Info("Processing output detection request")
+
+	response, err := h.pipeline.AnalyzeOutput(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Output analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Output analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious": response.IsMalicious,
+		"confidence":   response.Confidence,
+		"threat_types": response.ThreatTypes,
+	}).Info("Output detection completed")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DetectDocument handles POST /v1/detect/document requests for untrusted document content
+func (h *FallbackDetectionHandler) DetectDocument(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.DocumentDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid document request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"document_length": len(req.Document),
+		"client_ip":       c.ClientIP(),
+	}).Info("Processing document detection request")
+
+	response, err := h.pipeline.AnalyzeDocument(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Document analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Document analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious":        response.IsMalicious,
+		"confidence":          response.Confidence,
+		"threat_types":        response.ThreatTypes,
+		"suspicious_passages": len(response.Matches),
+	}).Info("Document detection completed")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DetectToolCall handles POST /v1/detect/tool-call requests for model-chosen tool arguments
+func (h *FallbackDetectionHandler) DetectToolCall(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.ToolCallDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid tool call request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"tool_name":      req.ToolName,
+		"argument_count": len(req.Arguments),
+		"client_ip":      c.ClientIP(),
+	}).Info("Processing tool call detection request")
+
+	response, err := h.pipeline.AnalyzeToolCall(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Tool call analysis failed")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Tool call analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"is_malicious": response.IsMalicious,
+		"confidence":   response.Confidence,
+		"threat_types": response.ThreatTypes,
+		"tool_name":    req.ToolName,
+	}).Info("Tool call detection completed")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DetectSession handles POST /v1/detect/session requests for multi-turn conversations
+func (h *FallbackDetectionHandler) DetectSession(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req detector.SessionDetectionRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		logger.WithError(apiErr).Error("Invalid session request payload")
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	logger.WithFields(logrus.Fields{
+		"session_id":    req.SessionID,
+		"message_count": len(req.Messages),
+		"client_ip":     c.ClientIP(),
+	}).Info("Processing session detection request")
+
+	response, err := h.pipeline.AnalyzeSession(ctx, &req)
+	if err != nil {
+		logger.WithError(err).Error("Session analysis failed")
+
+		if err == detector.ErrAllModelsFailed {
+			apierror.Render(c, errAllModelsUnavailable)
+			return
+		}
+
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Session analysis failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"session_id":   req.SessionID,
+		"is_malicious": response.IsMalicious,
+		"session_risk": response.SessionRisk.Risk,
+		"turns":        response.SessionRisk.Turns,
+	}).Info("Session detection completed")
+
 	c.JSON(http.StatusOK, response)
 }
 
+// Liveness handles GET /live requests; it succeeds whenever the process is serving HTTP
+func (h *FallbackDetectionHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Readiness handles GET /ready requests; it fails while no detection backend is usable
+func (h *FallbackDetectionHandler) Readiness(c *gin.Context) {
+	ready, reason := h.pipeline.Readiness()
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": reason})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "reason": reason})
+}
+
+// GetSelfTest handles GET /v1/selftest requests with the most recent self-test report
+func (h *FallbackDetectionHandler) GetSelfTest(c *gin.Context) {
+	report := h.pipeline.LastSelfTest()
+	if report == nil {
+		apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "No self-test has run").WithDetails("Enable selftest.enabled to run the self-test at startup"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRecentDetections handles GET /v1/detections/recent requests
+func (h *FallbackDetectionHandler) GetRecentDetections(c *gin.Context) {
+	writeRecentDetections(c, h.pipeline.RecentDetections())
+}
+
 // HealthCheck handles GET /health requests with circuit breaker status
 func (h *FallbackDetectionHandler) HealthCheck(c *gin.Context) {
 	health := h.pipeline.GetHealth()
@@ -114,14 +291,31 @@ func (h *FallbackDetectionHandler) GetMetrics(c *gin.Context) {
 		successRate = float64(metrics.RequestsSuccessful) / float64(metrics.RequestsTotal)
 	}
 
+	slotsInUse, slotsTotal := h.pipeline.DispatchSlots()
+	latency := metrics.GetLatencyPercentiles()
+
+	modelMetrics := h.pipeline.GetModelMetrics()
+	totalCost := float64(0)
+	for _, model := range modelMetrics {
+		totalCost += model.EstimatedCost
+	}
+
 	response := gin.H{
 		"requests_total":       metrics.GetRequestsTotal(),
 		"requests_successful":  metrics.RequestsSuccessful,
 		"requests_failed":      metrics.RequestsFailed,
 		"success_rate":         successRate,
 		"average_latency_ms":   metrics.GetAverageLatency().Milliseconds(),
+		"p50_latency_ms":       latency.P50.Milliseconds(),
+		"p95_latency_ms":       latency.P95.Milliseconds(),
+		"p99_latency_ms":       latency.P99.Milliseconds(),
+		"windows":              metrics.GetWindowCounts(),
 		"detection_method":     "circuit_breaker_fallback",
 		"detections_by_threat": metrics.DetectionsByThreat,
+		"dispatch_in_use":      slotsInUse,
+		"dispatch_capacity":    slotsTotal,
+		"models":               modelMetrics,
+		"estimated_cost_usd":   totalCost,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -165,29 +359,26 @@ func (h *FallbackDetectionHandler) GetCircuitBreakers(c *gin.Context) {
 
 // ResetCircuitBreaker handles POST /v1/circuit-breakers/:model/reset requests
 func (h *FallbackDetectionHandler) ResetCircuitBreaker(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
 	modelName := c.Param("model")
 	if modelName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Model name is required",
-		})
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Model name is required"))
 		return
 	}
 
 	err := h.pipeline.ResetCircuitBreaker(modelName)
 	if err != nil {
-		h.logger.WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"model": modelName,
 			"error": err.Error(),
 		}).Error("Failed to reset circuit breaker")
 
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Circuit breaker not found",
-			"details": err.Error(),
-		})
+		apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Circuit breaker not found").WithDetails(err.Error()))
 		return
 	}
 
-	h.logger.WithField("model", modelName).Info("Circuit breaker manually reset")
+	logger.WithField("model", modelName).Info("Circuit breaker manually reset")
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Circuit breaker reset successfully",
@@ -225,6 +416,115 @@ func (h *FallbackDetectionHandler) DiagnoseLLM(c *gin.Context) {
 		"fallback_strategy":      "ProtectAI -> Moonshot-Kimi-K2 -> Gemini -> HTTP 503",
 		"note":                   "Circuit breaker enabled with automatic fallback",
 	}
+	if len(health.QuotaExhaustedUntil) > 0 {
+		response["quota_exhausted_until"] = health.QuotaExhaustedUntil
+	}
+	if len(health.Probes) > 0 {
+		response["probes"] = health.Probes
+	}
 
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+// updateModelRequest is the body accepted by PATCH /v1/models/:name
+type updateModelRequest struct {
+	Enabled  *bool `json:"enabled,omitempty"`
+	Priority *int  `json:"priority,omitempty"`
+}
+
+// testModelRequest is the optional body of POST /v1/models/:name/test
+type testModelRequest struct {
+	Sample string `json:"sample,omitempty"` // Defaults to a known injection prompt
+}
+
+// ListModels handles GET /v1/models requests
+func (h *FallbackDetectionHandler) ListModels(c *gin.Context) {
+	models := h.pipeline.ListModels()
+
+	c.JSON(http.StatusOK, gin.H{
+		"models":       models,
+		"total_models": len(models),
+	})
+}
+
+// UpdateModel handles PATCH /v1/models/:name requests
+func (h *FallbackDetectionHandler) UpdateModel(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	modelName := c.Param("name")
+
+	var req updateModelRequest
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	if req.Enabled == nil && req.Priority == nil {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "At least one of enabled or priority is required"))
+		return
+	}
+
+	if req.Priority != nil && *req.Priority <= 0 {
+		apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Priority must be a positive integer"))
+		return
+	}
+
+	model, err := h.pipeline.UpdateModel(modelName, req.Enabled, req.Priority)
+	if err != nil {
+		if errors.Is(err, detector.ErrModelNotFound) {
+			apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Model not found").WithDetails(err.Error()))
+			return
+		}
+
+		logger.WithFields(logrus.Fields{
+			"model": modelName,
+			"error": err.Error(),
+		}).Error("Failed to update model")
+
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Failed to update model").WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Model updated successfully",
+		"model":   model,
+	})
+}
+
+// TestModel handles POST /v1/models/:name/test requests, classifying a sample with
+// exactly one model regardless of its circuit breaker
+func (h *FallbackDetectionHandler) TestModel(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	modelName := c.Param("name")
+
+	var req testModelRequest
+	if c.Request.ContentLength != 0 {
+		if apiErr := bindJSON(c, &req); apiErr != nil {
+			apierror.Render(c, apiErr)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.pipeline.TestModel(ctx, modelName, req.Sample)
+	if err != nil {
+		if errors.Is(err, detector.ErrModelNotFound) {
+			apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Model not found").WithDetails(err.Error()))
+			return
+		}
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Model test failed").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"model":         result.Model,
+		"score":         result.Score,
+		"latency_ms":    result.LatencyMs,
+		"circuit_state": result.CircuitState,
+		"error":         result.Error,
+	}).Info("Model test completed")
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/logging"
+)
+
+// JobHandler serves async batch detection jobs
+type JobHandler struct {
+	jobs         *detector.JobStore
+	maxBatchSize int
+	logger       *logrus.Logger
+}
+
+// NewJobHandler creates a handler for submitting and polling batch jobs
+func NewJobHandler(jobs *detector.JobStore, maxBatchSize int, logger *logrus.Logger) *JobHandler {
+	return &JobHandler{
+		jobs:         jobs,
+		maxBatchSize: maxBatchSize,
+		logger:       logger,
+	}
+}
+
+// SubmitBatch handles POST /v1/detect/batch/async requests
+func (h *JobHandler) SubmitBatch(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req struct {
+		Texts  []string                  `json:"texts" binding:"required"`
+		Config *detector.DetectionConfig `json:"config,omitempty"`
+	}
+
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	if apiErr := validateBatch(req.Texts, req.Config, h.maxBatchSize); apiErr != nil {
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	jobID, err := h.jobs.Submit(req.Texts, req.Config)
+	if errors.Is(err, detector.ErrJobQueueFull) {
+		c.Header("Retry-After", "30")
+		apierror.Render(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeOverloaded, "Too many batch jobs queued").WithDetails(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to submit batch job")
+		apierror.Render(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Failed to submit batch job").WithDetails(err.Error()))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"job_id": jobID,
+		"texts":  len(req.Texts),
+	}).Info("Batch job submitted")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": jobID,
+		"status": detector.JobPending,
+	})
+}
+
+// GetJob handles GET /v1/jobs/:id requests
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, exists := h.jobs.Get(c.Param("id"))
+	if !exists {
+		apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Job not found").WithDetails("The job ID is unknown or its results have expired"))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+// providerMetered is served by a fake detector that fails texts containing "fail"
+const providerMetered detector.ModelProvider = "metered"
+
+func TestMetricsPerModelSectionSums(t *testing.T) {
+	paid := testModel("paid", 1, true)
+	paid.Provider = providerMetered
+	paid.CostPerRequest = 0.002
+	free := testModel("free", 2, true)
+	free.Provider = providerMetered
+
+	pipeline := newTestFallbackPipeline(t, paid, free)
+	pipeline.RegisterProvider(providerMetered, detector.DetectorFunc(func(ctx context.Context, text string, model detector.ModelConfig) (*detector.DetectionResult, error) {
+		if model.Name == "paid" && strings.Contains(text, "fail") {
+			return nil, &detector.ProviderError{Category: detector.ErrorCategoryServer, StatusCode: http.StatusBadGateway, Message: "upstream failure"}
+		}
+		return &detector.DetectionResult{Method: detector.MethodLLM, Score: 0.1, Endpoint: model.Name}, nil
+	}))
+
+	h := NewFallbackDetectionHandler(pipeline, newTestLogger())
+	router := gin.New()
+	router.POST("/v1/detect", h.DetectInjection)
+	router.GET("/v1/metrics", h.GetMetrics)
+
+	// The failure goes last: it opens the paid model's breaker
+	for _, text := range []string{"first question", "second question", "please fail"} {
+		if recorder := serveJSON(t, router, http.MethodPost, "/v1/detect", gin.H{"text": text}); recorder.Code != http.StatusOK {
+			t.Fatalf("POST /v1/detect %q = %d: %s", text, recorder.Code, recorder.Body)
+		}
+	}
+
+	recorder := serveJSON(t, router, http.MethodGet, "/v1/metrics", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /v1/metrics = %d: %s", recorder.Code, recorder.Body)
+	}
+	var body struct {
+		RequestsTotal int64                   `json:"requests_total"`
+		Models        []detector.ModelMetrics `json:"models"`
+		TotalCost     float64                 `json:"estimated_cost_usd"`
+	}
+	decodeBody(t, recorder, &body)
+
+	if body.RequestsTotal != 3 {
+		t.Errorf("requests_total = %d, want 3", body.RequestsTotal)
+	}
+	if len(body.Models) != 2 {
+		t.Fatalf("models = %+v, want paid and free", body.Models)
+	}
+
+	want := map[string]detector.ModelMetrics{
+		"paid": {Name: "paid", Provider: string(providerMetered), TotalRequests: 3, SuccessfulRequests: 2, FailedRequests: 1, SuccessRate: 2.0 / 3, EstimatedCost: 0.006},
+		"free": {Name: "free", Provider: string(providerMetered), TotalRequests: 1, SuccessfulRequests: 1, FailedRequests: 0, SuccessRate: 1, EstimatedCost: 0},
+	}
+	costSum := 0.0
+	for _, got := range body.Models {
+		expected := want[got.Name]
+		if got.TotalRequests != expected.TotalRequests || got.SuccessfulRequests != expected.SuccessfulRequests || got.FailedRequests != expected.FailedRequests {
+			t.Errorf("%s counts = %+v, want %+v", got.Name, got, expected)
+		}
+		if got.SuccessfulRequests+got.FailedRequests != got.TotalRequests {
+			t.Errorf("%s: %d successful + %d failed != %d total", got.Name, got.SuccessfulRequests, got.FailedRequests, got.TotalRequests)
+		}
+		if math.Abs(got.SuccessRate-expected.SuccessRate) > 1e-9 || math.Abs(got.EstimatedCost-expected.EstimatedCost) > 1e-9 {
+			t.Errorf("%s rate/cost = %v/%v, want %v/%v", got.Name, got.SuccessRate, got.EstimatedCost, expected.SuccessRate, expected.EstimatedCost)
+		}
+		costSum += got.EstimatedCost
+	}
+	if math.Abs(body.TotalCost-costSum) > 1e-9 {
+		t.Errorf("estimated_cost_usd = %v, want the per-model sum %v", body.TotalCost, costSum)
+	}
+}
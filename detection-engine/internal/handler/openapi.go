@@ -0,0 +1,18 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIDocument describes the HTTP API; keep it in step with the routes registered in cmd/server
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// OpenAPI handles GET /openapi.json requests with the OpenAPI 3 description of the API
+func OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIDocument)
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+// openAPISpec is the part of an OpenAPI 3 document the tests check
+type openAPISpec struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// openAPIOperation is a single method of an OpenAPI path item
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Responses   map[string]json.RawMessage `json:"responses"`
+}
+
+// openAPIMethods are the path item keys that hold operations
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// fetchOpenAPI serves GET /openapi.json and returns the raw document
+func fetchOpenAPI(t *testing.T) []byte {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/openapi.json", OpenAPI)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", contentType)
+	}
+	return recorder.Body.Bytes()
+}
+
+// collectRefs appends every $ref value found anywhere in node
+func collectRefs(node any, refs []string) []string {
+	switch value := node.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = collectRefs(child, refs)
+		}
+	case []any:
+		for _, child := range value {
+			refs = collectRefs(child, refs)
+		}
+	}
+	return refs
+}
+
+// resolvePointer follows a local JSON pointer such as #/components/schemas/Error
+func resolvePointer(document any, ref string) bool {
+	if !strings.HasPrefix(ref, "#/") {
+		return false
+	}
+	node := document
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		if node, ok = object[strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFieldNames returns the JSON names of a struct's serialized fields, including promoted ones
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestOpenAPIDocumentIsValid(t *testing.T) {
+	body := fetchOpenAPI(t)
+
+	var spec openAPISpec
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("served document is not JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x version", spec.OpenAPI)
+	}
+	if spec.Info.Title == "" || spec.Info.Version == "" {
+		t.Errorf("info = %+v, want a title and version", spec.Info)
+	}
+
+	operationIDs := make(map[string]string)
+	for path, item := range spec.Paths {
+		if !strings.HasPrefix(path, "/") {
+			t.Errorf("path %q does not start with /", path)
+		}
+		for method, operation := range item {
+			if !slices.Contains(openAPIMethods, method) {
+				continue
+			}
+			if len(operation.Responses) == 0 {
+				t.Errorf("%s %s declares no responses", method, path)
+			}
+			if operation.OperationID == "" {
+				t.Errorf("%s %s has no operationId", method, path)
+			} else if previous, exists := operationIDs[operation.OperationID]; exists {
+				t.Errorf("operationId %q used by both %s and %s %s", operation.OperationID, previous, method, path)
+			}
+			operationIDs[operation.OperationID] = method + " " + path
+		}
+	}
+
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	for _, ref := range collectRefs(document, nil) {
+		if !resolvePointer(document, ref) {
+			t.Errorf("$ref %q does not resolve within the document", ref)
+		}
+	}
+
+	detect, ok := spec.Paths["/v1/detect"]["post"]
+	if !ok {
+		t.Fatal("document does not describe POST /v1/detect")
+	}
+	if _, ok := detect.Responses["200"]; !ok {
+		t.Error("POST /v1/detect does not describe its 200 response")
+	}
+}
+
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	var spec openAPISpec
+	if err := json.Unmarshal(fetchOpenAPI(t), &spec); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+
+	types := map[string]reflect.Type{
+		"DetectionRequest":  reflect.TypeOf(detector.DetectionRequest{}),
+		"DetectionConfig":   reflect.TypeOf(detector.DetectionConfig{}),
+		"DetectionResponse": reflect.TypeOf(detector.DetectionResponse{}),
+		"Explanation":       reflect.TypeOf(detector.Explanation{}),
+		"ModelResult":       reflect.TypeOf(detector.ModelResult{}),
+	}
+	for name, typ := range types {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := spec.Components.Schemas[name]
+			if !ok {
+				t.Fatalf("document has no %s schema", name)
+			}
+			fields := jsonFieldNames(typ)
+			for _, field := range fields {
+				if _, ok := schema.Properties[field]; !ok {
+					t.Errorf("%s.%s is serialized but missing from the schema", name, field)
+				}
+			}
+			for property := range schema.Properties {
+				if !slices.Contains(fields, property) {
+					t.Errorf("schema property %s.%s has no matching field", name, property)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+// applyThresholdQuery overrides the request's confidence threshold with ?threshold= when present.
+// Precedence is query parameter > config.confidence_threshold in the body > server default.
+func applyThresholdQuery(c *gin.Context, req *detector.DetectionRequest) error {
+	raw, ok := c.GetQuery("threshold")
+	if !ok {
+		return nil
+	}
+
+	threshold, err := strconv.ParseFloat(raw, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("threshold must be a number greater than 0 and at most 1, got %q", raw)
+	}
+
+	if req.Config == nil {
+		req.Config = &detector.DetectionConfig{}
+	}
+	req.Config.ConfidenceThreshold = threshold
+	return nil
+}
+
+// applyExplainQuery requests a score explanation when ?explain=true is present
+func applyExplainQuery(c *gin.Context, req *detector.DetectionRequest) error {
+	raw, ok := c.GetQuery("explain")
+	if !ok {
+		return nil
+	}
+
+	explain, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("explain must be true or false, got %q", raw)
+	}
+
+	if req.Config == nil {
+		req.Config = &detector.DetectionConfig{}
+	}
+	req.Config.Explain = explain
+	return nil
+}
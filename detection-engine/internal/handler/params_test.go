@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+// providerScored is served by a fake detector that scores every text 0.5 as an injection
+const providerScored detector.ModelProvider = "scored"
+
+// newThresholdRouter serves POST /v1/detect over a model scoring 0.5, between the server default and the test thresholds
+func newThresholdRouter(t *testing.T) *gin.Engine {
+	model := testModel("scored", 1, true)
+	model.Provider = providerScored
+
+	pipeline := newTestFallbackPipeline(t, model)
+	pipeline.RegisterProvider(providerScored, detector.DetectorFunc(func(ctx context.Context, text string, model detector.ModelConfig) (*detector.DetectionResult, error) {
+		return &detector.DetectionResult{Method: detector.MethodLLM, Score: 0.5, ThreatTypes: []detector.ThreatType{detector.ThreatTypeInjection}, Endpoint: model.Name}, nil
+	}))
+
+	router := gin.New()
+	router.POST("/v1/detect", NewFallbackDetectionHandler(pipeline, newTestLogger()).DetectInjection)
+	return router
+}
+
+func TestThresholdQueryPrecedence(t *testing.T) {
+	router := newThresholdRouter(t)
+
+	tests := map[string]struct {
+		query         string
+		bodyThreshold float64 // 0 leaves the body config out
+		wantMalicious bool
+	}{
+		"server default":         {"", 0, false},
+		"body lowers":            {"", 0.4, true},
+		"query lowers":           {"?threshold=0.4", 0, true},
+		"query raises over body": {"?threshold=0.9", 0.4, false},
+		"query lowers over body": {"?threshold=0.4", 0.9, true},
+		"query at the maximum":   {"?threshold=1", 0, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			body := gin.H{"text": "hello"}
+			if tt.bodyThreshold != 0 {
+				body["config"] = gin.H{"confidence_threshold": tt.bodyThreshold}
+			}
+
+			recorder := serveJSON(t, router, http.MethodPost, "/v1/detect"+tt.query, body)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+			}
+			var response detector.DetectionResponse
+			decodeBody(t, recorder, &response)
+			if response.IsMalicious != tt.wantMalicious {
+				t.Errorf("is_malicious = %v, want %v for a 0.5 score", response.IsMalicious, tt.wantMalicious)
+			}
+		})
+	}
+}
+
+func TestThresholdQueryRejectsOutOfRange(t *testing.T) {
+	router := newThresholdRouter(t)
+
+	for _, value := range []string{"0", "-0.1", "1.5", "high", ""} {
+		t.Run(value, func(t *testing.T) {
+			recorder := serveJSON(t, router, http.MethodPost, "/v1/detect?threshold="+value, gin.H{"text": "hello"})
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", recorder.Code, recorder.Body)
+			}
+			var apiErr apierror.APIError
+			decodeBody(t, recorder, &apiErr)
+			if apiErr.Code != apierror.CodeInvalidPayload {
+				t.Errorf("code = %q, want %q", apiErr.Code, apierror.CodeInvalidPayload)
+			}
+		})
+	}
+}
+
+func TestExplainQuery(t *testing.T) {
+	router := newThresholdRouter(t)
+
+	recorder := serveJSON(t, router, http.MethodPost, "/v1/detect?explain=true", gin.H{"text": "hello"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	var response detector.DetectionResponse
+	decodeBody(t, recorder, &response)
+	if response.Explanation == nil {
+		t.Fatalf("no explanation in %s", recorder.Body)
+	}
+	if response.Explanation.Score != 0.5 || len(response.Explanation.ThresholdSweep) == 0 {
+		t.Errorf("explanation = %+v, want the 0.5 score and a threshold sweep", response.Explanation)
+	}
+	for _, verdict := range response.Explanation.ThresholdSweep {
+		if want := verdict.Threshold <= 0.5; verdict.IsMalicious != want {
+			t.Errorf("at %v: IsMalicious = %v, want %v for a 0.5 score", verdict.Threshold, verdict.IsMalicious, want)
+		}
+	}
+
+	recorder = serveJSON(t, router, http.MethodPost, "/v1/detect?explain=maybe", gin.H{"text": "hello"})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an invalid explain value, want 400", recorder.Code)
+	}
+}
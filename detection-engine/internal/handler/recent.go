@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+// defaultRecentLimit is how many detections GET /v1/detections/recent returns without ?limit=
+const defaultRecentLimit = 50
+
+// writeRecentDetections serves the recent detection log, honoring the limit query parameter
+func writeRecentDetections(c *gin.Context, recent *detector.RecentDetections) {
+	if recent == nil {
+		apierror.Render(c, apierror.New(http.StatusNotFound, apierror.CodeFeatureDisabled, "Recent detection capture is disabled").WithDetails("Enable recent_detections.enabled to record detections"))
+		return
+	}
+
+	limit := defaultRecentLimit
+	if raw, ok := c.GetQuery("limit"); ok {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			apierror.Render(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid limit").WithDetails("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	detections := recent.List(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"detections": detections,
+		"count":      len(detections),
+		"capacity":   recent.Capacity(),
+	})
+}
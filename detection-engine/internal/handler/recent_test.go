@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+)
+
+// newRecentRouter serves the recent detections of a pipeline capturing into recent (disabled when nil)
+func newRecentRouter(t *testing.T, recent *detector.RecentDetections) *gin.Engine {
+	pipeline := newTestFallbackPipeline(t)
+	if recent != nil {
+		pipeline.SetRecentDetections(recent)
+	}
+	router := gin.New()
+	router.GET("/v1/detections/recent", NewFallbackDetectionHandler(pipeline, newTestLogger()).GetRecentDetections)
+	return router
+}
+
+func TestGetRecentDetectionsLimit(t *testing.T) {
+	recent := detector.NewRecentDetections(3, 0, nil)
+	for _, confidence := range []float64{0.1, 0.2, 0.3, 0.4, 0.5} {
+		recent.Record("prompt", &detector.DetectionResponse{Confidence: confidence, ThreatTypes: []string{}})
+	}
+	router := newRecentRouter(t, recent)
+
+	tests := map[string]struct {
+		path      string
+		wantCount int
+	}{
+		"default limit": {"/v1/detections/recent", 3},
+		"limit":         {"/v1/detections/recent?limit=2", 2},
+		"limit above":   {"/v1/detections/recent?limit=100", 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveJSON(t, router, http.MethodGet, tt.path, nil)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d: %s", tt.path, recorder.Code, recorder.Body)
+			}
+			var body struct {
+				Detections []detector.RecentDetection `json:"detections"`
+				Count      int                        `json:"count"`
+				Capacity   int                        `json:"capacity"`
+			}
+			decodeBody(t, recorder, &body)
+			if body.Count != tt.wantCount || len(body.Detections) != tt.wantCount || body.Capacity != 3 {
+				t.Fatalf("count %d with %d detections (capacity %d), want %d of 3", body.Count, len(body.Detections), body.Capacity, tt.wantCount)
+			}
+			if body.Detections[0].Confidence != 0.5 {
+				t.Errorf("first detection confidence = %v, want the newest (0.5)", body.Detections[0].Confidence)
+			}
+		})
+	}
+}
+
+func TestGetRecentDetectionsErrors(t *testing.T) {
+	tests := map[string]struct {
+		recent     *detector.RecentDetections
+		path       string
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		"disabled":       {nil, "/v1/detections/recent", http.StatusNotFound, apierror.CodeFeatureDisabled},
+		"zero limit":     {detector.NewRecentDetections(3, 0, nil), "/v1/detections/recent?limit=0", http.StatusBadRequest, apierror.CodeInvalidPayload},
+		"negative limit": {detector.NewRecentDetections(3, 0, nil), "/v1/detections/recent?limit=-1", http.StatusBadRequest, apierror.CodeInvalidPayload},
+		"not a number":   {detector.NewRecentDetections(3, 0, nil), "/v1/detections/recent?limit=ten", http.StatusBadRequest, apierror.CodeInvalidPayload},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveJSON(t, newRecentRouter(t, tt.recent), http.MethodGet, tt.path, nil)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			var apiErr apierror.APIError
+			decodeBody(t, recorder, &apiErr)
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", apiErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/logging"
+)
+
+// StreamHandler serves synchronous batches as a Server-Sent Events stream
+type StreamHandler struct {
+	analyzer     detector.Analyzer
+	workers      int
+	maxBatchSize int
+	logger       *logrus.Logger
+}
+
+// NewStreamHandler creates a handler that streams batch results as they complete
+func NewStreamHandler(analyzer detector.Analyzer, workers, maxBatchSize int, logger *logrus.Logger) *StreamHandler {
+	return &StreamHandler{
+		analyzer:     analyzer,
+		workers:      workers,
+		maxBatchSize: maxBatchSize,
+		logger:       logger,
+	}
+}
+
+// StreamBatch handles POST /v1/detect/batch/stream requests, emitting a "result" event per
+// text as soon as it is analyzed and a final "done" event. Disconnecting cancels pending work.
+func (h *StreamHandler) StreamBatch(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context(), h.logger)
+
+	var req struct {
+		Texts  []string                  `json:"texts" binding:"required"`
+		Config *detector.DetectionConfig `json:"config,omitempty"`
+	}
+
+	if apiErr := bindJSON(c, &req); apiErr != nil {
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	if apiErr := validateBatch(req.Texts, req.Config, h.maxBatchSize); apiErr != nil {
+		apierror.Render(c, apiErr)
+		return
+	}
+
+	logger.WithField("texts", len(req.Texts)).Info("Streaming batch detection")
+
+	// The request context is cancelled when the client goes away, which stops outstanding work
+	items := detector.StreamBatch(c.Request.Context(), h.analyzer, req.Texts, req.Config, h.workers)
+
+	// A stream outlives the server's WriteTimeout, so lift the deadline for this response
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.WithError(err).Warn("Failed to clear write deadline for batch stream")
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+
+	completed, failed := 0, 0
+	disconnected := c.Stream(func(w io.Writer) bool {
+		item, ok := <-items
+		if !ok {
+			c.SSEvent("done", gin.H{
+				"total":     len(req.Texts),
+				"completed": completed,
+				"failed":    failed,
+			})
+			return false
+		}
+
+		if item.Error != "" {
+			failed++
+		} else {
+			completed++
+		}
+		c.SSEvent("result", item)
+		return true
+	})
+
+	if disconnected {
+		logger.WithFields(logrus.Fields{
+			"texts":     len(req.Texts),
+			"completed": completed,
+		}).Warn("Client disconnected from batch stream")
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+// Threats handles GET /v1/threats requests with the catalog of threat types detections can report
+func Threats(c *gin.Context) {
+	threats := detector.ThreatCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"threats":       threats,
+		"total_threats": len(threats),
+	})
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/detector"
+)
+
+func TestThreatsListsCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/threats", Threats)
+
+	recorder := serveJSON(t, router, http.MethodGet, "/v1/threats", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	var body struct {
+		Threats      []detector.ThreatInfo `json:"threats"`
+		TotalThreats int                   `json:"total_threats"`
+	}
+	decodeBody(t, recorder, &body)
+
+	served := make(map[detector.ThreatType]detector.ThreatInfo, len(body.Threats))
+	for _, info := range body.Threats {
+		served[info.ID] = info
+	}
+	for _, want := range detector.ThreatCatalog() {
+		got, ok := served[want.ID]
+		if !ok {
+			t.Errorf("threat %q is missing from the response", want.ID)
+			continue
+		}
+		if got != want {
+			t.Errorf("threat %q = %+v, want %+v", want.ID, got, want)
+		}
+	}
+	if body.TotalThreats != len(body.Threats) || len(body.Threats) != len(detector.ThreatCatalog()) {
+		t.Errorf("total_threats = %d with %d threats, want %d", body.TotalThreats, len(body.Threats), len(detector.ThreatCatalog()))
+	}
+}
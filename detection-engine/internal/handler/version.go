@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/version"
+)
+
+// Version handles GET /version requests with the build metadata of the running binary
+func Version(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
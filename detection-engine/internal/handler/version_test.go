@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/version"
+)
+
+// injectVersion sets the build metadata as -ldflags -X would, restoring it when the test ends
+func injectVersion(t *testing.T, v, commit, buildDate string) {
+	previous := version.Get()
+	version.Version, version.Commit, version.BuildDate = v, commit, buildDate
+	t.Cleanup(func() {
+		version.Version, version.Commit, version.BuildDate = previous.Version, previous.Commit, previous.BuildDate
+	})
+}
+
+func TestVersionReturnsInjectedValues(t *testing.T) {
+	injectVersion(t, "v3.1.0", "abc1234", "2026-01-02T03:04:05Z")
+
+	router := gin.New()
+	router.GET("/version", Version)
+	recorder := serveJSON(t, router, http.MethodGet, "/version", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /version = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var info version.Info
+	decodeBody(t, recorder, &info)
+	want := version.Info{Version: "v3.1.0", Commit: "abc1234", BuildDate: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	if info != want {
+		t.Errorf("GET /version = %+v, want %+v", info, want)
+	}
+
+	// Health reports the same version instead of a per-pipeline string
+	if health := newTestFallbackPipeline(t).GetHealth(); health.Version != "v3.1.0" {
+		t.Errorf("health version = %q, want the injected version", health.Version)
+	}
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDKey is the context key under which the request ID is stored
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+// FromContext returns a log entry tagged with the request ID carried by ctx, if any
+func FromContext(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	if requestID := RequestID(ctx); requestID != "" {
+		return logger.WithField("request_id", requestID)
+	}
+	return logrus.NewEntry(logger)
+}
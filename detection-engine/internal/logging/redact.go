@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/redact"
+)
+
+// RedactionHook masks PII and secrets in log messages and string fields
+// before any formatter writes them
+type RedactionHook struct {
+	redactor *redact.Redactor
+}
+
+// NewRedactionHook creates a hook masking matches of the redactor's patterns
+func NewRedactionHook(redactor *redact.Redactor) *RedactionHook {
+	return &RedactionHook{redactor: redactor}
+}
+
+// Levels applies the hook to every log level
+func (h *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the entry's message and string fields in place
+func (h *RedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = h.redactor.Redact(v)
+		case error:
+			if redacted := h.redactor.Redact(v.Error()); redacted != v.Error() {
+				entry.Data[key] = redacted
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"prompt-injection-detection/internal/redact"
+)
+
+func TestRedactionHookMasksLogEntries(t *testing.T) {
+	// Redaction runs ahead of the capturing hook, as it does ahead of formatters
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(NewRedactionHook(redact.Default()))
+	hook := test.NewLocal(logger)
+
+	logger.WithFields(logrus.Fields{
+		"text":   "send it to jane@example.com",
+		"error":  errors.New("provider rejected key sk-abcdefghijklmnopqrstuvwxyz"),
+		"length": 42,
+	}).Warn("card 4111111111111111 was in the prompt")
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("nothing was logged")
+	}
+	line, err := entry.String()
+	if err != nil {
+		t.Fatalf("format entry: %v", err)
+	}
+	for _, secret := range []string{"jane@example.com", "sk-abcdefghijklmnopqrstuvwxyz", "4111111111111111"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("logged line leaks %q: %s", secret, line)
+		}
+	}
+	if entry.Data["length"] != 42 {
+		t.Errorf("length = %v, want non-string fields untouched", entry.Data["length"])
+	}
+	if _, isErr := entry.Data["error"].(error); isErr {
+		t.Errorf("error field = %v, want it replaced by its redacted message", entry.Data["error"])
+	}
+}
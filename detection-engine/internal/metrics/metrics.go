@@ -0,0 +1,110 @@
+// Package metrics exports per-model detection and circuit breaker metrics to Prometheus.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Collectors are registered once per process; every MetricsCollector reports through them
+var (
+	detectionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "detection_model_requests_total",
+		Help: "Detections answered by each model, by result",
+	}, []string{"model", "result"})
+
+	detectionThreats = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "detection_model_threats_total",
+		Help: "Threat types reported by each model",
+	}, []string{"model", "threat_type"})
+
+	detectionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "detection_model_duration_seconds",
+		Help:    "Time taken to answer a detection, by model",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"model"})
+
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "detection_circuit_breaker_state",
+		Help: "Circuit breaker state by model (0=closed, 1=half-open, 2=open)",
+	}, []string{"model"})
+
+	circuitBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "detection_circuit_breaker_transitions_total",
+		Help: "Circuit breaker transitions recorded by each breaker",
+	}, []string{"model", "from", "to"})
+
+	modelAvailable = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "detection_model_available",
+		Help: "Whether a model is accepting requests (1) or its circuit is open (0)",
+	}, []string{"model"})
+
+	modelSuccessRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "detection_model_success_rate",
+		Help: "Share of calls to a model that succeeded, as seen by its circuit breaker",
+	}, []string{"model"})
+)
+
+// Circuit breaker states as reported by the detection_circuit_breaker_state gauge
+const (
+	CircuitStateClosed   = 0
+	CircuitStateHalfOpen = 1
+	CircuitStateOpen     = 2
+)
+
+// MetricsCollector records per-model detection and circuit breaker metrics
+type MetricsCollector struct{}
+
+// NewMetricsCollector creates a collector reporting to the default Prometheus registry
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{}
+}
+
+// RecordDetectionRequest records a detection answered by model with its result
+// ("benign" or "malicious"), the threat types found and how long it took
+func (m *MetricsCollector) RecordDetectionRequest(model, result string, threatTypes []string, duration time.Duration) {
+	detectionRequests.WithLabelValues(model, result).Inc()
+	for _, threat := range threatTypes {
+		detectionThreats.WithLabelValues(model, threat).Inc()
+	}
+	detectionDuration.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// RecordCircuitBreakerState sets the model's breaker state gauge to one of the CircuitState constants
+func (m *MetricsCollector) RecordCircuitBreakerState(model string, state int) {
+	circuitBreakerState.WithLabelValues(model).Set(float64(state))
+}
+
+// RecordModelAvailability records whether the model is currently accepting requests
+func (m *MetricsCollector) RecordModelAvailability(model string, available bool) {
+	value := 0.0
+	if available {
+		value = 1
+	}
+	modelAvailable.WithLabelValues(model).Set(value)
+}
+
+// UpdateModelSuccessRate records the model's success rate between 0 and 1
+func (m *MetricsCollector) UpdateModelSuccessRate(model string, rate float64) {
+	modelSuccessRate.WithLabelValues(model).Set(rate)
+}
+
+// RecordCircuitBreakerTransition counts a breaker moving between the named states
+func (m *MetricsCollector) RecordCircuitBreakerTransition(model, from, to string) {
+	circuitBreakerTransitions.WithLabelValues(model, from, to).Inc()
+}
+
+// CircuitBreakerStateToInt maps a breaker state name ("CLOSED", "HALF_OPEN", "OPEN")
+// to its gauge value, treating unknown names as closed
+func CircuitBreakerStateToInt(state string) int {
+	switch state {
+	case "OPEN":
+		return CircuitStateOpen
+	case "HALF_OPEN":
+		return CircuitStateHalfOpen
+	default:
+		return CircuitStateClosed
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// BearerAuth rejects requests whose Authorization header does not carry one of the valid keys.
+// Requests to exempt paths (e.g. health checks) are always allowed through.
+func BearerAuth(validKeys []string, exemptPaths ...string) gin.HandlerFunc {
+	keys := make([][]byte, 0, len(validKeys))
+	for _, key := range validKeys {
+		if key != "" {
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok || !validKey(keys, []byte(token)) {
+			c.Header("WWW-Authenticate", `Bearer realm="prompt-shield"`)
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized").WithDetails("A valid API key is required in the Authorization header"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(header string) (string, bool) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// validKey compares the token against every key in constant time,
+// without short-circuiting so timing does not reveal which key matched
+func validKey(keys [][]byte, token []byte) bool {
+	matched := 0
+	for _, key := range keys {
+		matched |= subtle.ConstantTimeCompare(key, token)
+	}
+	return matched == 1
+}
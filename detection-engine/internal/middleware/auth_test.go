@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// newAuthRouter serves /v1/detect and /health behind bearer auth with the given keys
+func newAuthRouter(keys ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BearerAuth(keys, "/health"))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestBearerAuth(t *testing.T) {
+	router := newAuthRouter("first-key", "second-key")
+
+	tests := map[string]struct {
+		method        string
+		path          string
+		authorization string
+		want          int
+	}{
+		"valid key":             {http.MethodPost, "/v1/detect", "Bearer first-key", http.StatusOK},
+		"second valid key":      {http.MethodPost, "/v1/detect", "Bearer second-key", http.StatusOK},
+		"case-insensitive type": {http.MethodPost, "/v1/detect", "bearer first-key", http.StatusOK},
+		"invalid key":           {http.MethodPost, "/v1/detect", "Bearer wrong-key", http.StatusUnauthorized},
+		"key prefix":            {http.MethodPost, "/v1/detect", "Bearer first", http.StatusUnauthorized},
+		"missing header":        {http.MethodPost, "/v1/detect", "", http.StatusUnauthorized},
+		"wrong scheme":          {http.MethodPost, "/v1/detect", "Basic first-key", http.StatusUnauthorized},
+		"empty token":           {http.MethodPost, "/v1/detect", "Bearer ", http.StatusUnauthorized},
+		"health exempt":         {http.MethodGet, "/health", "", http.StatusOK},
+		"health with bad key":   {http.MethodGet, "/health", "Bearer wrong-key", http.StatusOK},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want != http.StatusUnauthorized {
+				return
+			}
+			if recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+			var apiErr apierror.APIError
+			if err := json.Unmarshal(recorder.Body.Bytes(), &apiErr); err != nil || apiErr.Code != apierror.CodeUnauthorized {
+				t.Errorf("body = %s, want an %q error", recorder.Body, apierror.CodeUnauthorized)
+			}
+		})
+	}
+}
+
+func TestBearerAuthIgnoresEmptyKeys(t *testing.T) {
+	router := newAuthRouter("")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401: an empty configured key must never match", recorder.Code)
+	}
+}
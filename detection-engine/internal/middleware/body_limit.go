@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// MaxBodySize rejects request bodies larger than maxBytes with 413 before any
+// handler binds them. Declared lengths are checked up front; bodies without one
+// are read through http.MaxBytesReader so at most maxBytes are ever buffered.
+func MaxBodySize(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		tooLarge := apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge, "Request body too large").WithDetails(fmt.Sprintf("body exceeds %d bytes", maxBytes))
+		if c.Request.ContentLength > maxBytes {
+			c.Header("Connection", "close")
+			apierror.Abort(c, tooLarge)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				apierror.Abort(c, tooLarge)
+				return
+			}
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Failed to read request body").WithDetails(err.Error()))
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
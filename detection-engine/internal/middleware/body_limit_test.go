@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// endlessBody is an unbounded request body of spaces that counts the bytes read from it
+type endlessBody struct {
+	read int64
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+// newBodyLimitRouter binds POST /v1/detect bodies behind MaxBodySize and counts the requests reaching the handler
+func newBodyLimitRouter(maxBytes int64) (*gin.Engine, *atomic.Int32) {
+	gin.SetMode(gin.TestMode)
+	var handled atomic.Int32
+	router := gin.New()
+	router.Use(MaxBodySize(maxBytes))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		handled.Add(1)
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"text": body.Text})
+	})
+	return router, &handled
+}
+
+// assertTooLarge fails unless recorder holds a 413 request_too_large error
+func assertTooLarge(t *testing.T, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413: %s", recorder.Code, recorder.Body)
+	}
+	var apiErr apierror.APIError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if apiErr.Code != apierror.CodeRequestTooLarge {
+		t.Errorf("code = %q, want %q", apiErr.Code, apierror.CodeRequestTooLarge)
+	}
+}
+
+func TestMaxBodySizeAllowsBodyWithinLimit(t *testing.T) {
+	router, handled := newBodyLimitRouter(1024)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", strings.NewReader(`{"text":"summarize this document"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	if !strings.Contains(recorder.Body.String(), "summarize this document") {
+		t.Errorf("body = %s, want the bound text echoed", recorder.Body)
+	}
+	if handled.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", handled.Load())
+	}
+}
+
+func TestMaxBodySizeRejectsDeclaredOversizedBody(t *testing.T) {
+	router, handled := newBodyLimitRouter(1024)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", strings.NewReader(`{"text":"`+strings.Repeat("a", 4096)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assertTooLarge(t, recorder)
+	if recorder.Header().Get("Connection") != "close" {
+		t.Errorf("Connection = %q, want close", recorder.Header().Get("Connection"))
+	}
+	if handled.Load() != 0 {
+		t.Errorf("handler ran %d times for an oversized body, want 0", handled.Load())
+	}
+}
+
+func TestMaxBodySizeStopsReadingUnboundedBody(t *testing.T) {
+	const maxBytes = 64 << 10
+	router, handled := newBodyLimitRouter(maxBytes)
+
+	body := &endlessBody{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", io.NopCloser(body))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assertTooLarge(t, recorder)
+	if handled.Load() != 0 {
+		t.Errorf("handler ran %d times for an oversized body, want 0", handled.Load())
+	}
+	// The reader is abandoned shortly after the limit instead of being drained
+	if body.read > 2*maxBytes {
+		t.Errorf("read %d bytes of the body, want at most about the %d byte limit", body.read, maxBytes)
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compress gzips responses of at least minBytes when the client sends
+// Accept-Encoding: gzip. Smaller responses are sent unchanged, and streamed
+// responses (anything that flushes) bypass compression.
+func Compress(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedResponseWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+
+		defer func() {
+			c.Writer = original
+			if recovered := recover(); recovered != nil {
+				// Let the recovery middleware write its own response
+				panic(recovered)
+			}
+			writer.finish(minBytes)
+		}()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
+
+// bufferedResponseWriter holds the response body until the handler finishes
+// so the size can decide whether it is worth compressing
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	buffer      bytes.Buffer
+	status      int
+	passthrough bool // Set once the handler flushes; later writes go straight out
+}
+
+// WriteHeader records the status until the response is written
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+// WriteHeaderNow defers writing headers until the response is finished
+func (w *bufferedResponseWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Write buffers the body unless the response is streaming
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buffer.Write(data)
+}
+
+// WriteString buffers the body unless the response is streaming
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status returns the status the handler set
+func (w *bufferedResponseWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+// Size returns the number of body bytes written so far
+func (w *bufferedResponseWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buffer.Len()
+}
+
+// Written reports whether the handler has produced a response
+func (w *bufferedResponseWriter) Written() bool {
+	return w.passthrough || w.buffer.Len() > 0 || w.status != http.StatusOK
+}
+
+// Flush switches to streaming: buffered output is sent uncompressed and later writes go straight out
+func (w *bufferedResponseWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buffer.Bytes())
+		w.buffer.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish writes the buffered response, gzipping it if it is large enough
+func (w *bufferedResponseWriter) finish(minBytes int) {
+	if w.passthrough {
+		return
+	}
+
+	header := w.ResponseWriter.Header()
+	body := w.buffer.Bytes()
+	if len(body) < minBytes || header.Get("Content-Encoding") != "" {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	gz := gzip.NewWriter(w.ResponseWriter)
+	gz.Write(body)
+	gz.Close()
+}
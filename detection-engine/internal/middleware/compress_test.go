@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// largeBody is a detection response well above the compression threshold
+var largeBody = `{"results":"` + strings.Repeat("benign ", 200) + `"}`
+
+// newCompressRouter serves a tiny /health response and a large /v1/detect/batch response behind Compress
+func newCompressRouter(minBytes int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(minBytes))
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	})
+	router.POST("/v1/detect/batch", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(largeBody))
+	})
+	return router
+}
+
+// serveWithAcceptEncoding sends a request with the given Accept-Encoding header (none when empty)
+func serveWithAcceptEncoding(router http.Handler, method, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestCompressGzipsLargeResponses(t *testing.T) {
+	recorder := serveWithAcceptEncoding(newCompressRouter(512), http.MethodPost, "/v1/detect/batch", "gzip, deflate")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if got := recorder.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := recorder.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+
+	reader, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatalf("response is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("inflate response: %v", err)
+	}
+	if string(body) != largeBody {
+		t.Error("inflated body differs from the handler's response")
+	}
+}
+
+func TestCompressLeavesResponsesUncompressed(t *testing.T) {
+	tests := map[string]struct {
+		method         string
+		path           string
+		acceptEncoding string
+	}{
+		"tiny health response": {http.MethodGet, "/health", "gzip"},
+		"client without gzip":  {http.MethodPost, "/v1/detect/batch", ""},
+		"gzip explicitly q=0":  {http.MethodPost, "/v1/detect/batch", "gzip;q=0, identity"},
+		"other encodings only": {http.MethodPost, "/v1/detect/batch", "br, deflate"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveWithAcceptEncoding(newCompressRouter(512), tt.method, tt.path, tt.acceptEncoding)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", recorder.Code)
+			}
+			if got := recorder.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, want none", got)
+			}
+			if got := recorder.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding even when uncompressed", got)
+			}
+			if !strings.HasPrefix(recorder.Body.String(), "{") {
+				t.Errorf("body = %q, want plain JSON", recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS sets cross-origin headers, echoing the request Origin only when it is allowed.
+// An allowed origin of "*" opens the API to every origin.
+func CORS(allowedOrigins, allowedMethods, allowedHeaders []string) gin.HandlerFunc {
+	allowAll := false
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.TrimRight(origin, "/")] = true
+	}
+
+	methods := strings.Join(allowedMethods, ", ")
+	headers := strings.Join(allowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		originAllowed := origin == "" || allowAll || origins[origin]
+
+		switch {
+		case allowAll:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin != "" && originAllowed:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Allow-Headers", headers)
+
+		if c.Request.Method == http.MethodOptions {
+			if !originAllowed {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
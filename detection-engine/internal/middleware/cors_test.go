@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCORSRouter serves POST /v1/detect behind the CORS middleware
+func newCORSRouter(allowedOrigins ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(allowedOrigins, []string{"GET", "POST", "OPTIONS"}, []string{"Content-Type", "Authorization"}))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// serveWithOrigin sends a request with the given Origin header (none when empty)
+func serveWithOrigin(router http.Handler, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/v1/detect", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestCORS(t *testing.T) {
+	router := newCORSRouter("https://app.example.com", "https://admin.example.com/")
+
+	tests := map[string]struct {
+		method     string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		"allowed origin":                {http.MethodPost, "https://app.example.com", http.StatusOK, "https://app.example.com"},
+		"allowed with trailing slash":   {http.MethodPost, "https://admin.example.com", http.StatusOK, "https://admin.example.com"},
+		"disallowed origin":             {http.MethodPost, "https://evil.example.com", http.StatusOK, ""},
+		"no origin":                     {http.MethodPost, "", http.StatusOK, ""},
+		"preflight from allowed origin": {http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		"preflight from disallowed one": {http.MethodOptions, "https://evil.example.com", http.StatusForbidden, ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveWithOrigin(router, tt.method, tt.origin)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin != "" && recorder.Header().Get("Vary") != "Origin" {
+				t.Error("echoed origin without Vary: Origin")
+			}
+		})
+	}
+}
+
+func TestCORSPreflightHeaders(t *testing.T) {
+	recorder := serveWithOrigin(newCORSRouter("https://app.example.com"), http.MethodOptions, "https://app.example.com")
+
+	if got := recorder.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, OPTIONS" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want the configured methods", got)
+	}
+	if got := recorder.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want the configured headers", got)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	router := newCORSRouter("*")
+
+	for _, method := range []string{http.MethodPost, http.MethodOptions} {
+		recorder := serveWithOrigin(router, method, "https://anywhere.example.com")
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("%s Access-Control-Allow-Origin = %q, want *", method, got)
+		}
+		if recorder.Code >= http.StatusBadRequest {
+			t.Errorf("%s status = %d, want success for an open deployment", method, recorder.Code)
+		}
+	}
+}
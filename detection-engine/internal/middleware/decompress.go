@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// DecompressRequest transparently inflates gzip-encoded request bodies so handlers
+// can bind them as plain JSON. Bodies that inflate beyond maxBytes are rejected with
+// 413 to guard against decompression bombs.
+func DecompressRequest(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(strings.TrimSpace(c.GetHeader("Content-Encoding")), "gzip") {
+			c.Next()
+			return
+		}
+
+		reader, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid gzip request body").WithDetails(err.Error()))
+			return
+		}
+		defer reader.Close()
+
+		// Read one byte past the limit to detect oversized payloads without inflating them fully
+		body, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+		if err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid gzip request body").WithDetails(err.Error()))
+			return
+		}
+		if int64(len(body)) > maxBytes {
+			apierror.Abort(c, apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge, "Decompressed request body too large").WithDetails(fmt.Sprintf("decompressed body exceeds %d bytes", maxBytes)))
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// newDecompressRouter binds POST /v1/detect bodies behind the decompression middleware and echoes the text
+func newDecompressRouter(maxBytes int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(DecompressRequest(maxBytes))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"text": body.Text, "content_encoding": c.GetHeader("Content-Encoding")})
+	})
+	return router
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// serveGzipped sends body to POST /v1/detect with Content-Encoding: gzip
+func serveGzipped(router http.Handler, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDecompressRequestInflatesGzipBody(t *testing.T) {
+	recorder := serveGzipped(newDecompressRouter(1024), gzipBytes(t, []byte(`{"text":"summarize this document"}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	var body struct {
+		Text            string `json:"text"`
+		ContentEncoding string `json:"content_encoding"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Text != "summarize this document" {
+		t.Errorf("bound text = %q, want the decompressed payload", body.Text)
+	}
+	if body.ContentEncoding != "" {
+		t.Errorf("Content-Encoding = %q after decompression, want it removed", body.ContentEncoding)
+	}
+}
+
+func TestDecompressRequestPassesPlainBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", strings.NewReader(`{"text":"plain"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	newDecompressRouter(1024).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"text":"plain"`) {
+		t.Errorf("plain request = %d %s, want it bound unchanged", recorder.Code, recorder.Body)
+	}
+}
+
+func TestDecompressRequestRejects(t *testing.T) {
+	// A kilobyte-sized bomb: highly compressible text that inflates past the limit
+	bomb := gzipBytes(t, []byte(`{"text":"`+strings.Repeat("a", 64*1024)+`"}`))
+	if len(bomb) >= 1024 {
+		t.Fatalf("bomb is %d bytes compressed, want it under the limit so only inflation trips it", len(bomb))
+	}
+
+	tests := map[string]struct {
+		body       []byte
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		"oversized decompressed body": {bomb, http.StatusRequestEntityTooLarge, apierror.CodeRequestTooLarge},
+		"not gzip":                    {[]byte(`{"text":"plain"}`), http.StatusBadRequest, apierror.CodeInvalidPayload},
+		"truncated gzip":              {gzipBytes(t, []byte(`{"text":"cut short"}`))[:15], http.StatusBadRequest, apierror.CodeInvalidPayload},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := serveGzipped(newDecompressRouter(1024), tt.body)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			var apiErr apierror.APIError
+			if err := json.Unmarshal(recorder.Body.Bytes(), &apiErr); err != nil {
+				t.Fatalf("decode error %q: %v", recorder.Body, err)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", apiErr.Code, tt.wantCode)
+			}
+		})
+	}
+}
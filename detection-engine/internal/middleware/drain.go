@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// Drainer tracks in-flight requests so shutdown can wait for them,
+// and rejects new requests once draining has started
+type Drainer struct {
+	mutex    sync.Mutex
+	active   int64
+	draining bool
+	idle     chan struct{} // Closed once draining has started and no requests remain
+}
+
+// NewDrainer creates a drainer with no in-flight requests
+func NewDrainer() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Middleware counts requests in flight and returns 503 for new requests while draining.
+// Exempt paths (e.g. liveness probes) are served without being counted.
+func (d *Drainer) Middleware(exemptPaths ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		if !d.enter() {
+			c.Header("Connection", "close")
+			c.Header("Retry-After", "5")
+			apierror.Abort(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeShuttingDown, "Server is shutting down").WithDetails("Retry the request against another instance"))
+			return
+		}
+		defer d.leave()
+
+		c.Next()
+	}
+}
+
+// enter counts a new request unless draining has started; checking and counting under
+// one lock keeps a request from slipping in after Wait has seen zero in flight
+func (d *Drainer) enter() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+// leave uncounts a finished request, signalling Wait when it was the last one during a drain
+func (d *Drainer) leave() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.active--
+	if d.draining && d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// StartDrain stops accepting new requests
+func (d *Drainer) StartDrain() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.draining {
+		return
+	}
+	d.draining = true
+	if d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// Draining reports whether the server is shutting down
+func (d *Drainer) Draining() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.draining
+}
+
+// InFlight returns the number of requests currently being served
+func (d *Drainer) InFlight() int64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.active
+}
+
+// Wait blocks until draining has started and all in-flight requests finish, or ctx is done
+func (d *Drainer) Wait(ctx context.Context) error {
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newDrainRouter serves /slow, which blocks until release is closed, behind the drainer
+func newDrainRouter(drainer *Drainer, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(drainer.Middleware("/live"))
+	router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/live", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestDrainerWaitsForSlowRequest(t *testing.T) {
+	drainer := NewDrainer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newDrainRouter(drainer, started, release)
+
+	slow := httptest.NewRecorder()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		router.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	drainer.StartDrain()
+	if got := drainer.InFlight(); got != 1 {
+		t.Fatalf("InFlight() = %d, want 1", got)
+	}
+
+	rejected := httptest.NewRecorder()
+	router.ServeHTTP(rejected, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rejected.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request during drain returned %d, want 503", rejected.Code)
+	}
+	if got := rejected.Header().Get("Retry-After"); got == "" {
+		t.Error("request during drain has no Retry-After header")
+	}
+
+	live := httptest.NewRecorder()
+	router.ServeHTTP(live, httptest.NewRequest(http.MethodGet, "/live", nil))
+	if live.Code != http.StatusOK {
+		t.Errorf("exempt path during drain returned %d, want 200", live.Code)
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- drainer.Wait(context.Background())
+	}()
+
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait returned %v while a request was in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("Wait() = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after the slow request finished")
+	}
+
+	wg.Wait()
+	if slow.Code != http.StatusOK {
+		t.Errorf("slow request returned %d, want 200", slow.Code)
+	}
+}
+
+func TestDrainerWaitTimesOut(t *testing.T) {
+	drainer := NewDrainer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newDrainRouter(drainer, started, release)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	drainer.StartDrain()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := drainer.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestDrainerWaitWithNothingInFlight(t *testing.T) {
+	drainer := NewDrainer()
+	drainer.StartDrain()
+	drainer.StartDrain() // A second call must not close the idle channel twice
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := drainer.Wait(ctx); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+const (
+	// IdempotencyKeyHeader names a client-chosen key that makes retried POSTs safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on responses served from a stored result
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength bounds client-supplied keys
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyStore remembers responses to POST requests carrying an Idempotency-Key
+// so retries return the stored response instead of running detection again
+type IdempotencyStore struct {
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List // Oldest (first to expire) at the front
+	entries    map[string]*list.Element
+	mutex      sync.Mutex
+}
+
+// idempotencyEntry is a stored response, or a placeholder while the first request runs
+type idempotencyEntry struct {
+	key         string
+	requestHash string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// NewIdempotencyStore creates a store keeping at most maxEntries responses for ttl
+func NewIdempotencyStore(ttl time.Duration, maxEntries int) *IdempotencyStore {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &IdempotencyStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// begin returns the stored entry for key, or reserves the key for a new request and returns nil
+func (s *IdempotencyStore) begin(key, requestHash string) *idempotencyEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for front := s.order.Front(); front != nil; front = s.order.Front() {
+		entry := front.Value.(*idempotencyEntry)
+		if now.Before(entry.expiresAt) && s.order.Len() < s.maxEntries {
+			break
+		}
+		s.order.Remove(front)
+		delete(s.entries, entry.key)
+	}
+
+	if element, ok := s.entries[key]; ok {
+		stored := *element.Value.(*idempotencyEntry)
+		return &stored
+	}
+
+	entry := &idempotencyEntry{key: key, requestHash: requestHash, expiresAt: now.Add(s.ttl)}
+	s.entries[key] = s.order.PushBack(entry)
+	return nil
+}
+
+// complete stores the response for a reserved key
+func (s *IdempotencyStore) complete(key string, status int, contentType string, body []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*idempotencyEntry)
+		entry.done = true
+		entry.status = status
+		entry.contentType = contentType
+		entry.body = body
+	}
+}
+
+// release drops a reserved key so the request can be retried
+func (s *IdempotencyStore) release(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+}
+
+// Idempotency replays the stored response for POST requests whose Idempotency-Key the
+// same client has already used. Keys are scoped per client (API key, or client IP
+// when auth is off) and bound to the request body; reusing a key for a different
+// body is rejected with 422, and retrying while the first request runs with 409.
+// Server errors and streamed responses are not stored so they can be retried.
+func Idempotency(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(idempotencyKey) {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Invalid Idempotency-Key header").WithDetails("Keys must be 1-255 printable ASCII characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidPayload, "Failed to read request body").WithDetails(err.Error()))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := idempotencyScope(c) + "\x00" + c.Request.URL.Path + "\x00" + idempotencyKey
+		bodyHash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodyHash[:])
+
+		stored := store.begin(key, requestHash)
+		switch {
+		case stored == nil:
+		case stored.requestHash != requestHash:
+			apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, apierror.CodeIdempotencyReused, "Idempotency-Key already used for a different request"))
+			return
+		case !stored.done:
+			c.Header("Retry-After", "1")
+			apierror.Abort(c, apierror.New(http.StatusConflict, apierror.CodeRequestInProgress, "A request with this Idempotency-Key is still being processed").WithRetryAfter(1))
+			return
+		default:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.status, stored.contentType, stored.body)
+			c.Abort()
+			return
+		}
+
+		original := c.Writer
+		writer := &recordingResponseWriter{ResponseWriter: original}
+		c.Writer = writer
+
+		defer func() {
+			c.Writer = original
+			if recovered := recover(); recovered != nil {
+				store.release(key)
+				panic(recovered)
+			}
+			if writer.streamed || writer.Status() >= http.StatusInternalServerError {
+				store.release(key)
+				return
+			}
+			store.complete(key, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+		}()
+
+		c.Next()
+	}
+}
+
+// idempotencyScope identifies the client a key belongs to without keeping its API key
+func idempotencyScope(c *gin.Context) string {
+	if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+		hash := sha256.Sum256([]byte(token))
+		return "key:" + hex.EncodeToString(hash[:])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// validIdempotencyKey accepts non-empty, bounded, printable ASCII keys
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// recordingResponseWriter copies the response body as it is written
+type recordingResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	streamed bool // Set once the handler flushes; streamed responses are not stored
+}
+
+// Write records and forwards the body
+func (w *recordingResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString records and forwards the body
+func (w *recordingResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush marks the response as streamed and forwards the flush
+func (w *recordingResponseWriter) Flush() {
+	w.streamed = true
+	w.ResponseWriter.Flush()
+}
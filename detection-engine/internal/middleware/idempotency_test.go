@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotencyRouter serves POST /v1/detect behind the idempotency middleware,
+// answering with status and counting the detections it runs
+func newIdempotencyRouter(store *IdempotencyStore, status int) (*gin.Engine, *atomic.Int32) {
+	gin.SetMode(gin.TestMode)
+	detections := &atomic.Int32{}
+	router := gin.New()
+	router.Use(Idempotency(store))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		run := detections.Add(1)
+		c.JSON(status, gin.H{"run": run})
+	})
+	return router, detections
+}
+
+// serveIdempotent posts body to /v1/detect with the given Idempotency-Key and Authorization headers
+func serveIdempotent(router http.Handler, key, authorization, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	router, detections := newIdempotencyRouter(NewIdempotencyStore(time.Minute, 100), http.StatusOK)
+
+	first := serveIdempotent(router, "retry-1", "", `{"text":"hello"}`)
+	second := serveIdempotent(router, "retry-1", "", `{"text":"hello"}`)
+
+	if detections.Load() != 1 {
+		t.Errorf("detections = %d, want the retry served without running detection again", detections.Load())
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("%s = %q then %q, want only the replay marked", IdempotentReplayedHeader, first.Header().Get(IdempotentReplayedHeader), second.Header().Get(IdempotentReplayedHeader))
+	}
+	if contentType := second.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("replay Content-Type = %q, want the stored JSON type", contentType)
+	}
+}
+
+func TestIdempotencyRunsDetection(t *testing.T) {
+	tests := map[string]struct {
+		requests       [][2]string // Idempotency-Key and Authorization per request
+		wantDetections int32
+	}{
+		"no key":          {[][2]string{{"", ""}, {"", ""}}, 2},
+		"different keys":  {[][2]string{{"a", ""}, {"b", ""}}, 2},
+		"different users": {[][2]string{{"a", "Bearer key-one"}, {"a", "Bearer key-two"}}, 2},
+		"same user":       {[][2]string{{"a", "Bearer key-one"}, {"a", "Bearer key-one"}}, 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			router, detections := newIdempotencyRouter(NewIdempotencyStore(time.Minute, 100), http.StatusOK)
+			for _, request := range tt.requests {
+				if recorder := serveIdempotent(router, request[0], request[1], `{"text":"hello"}`); recorder.Code != http.StatusOK {
+					t.Fatalf("POST = %d: %s", recorder.Code, recorder.Body)
+				}
+			}
+			if detections.Load() != tt.wantDetections {
+				t.Errorf("detections = %d, want %d", detections.Load(), tt.wantDetections)
+			}
+		})
+	}
+}
+
+func TestIdempotencyRejectsKeyReuseForDifferentBody(t *testing.T) {
+	router, detections := newIdempotencyRouter(NewIdempotencyStore(time.Minute, 100), http.StatusOK)
+
+	serveIdempotent(router, "reused", "", `{"text":"hello"}`)
+	recorder := serveIdempotent(router, "reused", "", `{"text":"something else"}`)
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("POST with a reused key = %d, want 422", recorder.Code)
+	}
+	if detections.Load() != 1 {
+		t.Errorf("detections = %d, want 1", detections.Load())
+	}
+}
+
+func TestIdempotencyConflictWhileInProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(Idempotency(NewIdempotencyStore(time.Minute, 100)))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusOK, gin.H{"done": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serveIdempotent(router, "slow", "", `{"text":"hello"}`)
+	}()
+	<-started
+
+	recorder := serveIdempotent(router, "slow", "", `{"text":"hello"}`)
+	if recorder.Code != http.StatusConflict || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("retry while running = %d Retry-After %q, want 409 with Retry-After", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Errorf("first request = %d, want 200", first.Code)
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	router, detections := newIdempotencyRouter(NewIdempotencyStore(time.Minute, 100), http.StatusBadGateway)
+
+	serveIdempotent(router, "failing", "", `{"text":"hello"}`)
+	recorder := serveIdempotent(router, "failing", "", `{"text":"hello"}`)
+	if detections.Load() != 2 || recorder.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("detections = %d replayed %q, want the failed request retried", detections.Load(), recorder.Header().Get(IdempotentReplayedHeader))
+	}
+}
+
+func TestIdempotencyEntriesExpire(t *testing.T) {
+	router, detections := newIdempotencyRouter(NewIdempotencyStore(20*time.Millisecond, 100), http.StatusOK)
+
+	serveIdempotent(router, "short-lived", "", `{"text":"hello"}`)
+	time.Sleep(30 * time.Millisecond)
+	serveIdempotent(router, "short-lived", "", `{"text":"hello"}`)
+	if detections.Load() != 2 {
+		t.Errorf("detections = %d, want the key forgotten after its TTL", detections.Load())
+	}
+}
+
+func TestIdempotencyRejectsInvalidKey(t *testing.T) {
+	router, detections := newIdempotencyRouter(NewIdempotencyStore(time.Minute, 100), http.StatusOK)
+
+	for name, key := range map[string]string{
+		"too long":      strings.Repeat("k", maxIdempotencyKeyLength+1),
+		"control chars": "key\twith\ttabs",
+	} {
+		if recorder := serveIdempotent(router, key, "", `{"text":"hello"}`); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s key = %d, want 400", name, recorder.Code)
+		}
+	}
+	if detections.Load() != 0 {
+		t.Errorf("detections = %d, want none", detections.Load())
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// inFlightRejections counts requests shed by the global in-flight limit
+var inFlightRejections = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "detection_inflight_rejections_total",
+	Help: "Requests rejected because the server was serving its maximum number of requests",
+})
+
+// MaxInFlight caps the requests served at once across all clients, returning 503
+// with Retry-After for requests over the cap so bursts cannot exhaust memory or
+// flood providers. Exempt paths (e.g. health probes) are never shed or counted.
+func MaxInFlight(max int, retryAfterSeconds int, exemptPaths ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	var active atomic.Int64
+	return func(c *gin.Context) {
+		if exempt[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		if active.Add(1) > int64(max) {
+			active.Add(-1)
+			inFlightRejections.Inc()
+			c.Header("Retry-After", fmt.Sprint(retryAfterSeconds))
+			apierror.Abort(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeOverloaded, "Server is at capacity").
+				WithDetails(fmt.Sprintf("%d requests are already in flight, retry after %d seconds", max, retryAfterSeconds)))
+			return
+		}
+		defer active.Add(-1)
+
+		c.Next()
+	}
+}
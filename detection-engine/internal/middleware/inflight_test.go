@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// counterValue reads the current value of a Prometheus counter
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+// newInFlightRouter serves /v1/detect, which signals entered and then blocks until
+// release is closed, and /health behind a limit of max in-flight requests
+func newInFlightRouter(max int, entered chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MaxInFlight(max, 7, "/health"))
+	router.POST("/v1/detect", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// serveRequest sends a bodiless request to path
+func serveRequest(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder
+}
+
+func TestMaxInFlightShedsOverflow(t *testing.T) {
+	entered := make(chan struct{}, 3)
+	release := make(chan struct{})
+	router := newInFlightRouter(2, entered, release)
+
+	// Saturate the limit with requests held inside the handler
+	var wg sync.WaitGroup
+	held := make([]*httptest.ResponseRecorder, 2)
+	for i := range held {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			held[i] = serveRequest(router, http.MethodPost, "/v1/detect")
+		}(i)
+		<-entered
+	}
+
+	rejectionsBefore := counterValue(t, inFlightRejections)
+	overflow := serveRequest(router, http.MethodPost, "/v1/detect")
+	if overflow.Code != http.StatusServiceUnavailable {
+		t.Fatalf("overflow status = %d, want 503: %s", overflow.Code, overflow.Body)
+	}
+	if got := overflow.Header().Get("Retry-After"); got != "7" {
+		t.Errorf("Retry-After = %q, want 7", got)
+	}
+	var apiErr apierror.APIError
+	if err := json.Unmarshal(overflow.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if apiErr.Code != apierror.CodeOverloaded {
+		t.Errorf("code = %q, want %q", apiErr.Code, apierror.CodeOverloaded)
+	}
+	if got := counterValue(t, inFlightRejections) - rejectionsBefore; got != 1 {
+		t.Errorf("rejections metric grew by %v, want 1", got)
+	}
+
+	// Exempt health probes are served while saturated
+	if health := serveRequest(router, http.MethodGet, "/health"); health.Code != http.StatusOK {
+		t.Errorf("/health status = %d while saturated, want 200", health.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	for i, recorder := range held {
+		if recorder.Code != http.StatusOK {
+			t.Errorf("held request %d status = %d, want 200", i, recorder.Code)
+		}
+	}
+
+	// Finished requests free their slots
+	if recorder := serveRequest(router, http.MethodPost, "/v1/detect"); recorder.Code != http.StatusOK {
+		t.Errorf("status after draining = %d, want 200", recorder.Code)
+	}
+}
+
+func TestMaxInFlightReleasesSlots(t *testing.T) {
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	close(release)
+	router := newInFlightRouter(1, entered, release)
+
+	// Sequential requests never overlap, so none is shed
+	for i := 0; i < 5; i++ {
+		if recorder := serveRequest(router, http.MethodPost, "/v1/detect"); recorder.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200 with the previous slot released", i, recorder.Code)
+		}
+	}
+}
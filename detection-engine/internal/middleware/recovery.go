@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/apierror"
+	"prompt-injection-detection/internal/logging"
+)
+
+// Recovery turns handler panics into a JSON internal_error response, logging the
+// panic with its stack trace and request ID. It replaces gin.Recovery, whose
+// plain-text 500 breaks clients that expect an APIError body.
+func Recovery(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// net/http uses this panic to abort a response on purpose; let it through
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			logging.FromContext(c.Request.Context(), logger).WithFields(logrus.Fields{
+				"panic":  recovered,
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"stack":  string(debug.Stack()),
+			}).Error("Recovered from panic in request handler")
+
+			// Headers already sent can't be replaced with an error body
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			apierror.Abort(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Internal server error").WithDetails("An unexpected error occurred; quote the X-Request-ID header when reporting it"))
+		}()
+
+		c.Next()
+	}
+}
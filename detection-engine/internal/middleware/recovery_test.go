@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"prompt-injection-detection/internal/apierror"
+)
+
+// newRecoveryRouter serves /panic, which panics, /partial, which panics after
+// writing its headers, and /ok behind RequestID and Recovery
+func newRecoveryRouter(logger *logrus.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Recovery(logger))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("detector exploded")
+	})
+	router.GET("/partial", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("detector exploded mid-response")
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// serveGet sends a GET request with the given X-Request-ID to path
+func serveGet(router http.Handler, path, requestID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(RequestIDHeader, requestID)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRecoveryReturnsJSONError(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	router := newRecoveryRouter(logger)
+
+	recorder := serveGet(router, "/panic", "panic-request-1")
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", recorder.Code, recorder.Body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", contentType)
+	}
+	var apiErr apierror.APIError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("decode response %q: %v", recorder.Body, err)
+	}
+	if apiErr.Code != apierror.CodeInternal {
+		t.Errorf("code = %q, want %q", apiErr.Code, apierror.CodeInternal)
+	}
+	if strings.Contains(recorder.Body.String(), "detector exploded") {
+		t.Errorf("response leaks the panic value: %s", recorder.Body)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("panic was not logged")
+	}
+	if entry.Level != logrus.ErrorLevel {
+		t.Errorf("logged at %v, want error", entry.Level)
+	}
+	if entry.Data["request_id"] != "panic-request-1" {
+		t.Errorf("logged request_id = %v, want panic-request-1", entry.Data["request_id"])
+	}
+	if entry.Data["panic"] != "detector exploded" {
+		t.Errorf("logged panic = %v, want the panic value", entry.Data["panic"])
+	}
+	if stack, _ := entry.Data["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("logged stack does not reach the panicking handler:\n%s", stack)
+	}
+
+	// The server keeps serving after the panic
+	if recorder := serveGet(router, "/ok", "after-panic"); recorder.Code != http.StatusOK {
+		t.Errorf("status after recovering = %d, want 200", recorder.Code)
+	}
+}
+
+func TestRecoveryKeepsWrittenResponse(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	recorder := serveGet(newRecoveryRouter(logger), "/partial", "partial-request")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want the handler's partial 200 left untouched", recorder.Code, recorder.Body)
+	}
+	if hook.LastEntry() == nil {
+		t.Error("panic after writing was not logged")
+	}
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery(logger))
+	router.GET("/abort", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed through", recovered)
+		}
+	}()
+	serveGet(router, "/abort", "abort-request")
+	t.Error("ErrAbortHandler was swallowed")
+}
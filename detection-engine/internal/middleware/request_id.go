@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"prompt-injection-detection/internal/logging"
+)
+
+const (
+	// RequestIDHeader is read from incoming requests and echoed on responses
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDKey is the gin context key holding the request ID
+	RequestIDKey = "request_id"
+
+	// maxRequestIDLength bounds client-supplied IDs so they can't bloat logs
+	maxRequestIDLength = 128
+)
+
+// RequestID tags each request with an ID taken from X-Request-ID or freshly generated,
+// exposing it in the gin context, the request context (for logging) and the response header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty, bounded, printable ASCII IDs
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random RFC 4122 version 4 UUID
+func newRequestID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic("request id: failed to read random bytes: " + err.Error())
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // Version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
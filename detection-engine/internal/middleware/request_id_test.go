@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"prompt-injection-detection/internal/logging"
+)
+
+// uuidV4 matches the IDs generated for requests without a usable X-Request-ID
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// newRequestIDRouter serves /v1/detect, which logs through the request-scoped logger
+func newRequestIDRouter(logger *logrus.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.POST("/v1/detect", func(c *gin.Context) {
+		if c.GetString(RequestIDKey) != logging.RequestID(c.Request.Context()) {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		logging.FromContext(c.Request.Context(), logger).Info("detecting")
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// serveWithRequestID sends a request with the given X-Request-ID (none when empty)
+// and returns the response and the request_id field of the logged entry
+func serveWithRequestID(t *testing.T, requestID string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	logger, hook := test.NewNullLogger()
+	req := httptest.NewRequest(http.MethodPost, "/v1/detect", nil)
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	recorder := httptest.NewRecorder()
+	newRequestIDRouter(logger).ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 with matching gin and request context IDs", recorder.Code)
+	}
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("handler logged nothing")
+	}
+	logged, _ := entry.Data["request_id"].(string)
+	return recorder, logged
+}
+
+func TestRequestIDPropagatesClientID(t *testing.T) {
+	recorder, logged := serveWithRequestID(t, "client-abc-123")
+
+	if got := recorder.Header().Get(RequestIDHeader); got != "client-abc-123" {
+		t.Errorf("response %s = %q, want the client's ID", RequestIDHeader, got)
+	}
+	if logged != "client-abc-123" {
+		t.Errorf("logged request_id = %q, want the client's ID", logged)
+	}
+}
+
+func TestRequestIDGeneratesID(t *testing.T) {
+	tests := map[string]string{
+		"missing":        "",
+		"too long":       strings.Repeat("a", maxRequestIDLength+1),
+		"unprintable":    "bad\x01id",
+		"contains space": "two words",
+	}
+	for name, requestID := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder, logged := serveWithRequestID(t, requestID)
+
+			header := recorder.Header().Get(RequestIDHeader)
+			if !uuidV4.MatchString(header) {
+				t.Errorf("response %s = %q, want a generated UUID", RequestIDHeader, header)
+			}
+			if logged != header {
+				t.Errorf("logged request_id = %q, want the response header %q", logged, header)
+			}
+		})
+	}
+}
+
+func TestNewRequestIDIsUnique(t *testing.T) {
+	if first, second := newRequestID(), newRequestID(); first == second {
+		t.Errorf("newRequestID returned %q twice", first)
+	}
+}
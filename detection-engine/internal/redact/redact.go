@@ -0,0 +1,110 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// Pattern is a named regular expression whose matches are masked
+type Pattern struct {
+	Name    string
+	Pattern string
+}
+
+// rule is a compiled pattern; validate optionally rejects matches that are not really secrets
+type rule struct {
+	name     string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// Redactor masks PII and secrets in text before it is stored or logged
+type Redactor struct {
+	rules []rule
+}
+
+// builtinRules cover the secrets and PII most often pasted into prompts
+var builtinRules = []rule{
+	{name: "private_key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "openai_key", pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{name: "huggingface_token", pattern: regexp.MustCompile(`\bhf_[A-Za-z0-9]{30,}`)},
+	{name: "aws_access_key", pattern: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{name: "github_token", pattern: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`)},
+	{name: "bearer_token", pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{name: "credential", pattern: regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key)\s*[:=]\s*\S{6,}`)},
+	{name: "email", pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{name: "card_number", pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), validate: luhnValid},
+}
+
+// Default returns a redactor using only the built-in patterns
+func Default() *Redactor {
+	return &Redactor{rules: builtinRules}
+}
+
+// New returns a redactor with the built-in patterns (unless disabled) followed by extra patterns
+func New(includeBuiltin bool, extra []Pattern) (*Redactor, error) {
+	var rules []rule
+	if includeBuiltin {
+		rules = append(rules, builtinRules...)
+	}
+	for _, p := range extra {
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p.Name, err)
+		}
+		rules = append(rules, rule{name: p.Name, pattern: pattern})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact returns text with every match of the redactor's patterns masked.
+// A nil redactor returns text unchanged.
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		if rule.validate == nil {
+			text = rule.pattern.ReplaceAllString(text, Mask)
+			continue
+		}
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.validate(match) {
+				return Mask
+			}
+			return match
+		})
+	}
+	return text
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by card numbers
+func luhnValid(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return len(digits) >= 13 && sum%10 == 0
+}
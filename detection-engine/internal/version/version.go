@@ -0,0 +1,32 @@
+package version
+
+import "runtime"
+
+// Build metadata, injected at build time with:
+//
+//	go build -ldflags "-X prompt-injection-detection/internal/version.Version=v3.1.0 \
+//	  -X prompt-injection-detection/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X prompt-injection-detection/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"prompt-injection-detection/internal/detector"
+	"prompt-injection-detection/internal/logging"
+	"prompt-injection-detection/internal/redact"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed with "sha256="
+const SignatureHeader = "X-Signature"
+
+// Config controls webhook delivery
+type Config struct {
+	URL           string
+	Secret        string        // Shared HMAC key used to sign payloads
+	MinConfidence float64       // Only malicious detections at or above this confidence are sent
+	IncludeText   bool          // Include the analyzed text in the payload
+	MaxRetries    int           // Attempts after the first failed delivery
+	Backoff       time.Duration // Initial retry delay, doubled after every attempt
+	Timeout       time.Duration // Per-attempt HTTP timeout
+	QueueSize     int           // Pending events before new ones are dropped
+
+	// Masks PII and secrets in included text, nil sends it as analyzed
+	Redactor *redact.Redactor
+}
+
+// Event is the JSON payload posted for a malicious detection
+type Event struct {
+	RequestID   string    `json:"request_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Confidence  float64   `json:"confidence"`
+	ThreatTypes []string  `json:"threat_types"`
+	Model       string    `json:"model,omitempty"`
+	Text        string    `json:"text,omitempty"`
+}
+
+// Notifier delivers malicious detections to a webhook in the background
+type Notifier struct {
+	config Config
+	client *http.Client
+	logger *logrus.Logger
+	queue  chan Event
+	done   chan struct{}
+}
+
+// NewNotifier creates a notifier and starts its delivery worker
+func NewNotifier(config Config, logger *logrus.Logger) *Notifier {
+	n := &Notifier{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		logger: logger,
+		queue:  make(chan Event, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+
+	go n.run()
+	return n
+}
+
+// NotifyDetection queues an event when the detection is malicious enough; it never blocks detection
+func (n *Notifier) NotifyDetection(ctx context.Context, text string, response *detector.DetectionResponse) {
+	if !response.IsMalicious || response.Confidence < n.config.MinConfidence {
+		return
+	}
+
+	event := Event{
+		RequestID:   logging.RequestID(ctx),
+		Timestamp:   time.Now().UTC(),
+		Confidence:  response.Confidence,
+		ThreatTypes: response.ThreatTypes,
+		Model:       response.Endpoint,
+	}
+	if n.config.IncludeText {
+		event.Text = n.config.Redactor.Redact(text)
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		n.logger.WithField("request_id", event.RequestID).Warn("Webhook queue full, dropping detection event")
+	}
+}
+
+// Stop delivers queued events and waits for the worker to exit
+func (n *Notifier) Stop() {
+	close(n.queue)
+	<-n.done
+}
+
+// run delivers queued events until the queue is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+
+	for event := range n.queue {
+		if err := n.deliver(event); err != nil {
+			n.logger.WithError(err).WithField("request_id", event.RequestID).Error("Webhook delivery failed")
+		}
+	}
+}
+
+// deliver posts a signed event, retrying with exponential backoff on network errors, 429 and 5xx
+func (n *Notifier) deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	signature := Sign(n.config.Secret, body)
+
+	backoff := n.config.Backoff
+	var lastErr error
+	for attempt := 0; attempt <= n.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		retry, err := n.post(body, signature)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post sends one delivery attempt and reports whether a failure is worth retrying
+func (n *Notifier) post(body []byte, signature string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook rejected event with status %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the X-Signature value for body: "sha256=" followed by the hex HMAC-SHA256
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}